@@ -0,0 +1,216 @@
+package archive
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheme prefixes archive paths. The archive file and the path inside it
+// are separated by "!/", as in archive:///home/user/a.zip!/dir/file.txt.
+const (
+	Scheme    = "archive://"
+	separator = "!/"
+)
+
+// Entry is a file or directory inside an archive.
+type Entry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	Mode    fs.FileMode
+	// LinkTarget is set for symbolic links. Zip archives store the target
+	// as the content of the entry, so it is only known once read.
+	LinkTarget string
+}
+
+func (e *Entry) IsLink() bool {
+	return e.Mode&fs.ModeSymlink != 0
+}
+
+// index lists the entries of an archive by name and by parent directory.
+// Directories that only appear as a prefix of other entries are added.
+type index struct {
+	modTime  time.Time
+	size     int64
+	entries  map[string]*Entry
+	children map[string][]*Entry
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = make(map[string]*index)
+)
+
+// IsArchive reports whether name has the extension of a supported archive.
+func IsArchive(name string) bool {
+	return formatOf(name) != formatUnknown
+}
+
+// IsArchivePath reports whether path points inside an archive.
+func IsArchivePath(path string) bool {
+	return strings.HasPrefix(path, Scheme)
+}
+
+// PathFor returns the archive path of inner inside archiveFile.
+func PathFor(archiveFile, inner string) string {
+	return Scheme + archiveFile + separator + inner
+}
+
+// SplitPath splits an archive path into the archive file and the slash
+// separated path inside it.
+func SplitPath(archivePath string) (archiveFile, inner string, ok bool) {
+	if !IsArchivePath(archivePath) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(archivePath, Scheme)
+	archiveFile, inner, found := strings.Cut(rest, separator)
+	if !found {
+		archiveFile = strings.TrimSuffix(rest, "!")
+	}
+	if archiveFile == "" {
+		return "", "", false
+	}
+	if inner != "" {
+		inner = cleanName(inner)
+	}
+	return archiveFile, inner, true
+}
+
+func readIndex(archiveFile string) (*index, error) {
+	info, err := os.Stat(archiveFile)
+	if err != nil {
+		return nil, err
+	}
+
+	indexesMu.Lock()
+	idx, found := indexes[archiveFile]
+	indexesMu.Unlock()
+	if found && idx.modTime.Equal(info.ModTime()) && idx.size == info.Size() {
+		return idx, nil
+	}
+
+	idx = &index{
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		entries:  make(map[string]*Entry),
+		children: make(map[string][]*Entry),
+	}
+	err = walk(archiveFile, func(entry Entry, open func() (io.ReadCloser, error)) error {
+		idx.add(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	indexesMu.Lock()
+	indexes[archiveFile] = idx
+	indexesMu.Unlock()
+	return idx, nil
+}
+
+func (idx *index) add(entry Entry) {
+	if existing, found := idx.entries[entry.Name]; found {
+		// A directory added for a child is replaced by its real entry.
+		*existing = entry
+		return
+	}
+	parent := parentName(entry.Name)
+	if parent != "" {
+		if _, found := idx.entries[parent]; !found {
+			idx.add(Entry{Name: parent, IsDir: true, Mode: fs.ModeDir | 0755, ModTime: entry.ModTime})
+		}
+	}
+	e := &entry
+	idx.entries[entry.Name] = e
+	idx.children[parent] = append(idx.children[parent], e)
+}
+
+func parentName(name string) string {
+	parent := path.Dir(name)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
+
+// Lookup returns the entry an archive path points to. The root of an
+// archive is returned as a directory.
+func Lookup(archivePath string) (*Entry, error) {
+	archiveFile, inner, ok := SplitPath(archivePath)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an archive path", archivePath)
+	}
+	idx, err := readIndex(archiveFile)
+	if err != nil {
+		return nil, err
+	}
+	if inner == "" {
+		return &Entry{IsDir: true, Mode: fs.ModeDir | 0755, ModTime: idx.modTime}, nil
+	}
+	entry, found := idx.entries[inner]
+	if !found {
+		return nil, fs.ErrNotExist
+	}
+	return entry, nil
+}
+
+// ExtractToCache extracts the file at archivePath into the user cache so it
+// can be previewed or opened by other applications, and returns its path.
+func ExtractToCache(archivePath string) (string, error) {
+	archiveFile, inner, ok := SplitPath(archivePath)
+	if !ok {
+		return "", fmt.Errorf("%s is not an archive path", archivePath)
+	}
+	entry, err := Lookup(archivePath)
+	if err != nil {
+		return "", err
+	}
+	if entry.IsDir {
+		return "", errors.New("cannot extract a directory to the cache")
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(archiveFile))
+	target, err := safeJoin(filepath.Join(cacheDir, "atilgan", "archives", hex.EncodeToString(sum[:])), inner)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(target); err == nil && info.Size() == entry.Size && info.ModTime().Equal(entry.ModTime) {
+		return target, nil
+	}
+	if err := Extract(archivePath, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// FileInfo returns the entry as an fs.FileInfo.
+func (e *Entry) FileInfo() fs.FileInfo {
+	return entryInfo{e}
+}
+
+type entryInfo struct {
+	entry *Entry
+}
+
+func (e entryInfo) Name() string       { return path.Base(e.entry.Name) }
+func (e entryInfo) Size() int64        { return e.entry.Size }
+func (e entryInfo) Mode() fs.FileMode  { return e.entry.Mode }
+func (e entryInfo) ModTime() time.Time { return e.entry.ModTime }
+func (e entryInfo) IsDir() bool        { return e.entry.IsDir }
+func (e entryInfo) Sys() any           { return nil }
@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"path"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+// ArchivePath lists a directory inside an archive.
+type ArchivePath struct {
+	archiveFile string
+	inner       string
+}
+
+func NewArchivePath(archivePath string) *ArchivePath {
+	archiveFile, inner, ok := SplitPath(archivePath)
+	if !ok {
+		return nil
+	}
+	return &ArchivePath{
+		archiveFile: archiveFile,
+		inner:       inner,
+	}
+}
+
+func (a *ArchivePath) GetItems() []*types.ListItem {
+	idx, err := readIndex(a.archiveFile)
+	if err != nil {
+		println("couldn't read archive:", err.Error())
+		return nil
+	}
+
+	children := idx.children[a.inner]
+	items := make([]*types.ListItem, 0, len(children))
+	for _, entry := range children {
+		item := &types.ListItem{
+			Name:        path.Base(entry.Name),
+			Path:        PathFor(a.archiveFile, entry.Name),
+			IsDir:       entry.IsDir,
+			ModTime:     entry.ModTime,
+			CreatedTime: entry.ModTime,
+		}
+		if entry.IsDir {
+			item.ItemCount = len(idx.children[entry.Name])
+		} else {
+			item.Size = entry.Size
+		}
+		items = append(items, item)
+	}
+
+	opts := sorter.DefaultSortOptions()
+	opts.FoldersFirst = true
+	sorter.Sort(items, opts)
+	return items
+}
+
+func (a *ArchivePath) GetPath() string {
+	return PathFor(a.archiveFile, a.inner)
+}
+
+// GetParentPath leaves the archive from its root.
+func (a *ArchivePath) GetParentPath() string {
+	if a.inner == "" {
+		return filepath.Dir(a.archiveFile)
+	}
+	return PathFor(a.archiveFile, parentName(a.inner))
+}
+
+func (a *ArchivePath) GetName() string {
+	if a.inner == "" {
+		return filepath.Base(a.archiveFile)
+	}
+	return path.Base(a.inner)
+}
+
+// ArchiveFile returns the path of the archive on disk.
+func (a *ArchivePath) ArchiveFile() string {
+	return a.archiveFile
+}
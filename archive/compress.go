@@ -0,0 +1,270 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type CompressFormat int
+
+const (
+	CompressZip CompressFormat = iota
+	CompressTarGz
+	CompressTarXz
+	CompressTarZst
+)
+
+var CompressFormats = []CompressFormat{CompressZip, CompressTarGz, CompressTarXz, CompressTarZst}
+
+// Extension returns the file extension written for the format.
+func (f CompressFormat) Extension() string {
+	switch f {
+	case CompressTarGz:
+		return ".tar.gz"
+	case CompressTarXz:
+		return ".tar.xz"
+	case CompressTarZst:
+		return ".tar.zst"
+	default:
+		return ".zip"
+	}
+}
+
+// CompressOptions configures Compress. Level goes from 1 (fastest) to 9
+// (smallest). Exclude holds shell patterns matched against the name and the
+// relative path of every file.
+type CompressOptions struct {
+	Format  CompressFormat
+	Level   int
+	Exclude []string
+}
+
+func DefaultCompressOptions() CompressOptions {
+	return CompressOptions{Format: CompressZip, Level: 6}
+}
+
+// sourceFile is a file on disk and the slash separated name it is stored
+// under.
+type sourceFile struct {
+	path string
+	name string
+	info fs.FileInfo
+}
+
+// Compress writes sources into a new archive at destination. The archive is
+// written next to destination first and only moved in place once complete.
+func Compress(ctx context.Context, sources []string, destination string, opts CompressOptions, progress func(float64)) error {
+	files, total, err := collectSources(sources, opts.Exclude)
+	if err != nil {
+		return err
+	}
+
+	partial := destination + ".part"
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+
+	var written int64
+	counter := &progressWriter{ctx: ctx, report: func(n int64) {
+		written += n
+		if progress != nil && total > 0 {
+			progress(float64(written) / float64(total))
+		}
+	}}
+
+	if opts.Format == CompressZip {
+		err = writeZip(file, files, opts.Level, counter)
+	} else {
+		err = writeTar(file, files, opts, counter)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, destination)
+}
+
+func collectSources(sources []string, exclude []string) ([]sourceFile, int64, error) {
+	var files []sourceFile
+	var total int64
+	for _, source := range sources {
+		base := filepath.Dir(source)
+		err := filepath.WalkDir(source, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(base, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if excluded(rel, exclude) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				total += info.Size()
+			} else if !info.IsDir() && info.Mode()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			files = append(files, sourceFile{path: p, name: rel, info: info})
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return files, total, nil
+}
+
+func excluded(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, path.Base(name)); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func writeZip(out io.Writer, files []sourceFile, level int, counter *progressWriter) error {
+	writer := zip.NewWriter(out)
+	writer.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+
+	for _, file := range files {
+		if err := counter.ctx.Err(); err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(file.info)
+		if err != nil {
+			return err
+		}
+		header.Name = file.name
+		if file.info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		entryWriter, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case file.info.IsDir():
+		case file.info.Mode()&fs.ModeSymlink != 0:
+			// Zip stores the target of a link as its content.
+			target, err := os.Readlink(file.path)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(entryWriter, target); err != nil {
+				return err
+			}
+		default:
+			if err := copySource(entryWriter, file.path, counter); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Close()
+}
+
+func writeTar(out io.Writer, files []sourceFile, opts CompressOptions, counter *progressWriter) error {
+	var stream io.WriteCloser
+	var err error
+	switch opts.Format {
+	case CompressTarGz:
+		stream, err = gzip.NewWriterLevel(out, opts.Level)
+	case CompressTarXz:
+		// The dictionary grows with the level like xz's own presets.
+		config := xz.WriterConfig{DictCap: 1 << (19 + min(max(opts.Level, 1), 9))}
+		stream, err = config.NewWriter(out)
+	case CompressTarZst:
+		stream, err = zstd.NewWriter(out, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level*2)))
+	}
+	if err != nil {
+		return err
+	}
+
+	writer := tar.NewWriter(stream)
+	for _, file := range files {
+		if err := counter.ctx.Err(); err != nil {
+			return err
+		}
+		linkTarget := ""
+		if file.info.Mode()&fs.ModeSymlink != 0 {
+			linkTarget, err = os.Readlink(file.path)
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(file.info, linkTarget)
+		if err != nil {
+			return err
+		}
+		header.Name = file.name
+		if file.info.IsDir() {
+			header.Name += "/"
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		if file.info.Mode().IsRegular() {
+			if err := copySource(writer, file.path, counter); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return stream.Close()
+}
+
+func copySource(writer io.Writer, source string, counter *progressWriter) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(io.MultiWriter(writer, counter), file)
+	return err
+}
+
+// ParseExclude splits a comma separated list of patterns.
+func ParseExclude(text string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(text, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
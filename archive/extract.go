@@ -0,0 +1,435 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ConflictMode int
+
+const (
+	// ConflictRename keeps both by giving the extracted item a free name.
+	ConflictRename ConflictMode = iota
+	ConflictOverwrite
+	ConflictSkip
+)
+
+var ConflictModes = []ConflictMode{ConflictRename, ConflictOverwrite, ConflictSkip}
+
+func (c ConflictMode) String() string {
+	switch c {
+	case ConflictOverwrite:
+		return "Overwrite"
+	case ConflictSkip:
+		return "Skip"
+	default:
+		return "Keep both"
+	}
+}
+
+// Extract writes the file or directory at archivePath to destination,
+// replacing what is already there.
+func Extract(archivePath, destination string) error {
+	archiveFile, inner, ok := SplitPath(archivePath)
+	if !ok {
+		return fmt.Errorf("%s is not an archive path", archivePath)
+	}
+	return extract(context.Background(), archiveFile, inner, destination, nil, nil)
+}
+
+// ExtractArchive extracts every entry of archiveFile into destination. Top
+// level items that already exist are handled as conflict says. On error or
+// cancellation the items created so far are removed.
+func ExtractArchive(ctx context.Context, archiveFile, destination string, conflict ConflictMode, progress func(float64)) error {
+	idx, err := readIndex(archiveFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return err
+	}
+
+	// Top level names are mapped to where they are written, "" skips them.
+	topNames := make(map[string]string)
+	created := make([]string, 0)
+	for _, entry := range idx.children[""] {
+		name := path.Base(entry.Name)
+		target := filepath.Join(destination, name)
+		if _, err := os.Lstat(target); err == nil {
+			switch conflict {
+			case ConflictSkip:
+				topNames[name] = ""
+				continue
+			case ConflictRename:
+				target = UniquePath(target)
+				created = append(created, target)
+			}
+		} else {
+			created = append(created, target)
+		}
+		topNames[name] = filepath.Base(target)
+	}
+
+	err = extract(ctx, archiveFile, "", destination, topNames, progress)
+	if err != nil {
+		for _, path := range created {
+			os.RemoveAll(path)
+		}
+	}
+	return err
+}
+
+// DefaultExtractDir returns where "Extract Here" writes an archive: its own
+// folder, or a new folder named after it when it has several top level
+// items.
+func DefaultExtractDir(archiveFile string) (string, error) {
+	idx, err := readIndex(archiveFile)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(archiveFile)
+	if len(idx.children[""]) <= 1 {
+		return dir, nil
+	}
+	return UniquePath(filepath.Join(dir, Stem(filepath.Base(archiveFile)))), nil
+}
+
+// Stem returns an archive name without its archive extension.
+func Stem(name string) string {
+	lower := strings.ToLower(name)
+	for _, f := range formatSuffixes {
+		if strings.HasSuffix(lower, f.suffix) {
+			return name[:len(name)-len(f.suffix)]
+		}
+	}
+	return name
+}
+
+// UniquePath returns target, or target with " (2)", " (3)" and so on added
+// before the extension when it already exists.
+func UniquePath(target string) string {
+	if _, err := os.Lstat(target); os.IsNotExist(err) {
+		return target
+	}
+	dir, name := filepath.Split(target)
+	stem, ext := Stem(name), ""
+	if stem == name {
+		ext = filepath.Ext(name)
+		stem = strings.TrimSuffix(name, ext)
+	} else {
+		ext = name[len(stem):]
+	}
+	for i := 2; ; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+type pendingLink struct {
+	target     string
+	linkTarget string
+}
+
+type pendingDir struct {
+	target string
+	mode   fs.FileMode
+}
+
+// extract writes the entry inner, or everything below it, to destination.
+// topNames renames the first path element of each entry when set.
+func extract(ctx context.Context, archiveFile, inner, destination string, topNames map[string]string, progress func(float64)) error {
+	root := &Entry{IsDir: true}
+	var total int64
+	idx, err := readIndex(archiveFile)
+	if err != nil {
+		return err
+	}
+	if inner != "" {
+		entry, found := idx.entries[inner]
+		if !found {
+			return fs.ErrNotExist
+		}
+		root = entry
+	}
+	prefix := ""
+	if inner != "" {
+		prefix = inner + "/"
+	}
+	for name, entry := range idx.entries {
+		if name == inner || strings.HasPrefix(name, prefix) {
+			total += entry.Size
+		}
+	}
+
+	var written int64
+	counter := &progressWriter{ctx: ctx, report: func(n int64) {
+		written += n
+		if progress != nil && total > 0 {
+			progress(float64(written) / float64(total))
+		}
+	}}
+
+	// Entries are checked against the destination with its links followed,
+	// which for a single file is the folder it goes in.
+	base := destination
+	if !root.IsDir {
+		base = filepath.Dir(destination)
+	}
+	realBase, err := realDir(base)
+	if err != nil {
+		return err
+	}
+
+	var links []pendingLink
+	var dirs []pendingDir
+	err = walk(archiveFile, func(entry Entry, open func() (io.ReadCloser, error)) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var target string
+		if !root.IsDir {
+			if entry.Name != inner {
+				return nil
+			}
+			target = destination
+		} else {
+			if !strings.HasPrefix(entry.Name, prefix) {
+				return nil
+			}
+			rel := strings.TrimPrefix(entry.Name, prefix)
+			if topNames != nil {
+				top, rest, _ := strings.Cut(rel, "/")
+				newTop, found := topNames[top]
+				if found && newTop == "" {
+					return nil
+				}
+				if found {
+					rel = path.Join(newTop, rest)
+				}
+			}
+			var err error
+			target, err = safeJoin(destination, rel)
+			if err != nil {
+				return err
+			}
+		}
+
+		// A link already in the destination could lead the entry outside.
+		checked := target
+		if !entry.IsDir {
+			checked = filepath.Dir(target)
+		}
+		if real, err := realDir(checked); err != nil || !inside(realBase, real) {
+			return fmt.Errorf("%s points outside the destination", entry.Name)
+		}
+
+		switch {
+		case entry.IsDir:
+			dirs = append(dirs, pendingDir{target: target, mode: entry.Mode.Perm()})
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case entry.IsLink():
+			linkTarget, err := readLinkTarget(entry, open)
+			if err != nil {
+				return err
+			}
+			links = append(links, pendingLink{target: target, linkTarget: linkTarget})
+		default:
+			if err := extractFile(entry, open, target, counter); err != nil {
+				return err
+			}
+		}
+		if !root.IsDir {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Links are created last so no entry can be written through one, and
+	// only when they stay inside the destination. A link is never created
+	// through another one made here either.
+	made := make(map[string]bool)
+	for _, link := range links {
+		if throughLink(base, link.target, made) || !linkStaysInside(realBase, link.target, link.linkTarget) {
+			println("skipping link that points outside the destination:", link.target)
+			continue
+		}
+		os.Remove(link.target)
+		if err := os.MkdirAll(filepath.Dir(link.target), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(link.linkTarget, link.target); err != nil {
+			return err
+		}
+		made[link.target] = true
+	}
+
+	// Directory permissions are applied deepest first so read only
+	// directories don't block writing their children.
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i].target) > len(dirs[j].target)
+	})
+	for _, dir := range dirs {
+		if dir.mode != 0 {
+			os.Chmod(dir.target, dir.mode|0700)
+		}
+	}
+	return nil
+}
+
+func readLinkTarget(entry Entry, open func() (io.ReadCloser, error)) (string, error) {
+	if entry.LinkTarget != "" {
+		return entry.LinkTarget, nil
+	}
+	reader, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	target, err := io.ReadAll(io.LimitReader(reader, 4096))
+	return string(target), err
+}
+
+// linkStaysInside tells whether a link at link pointing to linkTarget
+// resolves inside realDestination, following the links on the way.
+func linkStaysInside(realDestination, link, linkTarget string) bool {
+	if filepath.IsAbs(linkTarget) {
+		return false
+	}
+	// Going up after going down would be undone by Join before the link
+	// on the way down is followed, so such targets aren't trusted.
+	down := false
+	for _, part := range strings.Split(linkTarget, string(filepath.Separator)) {
+		switch part {
+		case "", ".":
+		case "..":
+			if down {
+				return false
+			}
+		default:
+			down = true
+		}
+	}
+	parent, err := realDir(filepath.Dir(link))
+	if err != nil || !inside(realDestination, parent) {
+		return false
+	}
+	resolved, err := realDir(filepath.Join(parent, linkTarget))
+	return err == nil && inside(realDestination, resolved)
+}
+
+// throughLink tells whether a folder between destination and target is a
+// link in made.
+func throughLink(destination, target string, made map[string]bool) bool {
+	for dir := filepath.Dir(target); dir != destination && inside(destination, dir); dir = filepath.Dir(dir) {
+		if made[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// realDir follows the links in dir. When dir doesn't exist yet its deepest
+// existing parent is followed instead, as what is missing can't be a link.
+func realDir(dir string) (string, error) {
+	missing := ""
+	for {
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(real, missing), nil
+		}
+		// A link to nowhere exists itself and would be followed on creation.
+		if _, lerr := os.Lstat(dir); !os.IsNotExist(err) || lerr == nil {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+		dir = parent
+	}
+}
+
+// inside tells whether path is dir or below it.
+func inside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// safeJoin joins a slash separated entry name to destination and refuses
+// names that would be written outside of it.
+func safeJoin(destination, name string) (string, error) {
+	target := filepath.Join(destination, filepath.FromSlash(name))
+	if !inside(destination, target) {
+		return "", fmt.Errorf("%s points outside the destination", name)
+	}
+	return target, nil
+}
+
+func extractFile(entry Entry, open func() (io.ReadCloser, error), target string, counter *progressWriter) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	reader, err := open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// An existing link is replaced instead of written through.
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		os.Remove(target)
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = file
+	if counter != nil {
+		writer = io.MultiWriter(file, counter)
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if perm := entry.Mode.Perm(); perm != 0 {
+		os.Chmod(target, perm)
+	}
+	if !entry.ModTime.IsZero() {
+		os.Chtimes(target, entry.ModTime, entry.ModTime)
+	}
+	return nil
+}
+
+// progressWriter counts the bytes written through it and stops the copy
+// once its context is cancelled.
+type progressWriter struct {
+	ctx    context.Context
+	report func(n int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	p.report(int64(len(b)))
+	return len(b), nil
+}
@@ -0,0 +1,254 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/ulikunitz/xz"
+)
+
+type format int
+
+const (
+	formatUnknown format = iota
+	formatZip
+	formatTar
+	formatTarGz
+	formatTarBz2
+	formatTarXz
+	formatTarZst
+	format7z
+	formatRar
+)
+
+var formatSuffixes = []struct {
+	suffix string
+	format format
+}{
+	{".tar.gz", formatTarGz},
+	{".tgz", formatTarGz},
+	{".tar.bz2", formatTarBz2},
+	{".tbz2", formatTarBz2},
+	{".tar.xz", formatTarXz},
+	{".txz", formatTarXz},
+	{".tar.zst", formatTarZst},
+	{".tzst", formatTarZst},
+	{".tar", formatTar},
+	{".zip", formatZip},
+	{".jar", formatZip},
+	{".7z", format7z},
+	{".rar", formatRar},
+}
+
+var errStop = errors.New("stop walking")
+
+func formatOf(name string) format {
+	name = strings.ToLower(name)
+	for _, f := range formatSuffixes {
+		if strings.HasSuffix(name, f.suffix) {
+			return f.format
+		}
+	}
+	return formatUnknown
+}
+
+// walkFunc is called for every file and directory in an archive. open is
+// only valid during the call. Returning errStop ends the walk early.
+type walkFunc func(entry Entry, open func() (io.ReadCloser, error)) error
+
+func walk(archiveFile string, fn walkFunc) error {
+	var err error
+	switch formatOf(archiveFile) {
+	case formatZip:
+		err = walkZip(archiveFile, fn)
+	case format7z:
+		err = walk7z(archiveFile, fn)
+	case formatRar:
+		err = walkRar(archiveFile, fn)
+	case formatTar, formatTarGz, formatTarBz2, formatTarXz, formatTarZst:
+		err = walkTar(archiveFile, fn)
+	default:
+		err = errors.New("unsupported archive format")
+	}
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+// cleanName turns an entry name into a slash separated relative path and
+// returns "" for names that would point outside the archive.
+func cleanName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Clean("/" + name)[1:]
+	if name == "" || name == ".." || strings.HasPrefix(name, "../") {
+		return ""
+	}
+	return name
+}
+
+// newEntry returns the entry for a directory, regular file or symbolic
+// link. Other entries such as devices and hard links are left out.
+func newEntry(name string, size int64, info fs.FileInfo, linkTarget string) (Entry, bool) {
+	name = cleanName(name)
+	if name == "" {
+		return Entry{}, false
+	}
+	mode := info.Mode()
+	if !mode.IsDir() && !mode.IsRegular() && mode&fs.ModeSymlink == 0 {
+		return Entry{}, false
+	}
+	return Entry{
+		Name:       name,
+		Size:       size,
+		ModTime:    info.ModTime(),
+		IsDir:      mode.IsDir(),
+		Mode:       mode,
+		LinkTarget: linkTarget,
+	}, true
+}
+
+func walkZip(archiveFile string, fn walkFunc) error {
+	reader, err := zip.OpenReader(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		entry, ok := newEntry(file.Name, int64(file.UncompressedSize64), file.FileInfo(), "")
+		if !ok {
+			continue
+		}
+		if err := fn(entry, file.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walk7z(archiveFile string, fn walkFunc) error {
+	reader, err := sevenzip.OpenReader(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		entry, ok := newEntry(file.Name, int64(file.UncompressedSize), file.FileInfo(), "")
+		if !ok {
+			continue
+		}
+		if err := fn(entry, file.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkRar(archiveFile string, fn walkFunc) error {
+	reader, err := rardecode.OpenReader(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entry, ok := newEntry(header.Name, header.UnPackedSize, rarFileInfo{header}, header.LinkTarget)
+		if !ok {
+			continue
+		}
+		open := func() (io.ReadCloser, error) {
+			return io.NopCloser(reader), nil
+		}
+		if err := fn(entry, open); err != nil {
+			return err
+		}
+	}
+}
+
+func walkTar(archiveFile string, fn walkFunc) error {
+	file, err := os.Open(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var stream io.Reader = file
+	switch formatOf(archiveFile) {
+	case formatTarGz:
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		stream = gzipReader
+	case formatTarBz2:
+		stream = bzip2.NewReader(file)
+	case formatTarXz:
+		xzReader, err := xz.NewReader(file)
+		if err != nil {
+			return err
+		}
+		stream = xzReader
+	case formatTarZst:
+		zstdReader, err := zstd.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		stream = zstdReader
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entry, ok := newEntry(header.Name, header.Size, header.FileInfo(), header.Linkname)
+		if !ok {
+			continue
+		}
+		open := func() (io.ReadCloser, error) {
+			return io.NopCloser(reader), nil
+		}
+		if err := fn(entry, open); err != nil {
+			return err
+		}
+	}
+}
+
+// rarFileInfo adapts a rar header to fs.FileInfo.
+type rarFileInfo struct {
+	header *rardecode.FileHeader
+}
+
+func (r rarFileInfo) Name() string       { return path.Base(r.header.Name) }
+func (r rarFileInfo) Size() int64        { return r.header.UnPackedSize }
+func (r rarFileInfo) Mode() fs.FileMode  { return r.header.Mode() }
+func (r rarFileInfo) ModTime() time.Time { return r.header.ModificationTime }
+func (r rarFileInfo) IsDir() bool        { return r.header.IsDir }
+func (r rarFileInfo) Sys() any           { return nil }
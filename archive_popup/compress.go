@@ -0,0 +1,151 @@
+package archive_popup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/rename_popup"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+type CompressWindow struct {
+	*gtk.Window
+	Sources []string
+	Dir     string
+	Applied func(destination string, opts archive.CompressOptions)
+
+	nameEntry      *gtk.Entry
+	formatDropDown *gtk.DropDown
+	levelSpin      *gtk.SpinButton
+	excludeEntry   *gtk.Entry
+	errorLabel     *gtk.Label
+	compressButton *gtk.Button
+}
+
+func NewCompressWindow(sources []string) *CompressWindow {
+	opts := archive.DefaultCompressOptions()
+	cw := &CompressWindow{
+		Window:         gtk.NewWindow(),
+		Sources:        sources,
+		Dir:            filepath.Dir(sources[0]),
+		nameEntry:      gtk.NewEntry(),
+		levelSpin:      gtk.NewSpinButtonWithRange(1, 9, 1),
+		excludeEntry:   gtk.NewEntry(),
+		errorLabel:     gtk.NewLabel(""),
+		compressButton: gtk.NewButtonWithLabel("Compress"),
+	}
+
+	formatNames := make([]string, len(archive.CompressFormats))
+	for i, format := range archive.CompressFormats {
+		formatNames[i] = format.Extension()
+	}
+	cw.formatDropDown = gtk.NewDropDownFromStrings(formatNames)
+
+	if len(sources) == 1 {
+		cw.SetTitle("Compress " + filepath.Base(sources[0]))
+		cw.nameEntry.SetText(filepath.Base(sources[0]))
+	} else {
+		cw.SetTitle(fmt.Sprintf("Compress %d Items", len(sources)))
+		cw.nameEntry.SetText("Archive")
+	}
+	cw.SetDefaultSize(420, -1)
+	cw.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	cw.SetChild(box)
+
+	form := gtk.NewGrid()
+	form.SetRowSpacing(6)
+	form.SetColumnSpacing(6)
+	box.Append(form)
+
+	cw.nameEntry.SetHExpand(true)
+	cw.levelSpin.SetValue(float64(opts.Level))
+	cw.levelSpin.SetTooltipText("1 is fastest, 9 is smallest")
+	cw.excludeEntry.SetPlaceholderText("*.log, .git, node_modules")
+
+	row := 0
+	addRow := func(label string, widgets ...gtk.Widgetter) {
+		title := gtk.NewLabel(label)
+		title.SetXAlign(0)
+		form.Attach(title, 0, row, 1, 1)
+		for i, widget := range widgets {
+			form.Attach(widget, i+1, row, 1, 1)
+		}
+		row++
+	}
+	addRow("Name", cw.nameEntry, cw.formatDropDown)
+	addRow("Level", cw.levelSpin)
+	addRow("Exclude", cw.excludeEntry)
+
+	cw.errorLabel.SetXAlign(0)
+	cw.errorLabel.SetHExpand(true)
+	cw.errorLabel.AddCSSClass("rename-error")
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(cw.Destroy)
+	cw.compressButton.AddCSSClass("suggested-action")
+	cw.compressButton.ConnectClicked(cw.apply)
+	buttonBox.Append(cw.errorLabel)
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(cw.compressButton)
+	box.Append(buttonBox)
+
+	cw.nameEntry.ConnectChanged(cw.update)
+	cw.nameEntry.ConnectActivate(cw.apply)
+	cw.excludeEntry.ConnectActivate(cw.apply)
+	cw.formatDropDown.NotifyProperty("selected", cw.update)
+
+	cw.update()
+	return cw
+}
+
+func (cw *CompressWindow) format() archive.CompressFormat {
+	return archive.CompressFormats[cw.formatDropDown.Selected()]
+}
+
+func (cw *CompressWindow) destination() (string, error) {
+	name := cw.nameEntry.Text() + cw.format().Extension()
+	if err := rename_popup.CheckName(name); err != nil {
+		return "", err
+	}
+	destination := filepath.Join(cw.Dir, name)
+	if _, err := os.Lstat(destination); err == nil {
+		return "", fmt.Errorf("\"%s\" already exists", name)
+	}
+	return destination, nil
+}
+
+func (cw *CompressWindow) update() {
+	_, err := cw.destination()
+	if err != nil {
+		cw.errorLabel.SetText(err.Error())
+		cw.compressButton.SetSensitive(false)
+		return
+	}
+	cw.errorLabel.SetText("")
+	cw.compressButton.SetSensitive(true)
+}
+
+func (cw *CompressWindow) apply() {
+	destination, err := cw.destination()
+	if err != nil {
+		return
+	}
+	opts := archive.CompressOptions{
+		Format:  cw.format(),
+		Level:   cw.levelSpin.ValueAsInt(),
+		Exclude: archive.ParseExclude(cw.excludeEntry.Text()),
+	}
+	if cw.Applied != nil {
+		cw.Applied(destination, opts)
+	}
+	cw.Destroy()
+}
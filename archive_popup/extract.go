@@ -0,0 +1,116 @@
+package archive_popup
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// ExtractWindow asks where an archive is extracted to and what happens to
+// items that already exist there.
+type ExtractWindow struct {
+	*gtk.Window
+	ArchiveFile string
+	Applied     func(destination string, conflict archive.ConflictMode)
+
+	destinationEntry *gtk.Entry
+	conflictDropDown *gtk.DropDown
+	extractButton    *gtk.Button
+}
+
+func NewExtractWindow(archiveFile string) *ExtractWindow {
+	ew := &ExtractWindow{
+		Window:           gtk.NewWindow(),
+		ArchiveFile:      archiveFile,
+		destinationEntry: gtk.NewEntry(),
+		extractButton:    gtk.NewButtonWithLabel("Extract"),
+	}
+
+	conflictNames := make([]string, len(archive.ConflictModes))
+	for i, mode := range archive.ConflictModes {
+		conflictNames[i] = mode.String()
+	}
+	ew.conflictDropDown = gtk.NewDropDownFromStrings(conflictNames)
+
+	destination, err := archive.DefaultExtractDir(archiveFile)
+	if err != nil {
+		destination = filepath.Dir(archiveFile)
+	}
+	ew.destinationEntry.SetText(destination)
+	ew.destinationEntry.SetHExpand(true)
+
+	ew.SetTitle("Extract " + filepath.Base(archiveFile))
+	ew.SetDefaultSize(480, -1)
+	ew.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	ew.SetChild(box)
+
+	form := gtk.NewGrid()
+	form.SetRowSpacing(6)
+	form.SetColumnSpacing(6)
+	box.Append(form)
+
+	browseButton := gtk.NewButtonFromIconName("folder-open-symbolic")
+	browseButton.SetTooltipText("Choose Folder")
+	browseButton.ConnectClicked(ew.browse)
+
+	destinationLabel := gtk.NewLabel("Destination")
+	destinationLabel.SetXAlign(0)
+	form.Attach(destinationLabel, 0, 0, 1, 1)
+	form.Attach(ew.destinationEntry, 1, 0, 1, 1)
+	form.Attach(browseButton, 2, 0, 1, 1)
+
+	conflictLabel := gtk.NewLabel("Existing items")
+	conflictLabel.SetXAlign(0)
+	form.Attach(conflictLabel, 0, 1, 1, 1)
+	form.Attach(ew.conflictDropDown, 1, 1, 2, 1)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(ew.Destroy)
+	ew.extractButton.AddCSSClass("suggested-action")
+	ew.extractButton.ConnectClicked(ew.apply)
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(ew.extractButton)
+	box.Append(buttonBox)
+
+	ew.destinationEntry.ConnectChanged(func() {
+		ew.extractButton.SetSensitive(filepath.IsAbs(ew.destinationEntry.Text()))
+	})
+	ew.destinationEntry.ConnectActivate(ew.apply)
+
+	return ew
+}
+
+func (ew *ExtractWindow) browse() {
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle("Extract To")
+	dialog.SetInitialFolder(gio.NewFileForPath(filepath.Dir(ew.ArchiveFile)))
+	dialog.SelectFolder(context.Background(), ew.Window, func(res gio.AsyncResulter) {
+		folder, err := dialog.SelectFolderFinish(res)
+		if err != nil || folder == nil {
+			return
+		}
+		ew.destinationEntry.SetText(folder.Path())
+	})
+}
+
+func (ew *ExtractWindow) apply() {
+	destination := filepath.Clean(ew.destinationEntry.Text())
+	if !filepath.IsAbs(destination) {
+		return
+	}
+	if ew.Applied != nil {
+		ew.Applied(destination, archive.ConflictModes[ew.conflictDropDown.Selected()])
+	}
+	ew.Destroy()
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gdkpixbuf/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+const maxIcons = 256
+
+type iconEntry struct {
+	key    string
+	pixbuf *gdkpixbuf.Pixbuf
+}
+
+var (
+	iconList  = list.New()
+	iconIndex = make(map[string]*list.Element)
+	iconMutex = &sync.Mutex{}
+)
+
+// GetIcon returns the pixbuf of iconName rendered at size for the given
+// scale factor. Icons are kept in a least recently used cache of at most
+// maxIcons entries; icons that can't be loaded are cached as nil.
+func GetIcon(iconTheme *gtk.IconTheme, iconName string, size int, scale int) *gdkpixbuf.Pixbuf {
+	key := fmt.Sprintf("%s@%dx%d", iconName, size, scale)
+
+	iconMutex.Lock()
+	if element, ok := iconIndex[key]; ok {
+		iconList.MoveToFront(element)
+		iconMutex.Unlock()
+		return element.Value.(*iconEntry).pixbuf
+	}
+	iconMutex.Unlock()
+
+	pixbuf := loadIcon(iconTheme, iconName, size, scale)
+
+	iconMutex.Lock()
+	defer iconMutex.Unlock()
+	iconIndex[key] = iconList.PushFront(&iconEntry{key: key, pixbuf: pixbuf})
+	for iconList.Len() > maxIcons {
+		oldest := iconList.Back()
+		iconList.Remove(oldest)
+		delete(iconIndex, oldest.Value.(*iconEntry).key)
+	}
+	return pixbuf
+}
+
+func loadIcon(iconTheme *gtk.IconTheme, iconName string, size int, scale int) *gdkpixbuf.Pixbuf {
+	paintable := iconTheme.LookupIcon(iconName, nil, size, scale, gtk.TextDirNone, 0)
+	if paintable == nil {
+		return nil
+	}
+	file := paintable.File()
+	if file == nil || file.Path() == "" {
+		return nil
+	}
+	texture, err := gdk.NewTextureFromFile(file)
+	if err != nil {
+		return nil
+	}
+	return gdk.PixbufGetFromTexture(texture)
+}
+
+func ClearIcons() {
+	iconMutex.Lock()
+	defer iconMutex.Unlock()
+	iconList.Init()
+	iconIndex = make(map[string]*list.Element)
+}
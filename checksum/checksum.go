@@ -0,0 +1,189 @@
+package checksum
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"lukechampine.com/blake3"
+)
+
+type Algorithm int
+
+const (
+	MD5 Algorithm = iota
+	SHA1
+	SHA256
+	SHA512
+	BLAKE3
+)
+
+var Algorithms = []Algorithm{MD5, SHA1, SHA256, SHA512, BLAKE3}
+
+func (a Algorithm) String() string {
+	switch a {
+	case MD5:
+		return "MD5"
+	case SHA1:
+		return "SHA-1"
+	case SHA512:
+		return "SHA-512"
+	case BLAKE3:
+		return "BLAKE3"
+	default:
+		return "SHA-256"
+	}
+}
+
+// Extension returns the extension used for single file checksum files,
+// such as .sha256 for file.iso.sha256.
+func (a Algorithm) Extension() string {
+	switch a {
+	case MD5:
+		return ".md5"
+	case SHA1:
+		return ".sha1"
+	case SHA512:
+		return ".sha512"
+	case BLAKE3:
+		return ".b3"
+	default:
+		return ".sha256"
+	}
+}
+
+// ManifestName returns the name coreutils style manifests of the algorithm
+// are usually given.
+func (a Algorithm) ManifestName() string {
+	switch a {
+	case MD5:
+		return "MD5SUMS"
+	case SHA1:
+		return "SHA1SUMS"
+	case SHA512:
+		return "SHA512SUMS"
+	case BLAKE3:
+		return "B3SUMS"
+	default:
+		return "SHA256SUMS"
+	}
+}
+
+func (a Algorithm) New() hash.Hash {
+	switch a {
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	case SHA512:
+		return sha512.New()
+	case BLAKE3:
+		return blake3.New(32, nil)
+	default:
+		return sha256.New()
+	}
+}
+
+// HexLength returns the length of a hex encoded sum of the algorithm.
+func (a Algorithm) HexLength() int {
+	return a.New().Size() * 2
+}
+
+// Sums holds the hex encoded sums of a file by algorithm.
+type Sums map[Algorithm]string
+
+// Match returns the algorithm whose sum equals expected, ignoring case.
+func (s Sums) Match(expected string) (Algorithm, bool) {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	for _, algorithm := range Algorithms {
+		if sum, found := s[algorithm]; found && sum == expected {
+			return algorithm, true
+		}
+	}
+	return 0, false
+}
+
+// File computes the sums of path for every algorithm in a single read.
+// progress is called with the number of bytes read since its last call.
+func File(ctx context.Context, path string, algorithms []Algorithm, progress func(n int64)) (Sums, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	for i, algorithm := range algorithms {
+		hashes[i] = algorithm.New()
+		writers[i] = hashes[i]
+	}
+	writer := io.MultiWriter(writers...)
+
+	buffer := make([]byte, 1024*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := file.Read(buffer)
+		if n > 0 {
+			writer.Write(buffer[:n])
+			if progress != nil {
+				progress(int64(n))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sums := make(Sums, len(algorithms))
+	for i, algorithm := range algorithms {
+		sums[algorithm] = hex.EncodeToString(hashes[i].Sum(nil))
+	}
+	return sums, nil
+}
+
+// Result is the outcome of hashing one of several files.
+type Result struct {
+	Path string
+	Sums Sums
+	Err  error
+}
+
+// Files computes the sums of every path. progress gets the fraction of all
+// bytes read so far. Files that can't be read are reported in their result
+// and don't stop the others.
+func Files(ctx context.Context, paths []string, algorithms []Algorithm, progress func(float64)) ([]Result, error) {
+	var total, done int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+
+	results := make([]Result, len(paths))
+	for i, path := range paths {
+		sums, err := File(ctx, path, algorithms, func(n int64) {
+			done += n
+			if progress != nil && total > 0 {
+				progress(float64(done) / float64(total))
+			}
+		})
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		results[i] = Result{Path: path, Sums: sums, Err: err}
+	}
+	return results, nil
+}
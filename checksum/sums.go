@@ -0,0 +1,319 @@
+package checksum
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Expected is a sum read from pasted text or a checksum file. Name is empty
+// when the sum was given on its own.
+type Expected struct {
+	Name      string
+	Sum       string
+	Algorithm Algorithm
+	// Known is set when the algorithm was named, otherwise it is guessed
+	// from the length of the sum.
+	Known bool
+}
+
+// Candidates returns the algorithms the sum could have been made with.
+func (e Expected) Candidates() []Algorithm {
+	if e.Known {
+		return []Algorithm{e.Algorithm}
+	}
+	var candidates []Algorithm
+	for _, algorithm := range Algorithms {
+		if algorithm.HexLength() == len(e.Sum) {
+			candidates = append(candidates, algorithm)
+		}
+	}
+	return candidates
+}
+
+var bsdTags = map[string]Algorithm{
+	"MD5":     MD5,
+	"SHA1":    SHA1,
+	"SHA256":  SHA256,
+	"SHA512":  SHA512,
+	"BLAKE3":  BLAKE3,
+	"SHA-1":   SHA1,
+	"SHA-256": SHA256,
+	"SHA-512": SHA512,
+}
+
+func isHex(s string) bool {
+	if len(s) == 0 || len(s)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Parse reads sums in the formats written by sha256sum and friends
+// ("<sum>  name" or "<sum> *name"), in the BSD format
+// ("SHA256 (name) = <sum>") and as bare sums. Other lines are ignored.
+func Parse(text string) []Expected {
+	var entries []Expected
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if entry, ok := parseLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func parseLine(line string) (Expected, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Expected{}, false
+	}
+
+	if open := strings.Index(line, " ("); open > 0 {
+		if algorithm, found := bsdTags[strings.ToUpper(line[:open])]; found {
+			close := strings.LastIndex(line, ") = ")
+			if close > open {
+				sum := strings.ToLower(strings.TrimSpace(line[close+4:]))
+				if isHex(sum) {
+					return Expected{Name: line[open+2 : close], Sum: sum, Algorithm: algorithm, Known: true}, true
+				}
+			}
+		}
+	}
+
+	// Names with a backslash or newline are escaped and the line starts
+	// with a backslash.
+	escaped := strings.HasPrefix(line, "\\")
+	line = strings.TrimPrefix(line, "\\")
+	sum, name, _ := strings.Cut(line, " ")
+	sum = strings.ToLower(sum)
+	if !isHex(sum) {
+		return Expected{}, false
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+	if escaped {
+		name = strings.NewReplacer("\\\\", "\\", "\\n", "\n").Replace(name)
+	}
+	entry := Expected{Name: name, Sum: sum}
+	if candidates := entry.Candidates(); len(candidates) > 0 {
+		entry.Algorithm = candidates[0]
+		return entry, true
+	}
+	return Expected{}, false
+}
+
+// hintAlgorithm narrows entries of unnamed algorithms using the name of the
+// file they were read from, which tells SHA-256 and BLAKE3 apart.
+func hintAlgorithm(entries []Expected, fileName string) {
+	lower := strings.ToLower(fileName)
+	for _, algorithm := range Algorithms {
+		hint := strings.ToLower(strings.TrimPrefix(algorithm.Extension(), "."))
+		if !strings.Contains(lower, hint) && !strings.Contains(lower, strings.ToLower(algorithm.ManifestName())) {
+			continue
+		}
+		for i := range entries {
+			if !entries[i].Known && algorithm.HexLength() == len(entries[i].Sum) {
+				entries[i].Algorithm = algorithm
+				entries[i].Known = true
+			}
+		}
+	}
+}
+
+// ReadFile parses a checksum file.
+func ReadFile(sumsFile string) ([]Expected, error) {
+	data, err := os.ReadFile(sumsFile)
+	if err != nil {
+		return nil, err
+	}
+	entries := Parse(string(data))
+	hintAlgorithm(entries, filepath.Base(sumsFile))
+	return entries, nil
+}
+
+// Lookup returns the entry for name. A single entry without a name matches
+// any file.
+func Lookup(entries []Expected, name string) (Expected, bool) {
+	for _, entry := range entries {
+		if path.Clean(filepath.ToSlash(entry.Name)) == name {
+			return entry, true
+		}
+	}
+	if len(entries) == 1 && entries[0].Name == "" {
+		return entries[0], true
+	}
+	return Expected{}, false
+}
+
+// sumsFileNames returns the checksum files that may describe target, such
+// as file.iso.sha256 or SHA256SUMS in the same directory.
+func sumsFileNames(target string) []string {
+	dir, name := filepath.Split(target)
+	var names []string
+	for _, algorithm := range Algorithms {
+		names = append(names,
+			filepath.Join(dir, name+algorithm.Extension()),
+			filepath.Join(dir, name+algorithm.Extension()+"sum"),
+			filepath.Join(dir, algorithm.ManifestName()),
+			filepath.Join(dir, algorithm.ManifestName()+".txt"),
+			filepath.Join(dir, strings.ToLower(algorithm.ManifestName())+".txt"),
+		)
+	}
+	return append(names, filepath.Join(dir, "CHECKSUMS"), filepath.Join(dir, "checksums.txt"))
+}
+
+// FindExpected looks next to target for a checksum file listing it and
+// returns the sum and the file it was found in.
+func FindExpected(target string) (Expected, string, bool) {
+	name := filepath.Base(target)
+	for _, sumsFile := range sumsFileNames(target) {
+		info, err := os.Stat(sumsFile)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > 16*1024*1024 {
+			continue
+		}
+		entries, err := ReadFile(sumsFile)
+		if err != nil {
+			continue
+		}
+		if entry, found := Lookup(entries, name); found {
+			return entry, sumsFile, true
+		}
+	}
+	return Expected{}, "", false
+}
+
+// Verification is the result of checking one file against its expected
+// sum.
+type Verification struct {
+	Path     string
+	Expected Expected
+	Actual   string
+	OK       bool
+	Err      error
+}
+
+// Verify checks path against expected, trying every algorithm the sum
+// could belong to.
+func Verify(ctx context.Context, path string, expected Expected, progress func(n int64)) Verification {
+	v := Verification{Path: path, Expected: expected}
+	candidates := expected.Candidates()
+	if len(candidates) == 0 {
+		v.Err = fmt.Errorf("%q is not a known checksum", expected.Sum)
+		return v
+	}
+	sums, err := File(ctx, path, candidates, progress)
+	if err != nil {
+		v.Err = err
+		return v
+	}
+	if algorithm, ok := sums.Match(expected.Sum); ok {
+		v.Expected.Algorithm = algorithm
+		v.Actual = sums[algorithm]
+		v.OK = true
+		return v
+	}
+	v.Actual = sums[candidates[0]]
+	return v
+}
+
+// VerifyManifest checks every file a checksum file lists. Names are
+// relative to the directory of the checksum file.
+func VerifyManifest(ctx context.Context, sumsFile string, progress func(float64)) ([]Verification, error) {
+	entries, err := ReadFile(sumsFile)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(sumsFile)
+
+	var total, done int64
+	for _, entry := range entries {
+		if info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(entry.Name))); err == nil {
+			total += info.Size()
+		}
+	}
+
+	verifications := make([]Verification, 0, len(entries))
+	for _, entry := range entries {
+		v := Verify(ctx, filepath.Join(dir, filepath.FromSlash(entry.Name)), entry, func(n int64) {
+			done += n
+			if progress != nil && total > 0 {
+				progress(float64(done) / float64(total))
+			}
+		})
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, v)
+	}
+	return verifications, nil
+}
+
+// WriteManifest writes a checksum file for every file below dir, in the
+// format sha256sum -c reads, and returns its path.
+func WriteManifest(ctx context.Context, dir string, algorithm Algorithm, progress func(float64)) (string, error) {
+	manifest := filepath.Join(dir, algorithm.ManifestName())
+
+	var names []string
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || p == manifest {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	var done int64
+	for _, name := range names {
+		sums, err := File(ctx, filepath.Join(dir, filepath.FromSlash(name)), []Algorithm{algorithm}, func(n int64) {
+			done += n
+			if progress != nil && total > 0 {
+				progress(float64(done) / float64(total))
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsAny(name, "\\\n") {
+			name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+			builder.WriteString("\\")
+		}
+		fmt.Fprintf(&builder, "%s  %s\n", sums[algorithm], name)
+	}
+
+	partial := manifest + ".part"
+	if err := os.WriteFile(partial, []byte(builder.String()), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(partial, manifest); err != nil {
+		os.Remove(partial)
+		return "", err
+	}
+	return manifest, nil
+}
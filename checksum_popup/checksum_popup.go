@@ -0,0 +1,393 @@
+package checksum_popup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/checksum"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+// ChecksumWindow computes checksums of files and compares them with pasted
+// or found sums. For a single folder it writes a checksum manifest instead,
+// and for a checksum file it verifies the files it lists.
+type ChecksumWindow struct {
+	*gtk.Window
+	Paths []string
+	// ManifestCreated is called with the path of a written manifest.
+	ManifestCreated func(path string)
+
+	algorithmChecks  []*gtk.CheckButton
+	manifestDropDown *gtk.DropDown
+	expectedView     *gtk.TextView
+	expectedLabel    *gtk.Label
+	results          *gtk.Box
+	progressBar      *gtk.ProgressBar
+	statusLabel      *gtk.Label
+	buttons          []*gtk.Button
+	cancel           context.CancelFunc
+}
+
+func NewChecksumWindow(paths []string) *ChecksumWindow {
+	cw := &ChecksumWindow{
+		Window:        gtk.NewWindow(),
+		Paths:         paths,
+		expectedView:  gtk.NewTextView(),
+		expectedLabel: gtk.NewLabel(""),
+		results:       gtk.NewBox(gtk.OrientationVertical, 6),
+		progressBar:   gtk.NewProgressBar(),
+		statusLabel:   gtk.NewLabel(""),
+	}
+
+	if len(paths) == 1 {
+		cw.SetTitle("Checksums of " + filepath.Base(paths[0]))
+	} else {
+		cw.SetTitle(fmt.Sprintf("Checksums of %d Files", len(paths)))
+	}
+	cw.SetDefaultSize(640, 480)
+	cw.SetModal(true)
+	cw.ConnectCloseRequest(func() bool {
+		cw.stop()
+		return false
+	})
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	cw.SetChild(box)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	cw.statusLabel.SetXAlign(0)
+	cw.statusLabel.SetHExpand(true)
+	cw.statusLabel.SetEllipsize(pango.EllipsizeMiddle)
+	buttonBox.Append(cw.statusLabel)
+
+	if len(paths) == 1 && isDir(paths[0]) {
+		cw.buildManifest(box, buttonBox)
+	} else {
+		cw.buildSums(box, buttonBox)
+	}
+
+	resultsScroll := gtk.NewScrolledWindow()
+	resultsScroll.SetVExpand(true)
+	resultsScroll.SetChild(cw.results)
+	box.Append(resultsScroll)
+	box.Append(cw.progressBar)
+	cw.progressBar.SetVisible(false)
+
+	closeButton := gtk.NewButtonWithLabel("Close")
+	closeButton.ConnectClicked(func() {
+		cw.stop()
+		cw.Destroy()
+	})
+	buttonBox.Append(closeButton)
+	box.Append(buttonBox)
+
+	return cw
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (cw *ChecksumWindow) buildSums(box, buttonBox *gtk.Box) {
+	checkBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	for _, algorithm := range checksum.Algorithms {
+		check := gtk.NewCheckButtonWithLabel(algorithm.String())
+		check.SetActive(algorithm == checksum.SHA256)
+		cw.algorithmChecks = append(cw.algorithmChecks, check)
+		checkBox.Append(check)
+	}
+	box.Append(checkBox)
+
+	expectedTitle := gtk.NewLabel("Expected checksums")
+	expectedTitle.SetXAlign(0)
+	box.Append(expectedTitle)
+
+	cw.expectedView.SetMonospace(true)
+	cw.expectedView.SetWrapMode(gtk.WrapChar)
+	expectedScroll := gtk.NewScrolledWindow()
+	expectedScroll.SetMinContentHeight(80)
+	expectedScroll.SetChild(cw.expectedView)
+	box.Append(expectedScroll)
+
+	cw.expectedLabel.SetXAlign(0)
+	cw.expectedLabel.AddCSSClass("dim-label")
+	box.Append(cw.expectedLabel)
+
+	// A sum found next to the first file is filled in so a download can be
+	// checked with a single click.
+	if expected, sumsFile, found := checksum.FindExpected(cw.Paths[0]); found {
+		cw.expectedView.Buffer().SetText(expected.Sum)
+		cw.expectedLabel.SetText("Found in " + filepath.Base(sumsFile))
+	} else {
+		cw.expectedLabel.SetText("Paste sums, sha256sum output or a BSD style list")
+	}
+
+	if len(cw.Paths) == 1 {
+		if entries, err := checksum.ReadFile(cw.Paths[0]); err == nil && len(entries) > 0 && entries[0].Name != "" {
+			verifyButton := gtk.NewButtonWithLabel("Verify Listed Files")
+			verifyButton.ConnectClicked(cw.verifyManifest)
+			cw.addButton(buttonBox, verifyButton)
+		}
+	}
+
+	computeButton := gtk.NewButtonWithLabel("Compute")
+	computeButton.AddCSSClass("suggested-action")
+	computeButton.ConnectClicked(cw.compute)
+	cw.addButton(buttonBox, computeButton)
+}
+
+func (cw *ChecksumWindow) buildManifest(box, buttonBox *gtk.Box) {
+	names := make([]string, len(checksum.Algorithms))
+	for i, algorithm := range checksum.Algorithms {
+		names[i] = algorithm.String()
+	}
+	cw.manifestDropDown = gtk.NewDropDownFromStrings(names)
+	cw.manifestDropDown.SetSelected(uint(checksum.SHA256))
+
+	row := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	label := gtk.NewLabel("Write a checksum file listing every file in the folder")
+	label.SetXAlign(0)
+	label.SetHExpand(true)
+	row.Append(label)
+	row.Append(cw.manifestDropDown)
+	box.Append(row)
+
+	manifestButton := gtk.NewButtonWithLabel("Create Manifest")
+	manifestButton.AddCSSClass("suggested-action")
+	manifestButton.ConnectClicked(cw.createManifest)
+	cw.addButton(buttonBox, manifestButton)
+}
+
+func (cw *ChecksumWindow) addButton(buttonBox *gtk.Box, button *gtk.Button) {
+	cw.buttons = append(cw.buttons, button)
+	buttonBox.Append(button)
+}
+
+func (cw *ChecksumWindow) stop() {
+	if cw.cancel != nil {
+		cw.cancel()
+		cw.cancel = nil
+	}
+}
+
+// run calls work on a goroutine and done with its result on the main loop.
+func (cw *ChecksumWindow) run(work func(ctx context.Context, progress func(float64)) error, done func(err error)) {
+	cw.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	cw.cancel = cancel
+	for _, button := range cw.buttons {
+		button.SetSensitive(false)
+	}
+	cw.clearResults()
+	cw.statusLabel.SetText("Working…")
+	cw.progressBar.SetFraction(0)
+	cw.progressBar.SetVisible(true)
+
+	go func() {
+		err := work(ctx, func(fraction float64) {
+			glib.IdleAdd(func() {
+				cw.progressBar.SetFraction(fraction)
+			})
+		})
+		glib.IdleAdd(func() {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			cw.cancel = nil
+			cancel()
+			for _, button := range cw.buttons {
+				button.SetSensitive(true)
+			}
+			cw.progressBar.SetVisible(false)
+			if err != nil {
+				cw.statusLabel.SetText(err.Error())
+			} else {
+				cw.statusLabel.SetText("")
+			}
+			done(err)
+		})
+	}()
+}
+
+func (cw *ChecksumWindow) selectedAlgorithms() []checksum.Algorithm {
+	var algorithms []checksum.Algorithm
+	for i, check := range cw.algorithmChecks {
+		if check.Active() {
+			algorithms = append(algorithms, checksum.Algorithms[i])
+		}
+	}
+	return algorithms
+}
+
+func (cw *ChecksumWindow) expectedText() string {
+	buffer := cw.expectedView.Buffer()
+	start, end := buffer.Bounds()
+	return buffer.Text(start, end, false)
+}
+
+func (cw *ChecksumWindow) compute() {
+	expected := checksum.Parse(cw.expectedText())
+	algorithms := cw.selectedAlgorithms()
+	// Sums that are pasted are always computed so they can be compared.
+	for _, entry := range expected {
+		for _, candidate := range entry.Candidates() {
+			if !containsAlgorithm(algorithms, candidate) {
+				algorithms = append(algorithms, candidate)
+			}
+		}
+	}
+	if len(algorithms) == 0 {
+		cw.statusLabel.SetText("Choose at least one algorithm")
+		return
+	}
+
+	var results []checksum.Result
+	cw.run(func(ctx context.Context, progress func(float64)) error {
+		var err error
+		results, err = checksum.Files(ctx, cw.Paths, algorithms, progress)
+		return err
+	}, func(err error) {
+		if err != nil {
+			return
+		}
+		matched, failed := 0, 0
+		for _, result := range results {
+			status := ""
+			if entry, found := checksum.Lookup(expected, filepath.Base(result.Path)); found && result.Err == nil {
+				if _, ok := result.Sums.Match(entry.Sum); ok {
+					status = "OK"
+					matched++
+				} else {
+					status = "FAILED"
+					failed++
+				}
+			}
+			cw.addResult(result, algorithms, status)
+		}
+		if matched+failed > 0 {
+			cw.statusLabel.SetText(fmt.Sprintf("%d matched, %d failed", matched, failed))
+		}
+	})
+}
+
+func containsAlgorithm(algorithms []checksum.Algorithm, algorithm checksum.Algorithm) bool {
+	for _, a := range algorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *ChecksumWindow) verifyManifest() {
+	var verifications []checksum.Verification
+	cw.run(func(ctx context.Context, progress func(float64)) error {
+		var err error
+		verifications, err = checksum.VerifyManifest(ctx, cw.Paths[0], progress)
+		return err
+	}, func(err error) {
+		if err != nil {
+			return
+		}
+		failed := 0
+		for _, v := range verifications {
+			status := "OK"
+			if v.Err != nil {
+				status = v.Err.Error()
+				failed++
+			} else if !v.OK {
+				status = "FAILED"
+				failed++
+			}
+			cw.addStatusRow(v.Expected.Name, status, v.OK)
+		}
+		cw.statusLabel.SetText(fmt.Sprintf("%d of %d files OK", len(verifications)-failed, len(verifications)))
+	})
+}
+
+func (cw *ChecksumWindow) createManifest() {
+	algorithm := checksum.Algorithms[cw.manifestDropDown.Selected()]
+	var manifest string
+	cw.run(func(ctx context.Context, progress func(float64)) error {
+		var err error
+		manifest, err = checksum.WriteManifest(ctx, cw.Paths[0], algorithm, progress)
+		return err
+	}, func(err error) {
+		if err != nil {
+			return
+		}
+		cw.statusLabel.SetText("Wrote " + manifest)
+		if cw.ManifestCreated != nil {
+			cw.ManifestCreated(manifest)
+		}
+	})
+}
+
+func (cw *ChecksumWindow) clearResults() {
+	for child := cw.results.FirstChild(); child != nil; child = cw.results.FirstChild() {
+		cw.results.Remove(child)
+	}
+}
+
+func (cw *ChecksumWindow) addResult(result checksum.Result, algorithms []checksum.Algorithm, status string) {
+	cw.addStatusRow(filepath.Base(result.Path), status, status == "OK")
+	if result.Err != nil {
+		cw.addStatusRow(result.Err.Error(), "", false)
+		return
+	}
+
+	grid := gtk.NewGrid()
+	grid.SetRowSpacing(2)
+	grid.SetColumnSpacing(6)
+	for i, algorithm := range algorithms {
+		sum := result.Sums[algorithm]
+		name := gtk.NewLabel(algorithm.String())
+		name.SetXAlign(0)
+		name.AddCSSClass("dim-label")
+		value := gtk.NewLabel(sum)
+		value.SetXAlign(0)
+		value.SetHExpand(true)
+		value.SetSelectable(true)
+		value.SetEllipsize(pango.EllipsizeMiddle)
+		value.AddCSSClass("monospace")
+		copyButton := gtk.NewButtonFromIconName("edit-copy-symbolic")
+		copyButton.SetTooltipText("Copy")
+		copyButton.ConnectClicked(func() {
+			gdk.DisplayGetDefault().Clipboard().SetText(sum)
+		})
+		grid.Attach(name, 0, i, 1, 1)
+		grid.Attach(value, 1, i, 1, 1)
+		grid.Attach(copyButton, 2, i, 1, 1)
+	}
+	cw.results.Append(grid)
+}
+
+func (cw *ChecksumWindow) addStatusRow(name, status string, ok bool) {
+	row := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	nameLabel := gtk.NewLabel(name)
+	nameLabel.SetXAlign(0)
+	nameLabel.SetHExpand(true)
+	nameLabel.SetEllipsize(pango.EllipsizeMiddle)
+	row.Append(nameLabel)
+	if status != "" {
+		statusLabel := gtk.NewLabel(status)
+		if ok {
+			statusLabel.AddCSSClass("success")
+		} else {
+			statusLabel.AddCSSClass("error")
+		}
+		row.Append(statusLabel)
+	}
+	cw.results.Append(row)
+}
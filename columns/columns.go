@@ -0,0 +1,182 @@
+package columns
+
+import (
+	"slices"
+
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
+)
+
+type Column string
+
+const (
+	ColumnName        Column = "name"
+	ColumnSize        Column = "size"
+	ColumnModified    Column = "modified"
+	ColumnCreated     Column = "created"
+	ColumnPermissions Column = "permissions"
+	ColumnOwner       Column = "owner"
+	ColumnGroup       Column = "group"
+	ColumnMimeType    Column = "mime_type"
+	ColumnExtension   Column = "extension"
+	ColumnTags        Column = "tags"
+	ColumnDimensions  Column = "dimensions"
+	ColumnDiskUsage   Column = "disk_usage"
+)
+
+var AllColumns = []Column{
+	ColumnName,
+	ColumnSize,
+	ColumnModified,
+	ColumnCreated,
+	ColumnPermissions,
+	ColumnOwner,
+	ColumnGroup,
+	ColumnMimeType,
+	ColumnExtension,
+	ColumnTags,
+	ColumnDimensions,
+	ColumnDiskUsage,
+}
+
+const MinWidth = 40
+
+func (c Column) String() string {
+	switch c {
+	case ColumnSize:
+		return "Size"
+	case ColumnModified:
+		return "Modified"
+	case ColumnCreated:
+		return "Created"
+	case ColumnPermissions:
+		return "Permissions"
+	case ColumnOwner:
+		return "Owner"
+	case ColumnGroup:
+		return "Group"
+	case ColumnMimeType:
+		return "Type"
+	case ColumnExtension:
+		return "Extension"
+	case ColumnTags:
+		return "Tags"
+	case ColumnDimensions:
+		return "Dimensions"
+	case ColumnDiskUsage:
+		return "Disk Usage"
+	default:
+		return "Name"
+	}
+}
+
+func (c Column) DefaultWidth() int {
+	switch c {
+	case ColumnName:
+		return 320
+	case ColumnModified, ColumnCreated:
+		return 150
+	case ColumnMimeType, ColumnTags:
+		return 140
+	case ColumnPermissions:
+		return 100
+	default:
+		return 90
+	}
+}
+
+// SortOptions returns the sort options a click on the header of c selects.
+// Clicking the column that is already sorted flips the direction, another
+// one starts in the default direction of its key.
+func (c Column) SortOptions(current sorter.SortOptions) sorter.SortOptions {
+	key, column := c.sortKey()
+	opts := sorter.SortOptions{Key: key, Column: column, FoldersFirst: current.FoldersFirst, Descending: key.DefaultDescending()}
+	if c.IsSortedBy(current) {
+		opts.Descending = !current.Descending
+	}
+	return opts
+}
+
+func (c Column) IsSortedBy(opts sorter.SortOptions) bool {
+	key, column := c.sortKey()
+	return opts.Key == key && opts.Column == column
+}
+
+func (c Column) sortKey() (sorter.SortKey, string) {
+	switch c {
+	case ColumnName:
+		return sorter.SortByName, ""
+	case ColumnSize:
+		return sorter.SortBySize, ""
+	case ColumnModified:
+		return sorter.SortByTime, ""
+	case ColumnCreated:
+		return sorter.SortByCreated, ""
+	case ColumnDiskUsage:
+		return sorter.SortByDiskUsage, ""
+	default:
+		return sorter.SortByColumn, string(c)
+	}
+}
+
+type ColumnLayout struct {
+	Column Column `json:"column"`
+	Width  int    `json:"width"`
+}
+
+type Layout []ColumnLayout
+
+func DefaultLayout() Layout {
+	return Layout{
+		{ColumnName, ColumnName.DefaultWidth()},
+		{ColumnSize, ColumnSize.DefaultWidth()},
+		{ColumnModified, ColumnModified.DefaultWidth()},
+	}
+}
+
+func (l Layout) Contains(c Column) bool {
+	return l.Index(c) >= 0
+}
+
+func (l Layout) Index(c Column) int {
+	return slices.IndexFunc(l, func(cl ColumnLayout) bool { return cl.Column == c })
+}
+
+// Toggle shows or hides c. The name column is always shown.
+func (l Layout) Toggle(c Column) Layout {
+	if c == ColumnName {
+		return l
+	}
+	if i := l.Index(c); i >= 0 {
+		return slices.Delete(slices.Clone(l), i, i+1)
+	}
+	return append(slices.Clone(l), ColumnLayout{c, c.DefaultWidth()})
+}
+
+// Move places the column at index from at index to. The name column stays
+// first.
+func (l Layout) Move(from, to int) Layout {
+	if from <= 0 || from >= len(l) || to <= 0 || to >= len(l) || from == to {
+		return l
+	}
+	moved := slices.Clone(l)
+	column := moved[from]
+	moved = slices.Delete(moved, from, from+1)
+	return slices.Insert(moved, to, column)
+}
+
+func (l Layout) Resize(index int, width int) Layout {
+	if index < 0 || index >= len(l) {
+		return l
+	}
+	resized := slices.Clone(l)
+	resized[index].Width = max(width, MinWidth)
+	return resized
+}
+
+func (l Layout) TotalWidth() int {
+	total := 0
+	for _, cl := range l {
+		total += cl.Width
+	}
+	return total
+}
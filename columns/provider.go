@@ -0,0 +1,353 @@
+package columns
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+// Details holds the column values that need a stat or a read of the file.
+type Details struct {
+	// ModTime, ChangeTime and Size are those of the item the details were
+	// loaded for, a chmod or chown only changes the ChangeTime.
+	ModTime     time.Time
+	ChangeTime  time.Time
+	Size        int64
+	Permissions string
+	Owner       string
+	Group       string
+	MimeType    string
+	Width       int
+	Height      int
+}
+
+// maxDetails is how many files the details are cached of, which is well
+// more than fit on screen.
+const maxDetails = 4096
+
+type detailsEntry struct {
+	path    string
+	details *Details
+}
+
+// Provider returns column values for list items. Values that are expensive
+// to compute are loaded by a background worker and kept in a least
+// recently used cache by path.
+type Provider struct {
+	TagLookup func(path string) []string
+
+	mutex   sync.Mutex
+	details map[string]*list.Element
+	order   *list.List
+	pending map[string]func()
+	queue   chan *types.ListItem
+	// sortLoad counts the calls of LoadSortValues, so that a load can tell
+	// it was superseded.
+	sortLoad int
+}
+
+func NewProvider() *Provider {
+	p := &Provider{
+		details: make(map[string]*list.Element),
+		order:   list.New(),
+		pending: make(map[string]func()),
+		queue:   make(chan *types.ListItem, 1024),
+	}
+	go p.work()
+	return p
+}
+
+// Value returns the text of col for item. If the value isn't loaded yet it
+// returns false and loads it in the background, calling loaded from the
+// worker goroutine when it is done.
+func (p *Provider) Value(item *types.ListItem, col Column, loaded func()) (string, bool) {
+	if value, ok := p.plainValue(item, col); ok {
+		return value, true
+	}
+	details, ok := p.cached(item)
+	if !ok {
+		p.request(item, loaded)
+		return "", false
+	}
+	return details.value(col), true
+}
+
+// plainValue returns the text of col for the columns the item itself holds,
+// false for those that need its details.
+func (p *Provider) plainValue(item *types.ListItem, col Column) (string, bool) {
+	switch col {
+	case ColumnName:
+		return item.Name, true
+	case ColumnSize:
+		if item.IsDir {
+			return fmt.Sprintf("%d item", item.ItemCount), true
+		}
+		return fileops.GetFileSizeAsString(item.Size), true
+	case ColumnDiskUsage:
+		if item.IsDir && item.TotalSize == 0 {
+			return "", true
+		}
+		return fileops.GetFileSizeAsString(sorter.DiskUsage(item)), true
+	case ColumnModified:
+		return formatTime(item.ModTime), true
+	case ColumnCreated:
+		return formatTime(item.CreatedTime), true
+	case ColumnExtension:
+		if item.IsDir {
+			return "", true
+		}
+		return strings.TrimPrefix(strings.ToLower(filepath.Ext(item.Name)), "."), true
+	case ColumnTags:
+		if p.TagLookup == nil {
+			return "", true
+		}
+		return strings.Join(p.TagLookup(item.Path), ", "), true
+	}
+	return "", false
+}
+
+// SortValue returns the text of col for item if it is at hand, without
+// loading it. Items whose values are missing can be sorted again once
+// LoadSortValues has them.
+func (p *Provider) SortValue(item *types.ListItem, col Column) (string, bool) {
+	if value, ok := p.plainValue(item, col); ok {
+		return value, true
+	}
+	details, ok := p.cached(item)
+	if !ok {
+		return "", false
+	}
+	return details.value(col), true
+}
+
+// LoadSortValues loads the text of col for every item in the background and
+// calls loaded with them by path, from another goroutine. A later call
+// supersedes the load, which then stops without calling loaded.
+func (p *Provider) LoadSortValues(items []*types.ListItem, col Column, loaded func(values map[string]string)) {
+	p.mutex.Lock()
+	p.sortLoad++
+	load := p.sortLoad
+	p.mutex.Unlock()
+	items = slices.Clone(items)
+	go func() {
+		values := make(map[string]string, len(items))
+		for _, item := range items {
+			p.mutex.Lock()
+			superseded := p.sortLoad != load
+			p.mutex.Unlock()
+			if superseded {
+				return
+			}
+			value, ok := p.SortValue(item, col)
+			if !ok {
+				details := loadDetails(item)
+				p.store(item.Path, details)
+				value = details.value(col)
+			}
+			values[item.Path] = value
+		}
+		loaded(values)
+	}()
+}
+
+func (d *Details) value(col Column) string {
+	switch col {
+	case ColumnPermissions:
+		return d.Permissions
+	case ColumnOwner:
+		return d.Owner
+	case ColumnGroup:
+		return d.Group
+	case ColumnMimeType:
+		return d.MimeType
+	case ColumnDimensions:
+		if d.Width == 0 && d.Height == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d x %d", d.Width, d.Height)
+	}
+	return ""
+}
+
+func (p *Provider) cached(item *types.ListItem) (*Details, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	element, ok := p.details[item.Path]
+	if !ok {
+		return nil, false
+	}
+	details := element.Value.(*detailsEntry).details
+	if !details.ModTime.Equal(item.ModTime) || !details.ChangeTime.Equal(item.ChangeTime) || details.Size != item.Size {
+		return nil, false
+	}
+	p.order.MoveToFront(element)
+	return details, true
+}
+
+func (p *Provider) request(item *types.ListItem, loaded func()) {
+	p.mutex.Lock()
+	if _, ok := p.pending[item.Path]; ok {
+		p.mutex.Unlock()
+		return
+	}
+	p.pending[item.Path] = loaded
+	p.mutex.Unlock()
+
+	select {
+	case p.queue <- item:
+	default:
+		// The worker is behind; drop the request so it is retried on the
+		// next draw.
+		p.mutex.Lock()
+		delete(p.pending, item.Path)
+		p.mutex.Unlock()
+	}
+}
+
+func (p *Provider) store(path string, details *Details) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.storeLocked(path, details)
+}
+
+func (p *Provider) storeLocked(path string, details *Details) {
+	if element, ok := p.details[path]; ok {
+		element.Value.(*detailsEntry).details = details
+		p.order.MoveToFront(element)
+		return
+	}
+	p.details[path] = p.order.PushFront(&detailsEntry{path: path, details: details})
+	for p.order.Len() > maxDetails {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.details, oldest.Value.(*detailsEntry).path)
+	}
+}
+
+func (p *Provider) work() {
+	for item := range p.queue {
+		details := loadDetails(item)
+		p.mutex.Lock()
+		p.storeLocked(item.Path, details)
+		loaded := p.pending[item.Path]
+		delete(p.pending, item.Path)
+		p.mutex.Unlock()
+		if loaded != nil {
+			loaded()
+		}
+	}
+}
+
+func loadDetails(item *types.ListItem) *Details {
+	details := &Details{ModTime: item.ModTime, ChangeTime: item.ChangeTime, Size: item.Size}
+	info, err := os.Lstat(item.Path)
+	if err != nil {
+		return details
+	}
+	details.Permissions = info.Mode().String()
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		details.Owner = lookupUser(stat.Uid)
+		details.Group = lookupGroup(stat.Gid)
+	}
+	details.MimeType = DetectMimeType(item.Path, info)
+	if strings.HasPrefix(details.MimeType, "image/") {
+		details.Width, details.Height = imageDimensions(item.Path)
+	}
+	return details
+}
+
+// DetectMimeType guesses the MIME type of path from its extension and then
+// from its first bytes.
+func DetectMimeType(path string, info os.FileInfo) string {
+	if info.IsDir() {
+		return "inode/directory"
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "inode/symlink"
+	}
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		return mimeType
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	buffer := make([]byte, 512)
+	n, _ := file.Read(buffer)
+	mimeType, _, _ := strings.Cut(http.DetectContentType(buffer[:n]), ";")
+	return mimeType
+}
+
+func imageDimensions(path string) (int, int) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
+
+var (
+	namesMutex sync.Mutex
+	userNames  = make(map[uint32]string)
+	groupNames = make(map[uint32]string)
+)
+
+func lookupUser(uid uint32) string {
+	namesMutex.Lock()
+	defer namesMutex.Unlock()
+	if name, ok := userNames[uid]; ok {
+		return name
+	}
+	name := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	userNames[uid] = name
+	return name
+}
+
+func lookupGroup(gid uint32) string {
+	namesMutex.Lock()
+	defer namesMutex.Unlock()
+	if name, ok := groupNames[gid]; ok {
+		return name
+	}
+	name := strconv.FormatUint(uint64(gid), 10)
+	if g, err := user.LookupGroupId(name); err == nil {
+		name = g.Name
+	}
+	groupNames[gid] = name
+	return name
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,143 @@
+package compare_popup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/dir_compare"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// CompareWindow asks for the two folders to compare.
+type CompareWindow struct {
+	*gtk.Window
+	Applied func(left, right string, opts dir_compare.Options)
+
+	leftEntry     *gtk.Entry
+	rightEntry    *gtk.Entry
+	contentCheck  *gtk.CheckButton
+	compareButton *gtk.Button
+}
+
+func NewCompareWindow(left, right string) *CompareWindow {
+	cw := &CompareWindow{
+		Window:        gtk.NewWindow(),
+		leftEntry:     gtk.NewEntry(),
+		rightEntry:    gtk.NewEntry(),
+		contentCheck:  gtk.NewCheckButtonWithLabel("Compare contents of files with the same size"),
+		compareButton: gtk.NewButtonWithLabel("Compare"),
+	}
+	cw.leftEntry.SetText(left)
+	cw.leftEntry.SetHExpand(true)
+	cw.rightEntry.SetText(right)
+	cw.rightEntry.SetHExpand(true)
+
+	cw.SetTitle("Compare Folders")
+	cw.SetDefaultSize(520, -1)
+	cw.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	cw.SetChild(box)
+
+	form := gtk.NewGrid()
+	form.SetRowSpacing(6)
+	form.SetColumnSpacing(6)
+	box.Append(form)
+
+	leftLabel := gtk.NewLabel("Left")
+	leftLabel.SetXAlign(0)
+	form.Attach(leftLabel, 0, 0, 1, 1)
+	form.Attach(cw.leftEntry, 1, 0, 1, 1)
+	form.Attach(cw.browseButton(cw.leftEntry), 2, 0, 1, 1)
+
+	rightLabel := gtk.NewLabel("Right")
+	rightLabel.SetXAlign(0)
+	form.Attach(rightLabel, 0, 1, 1, 1)
+	form.Attach(cw.rightEntry, 1, 1, 1, 1)
+	form.Attach(cw.browseButton(cw.rightEntry), 2, 1, 1, 1)
+
+	swapButton := gtk.NewButtonFromIconName("object-flip-vertical-symbolic")
+	swapButton.SetTooltipText("Swap Sides")
+	swapButton.ConnectClicked(func() {
+		left, right := cw.leftEntry.Text(), cw.rightEntry.Text()
+		cw.leftEntry.SetText(right)
+		cw.rightEntry.SetText(left)
+	})
+	form.Attach(swapButton, 3, 0, 1, 2)
+	form.Attach(cw.contentCheck, 1, 2, 3, 1)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(cw.Destroy)
+	cw.compareButton.AddCSSClass("suggested-action")
+	cw.compareButton.ConnectClicked(cw.apply)
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(cw.compareButton)
+	box.Append(buttonBox)
+
+	cw.leftEntry.ConnectChanged(cw.validate)
+	cw.rightEntry.ConnectChanged(cw.validate)
+	cw.rightEntry.ConnectActivate(cw.apply)
+	cw.validate()
+
+	return cw
+}
+
+func (cw *CompareWindow) browseButton(entry *gtk.Entry) *gtk.Button {
+	button := gtk.NewButtonFromIconName("folder-open-symbolic")
+	button.SetTooltipText("Choose Folder")
+	button.ConnectClicked(func() {
+		dialog := gtk.NewFileDialog()
+		dialog.SetTitle("Compare Folder")
+		if filepath.IsAbs(entry.Text()) {
+			dialog.SetInitialFolder(gio.NewFileForPath(entry.Text()))
+		}
+		dialog.SelectFolder(context.Background(), cw.Window, func(res gio.AsyncResulter) {
+			folder, err := dialog.SelectFolderFinish(res)
+			if err != nil || folder == nil {
+				return
+			}
+			entry.SetText(folder.Path())
+		})
+	})
+	return button
+}
+
+func (cw *CompareWindow) folders() (string, string, bool) {
+	left, right := filepath.Clean(cw.leftEntry.Text()), filepath.Clean(cw.rightEntry.Text())
+	if !isDir(left) || !isDir(right) || left == right {
+		return "", "", false
+	}
+	return left, right, true
+}
+
+func isDir(path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (cw *CompareWindow) validate() {
+	_, _, ok := cw.folders()
+	cw.compareButton.SetSensitive(ok)
+}
+
+func (cw *CompareWindow) apply() {
+	left, right, ok := cw.folders()
+	if !ok {
+		return
+	}
+	if cw.Applied != nil {
+		cw.Applied(left, right, dir_compare.Options{CompareContent: cw.contentCheck.Active()})
+	}
+	cw.Destroy()
+}
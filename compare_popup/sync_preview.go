@@ -0,0 +1,146 @@
+package compare_popup
+
+import (
+	"fmt"
+
+	"github.com/MrSametBurgazoglu/atilgan/dir_compare"
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+// SyncWindow is the dry run of a sync. It lists what the chosen mode would
+// copy and delete, and nothing is changed until it is applied.
+type SyncWindow struct {
+	*gtk.Window
+	Applied func(actions []dir_compare.Action)
+
+	result        *dir_compare.Result
+	selected      []string
+	actions       []dir_compare.Action
+	modeDropDown  *gtk.DropDown
+	selectedCheck *gtk.CheckButton
+	summary       *gtk.Label
+	actionList    *gtk.ListBox
+	syncButton    *gtk.Button
+}
+
+// NewSyncWindow previews syncing result. selected are the entries chosen in
+// the view, the sync can be limited to them.
+func NewSyncWindow(result *dir_compare.Result, selected []string) *SyncWindow {
+	sw := &SyncWindow{
+		Window:        gtk.NewWindow(),
+		result:        result,
+		selected:      selected,
+		selectedCheck: gtk.NewCheckButtonWithLabel(fmt.Sprintf("Only the %d selected items", len(selected))),
+		summary:       gtk.NewLabel(""),
+		actionList:    gtk.NewListBox(),
+		syncButton:    gtk.NewButtonWithLabel("Sync"),
+	}
+
+	modeNames := make([]string, len(dir_compare.Modes))
+	for i, mode := range dir_compare.Modes {
+		modeNames[i] = mode.String()
+	}
+	sw.modeDropDown = gtk.NewDropDownFromStrings(modeNames)
+
+	sw.SetTitle("Sync Folders")
+	sw.SetDefaultSize(640, 480)
+	sw.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	sw.SetChild(box)
+
+	form := gtk.NewGrid()
+	form.SetRowSpacing(6)
+	form.SetColumnSpacing(6)
+	box.Append(form)
+
+	modeLabel := gtk.NewLabel("Mode")
+	modeLabel.SetXAlign(0)
+	form.Attach(modeLabel, 0, 0, 1, 1)
+	form.Attach(sw.modeDropDown, 1, 0, 1, 1)
+	sw.selectedCheck.SetVisible(len(selected) > 0)
+	sw.selectedCheck.SetActive(len(selected) > 0)
+	form.Attach(sw.selectedCheck, 1, 1, 1, 1)
+
+	sw.summary.SetXAlign(0)
+	sw.summary.SetWrap(true)
+	box.Append(sw.summary)
+
+	scrolledWindow := gtk.NewScrolledWindow()
+	scrolledWindow.SetVExpand(true)
+	scrolledWindow.SetChild(sw.actionList)
+	box.Append(scrolledWindow)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(sw.Destroy)
+	sw.syncButton.AddCSSClass("suggested-action")
+	sw.syncButton.ConnectClicked(sw.apply)
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(sw.syncButton)
+	box.Append(buttonBox)
+
+	sw.modeDropDown.NotifyProperty("selected", sw.updatePlan)
+	sw.selectedCheck.ConnectToggled(sw.updatePlan)
+	sw.updatePlan()
+
+	return sw
+}
+
+func (sw *SyncWindow) updatePlan() {
+	mode := dir_compare.Modes[sw.modeDropDown.Selected()]
+	var rels []string
+	if sw.selectedCheck.Active() {
+		rels = sw.selected
+	}
+	var skipped []dir_compare.Entry
+	sw.actions, skipped = sw.result.Plan(mode, rels)
+
+	for child := sw.actionList.FirstChild(); child != nil; child = sw.actionList.FirstChild() {
+		sw.actionList.Remove(child)
+	}
+	var copies, deletions int
+	var size int64
+	for _, action := range sw.actions {
+		icon := "edit-copy-symbolic"
+		if action.Kind == dir_compare.DeleteAction {
+			icon = "user-trash-symbolic"
+			deletions++
+		} else {
+			copies++
+			size += action.Size
+		}
+		row := gtk.NewBox(gtk.OrientationHorizontal, 6)
+		row.Append(gtk.NewImageFromIconName(icon))
+		label := gtk.NewLabel(action.String())
+		label.SetXAlign(0)
+		label.SetEllipsize(pango.EllipsizeMiddle)
+		label.SetTooltipText(action.String())
+		row.Append(label)
+		sw.actionList.Append(row)
+	}
+
+	text := fmt.Sprintf("%d to copy (%s), %d to move to the trash", copies, fileops.GetFileSizeAsString(size), deletions)
+	if len(skipped) > 0 {
+		text += fmt.Sprintf(", %d left as they are", len(skipped))
+	}
+	sw.summary.SetText(text)
+	sw.syncButton.SetSensitive(len(sw.actions) > 0)
+}
+
+func (sw *SyncWindow) apply() {
+	if len(sw.actions) == 0 {
+		return
+	}
+	if sw.Applied != nil {
+		sw.Applied(sw.actions)
+	}
+	sw.Destroy()
+}
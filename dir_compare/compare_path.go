@@ -0,0 +1,103 @@
+package dir_compare
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+const (
+	Scheme    = "compare://"
+	separator = "::"
+)
+
+// PathFor returns the compare view of left and right.
+func PathFor(left, right string) string {
+	return Scheme + left + separator + right
+}
+
+// statusOrder is the order the groups of the view are listed in.
+var statusOrder = []Status{OnlyLeft, OnlyRight, LeftNewer, RightNewer, Different}
+
+// ComparePath lists the last comparison of two folders grouped by how the
+// entries differ.
+type ComparePath struct {
+	left    string
+	right   string
+	manager *Manager
+}
+
+func NewComparePath(path string, manager *Manager) *ComparePath {
+	left, right, _ := strings.Cut(strings.TrimPrefix(path, Scheme), separator)
+	return &ComparePath{left: left, right: right, manager: manager}
+}
+
+func (c *ComparePath) GetItems() []*types.ListItem {
+	result := c.Result()
+	if result == nil {
+		return nil
+	}
+
+	var items []*types.ListItem
+	for _, status := range statusOrder {
+		for _, entry := range result.Entries {
+			if entry.Status != status {
+				continue
+			}
+			// The item stands for the side that has the entry, the left
+			// one when both do.
+			path, side := filepath.Join(c.left, entry.Rel), entry.Left
+			if side == nil {
+				path, side = filepath.Join(c.right, entry.Rel), entry.Right
+			}
+			items = append(items, &types.ListItem{
+				Name:        entry.Rel,
+				IsDir:       side.IsDir,
+				Path:        path,
+				Group:       status.String(),
+				Size:        side.Size,
+				ModTime:     side.ModTime,
+				CreatedTime: side.ModTime,
+				SpecialInfo: describe(entry),
+			})
+		}
+	}
+	return items
+}
+
+func describe(entry Entry) string {
+	if entry.Left == nil || entry.Right == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s, %s ⇄ %s, %s",
+		fileops.GetFileSizeAsString(entry.Left.Size), fileops.GetModifiedTimeAsString(entry.Left.ModTime),
+		fileops.GetFileSizeAsString(entry.Right.Size), fileops.GetModifiedTimeAsString(entry.Right.ModTime))
+}
+
+func (c *ComparePath) GetPath() string {
+	return PathFor(c.left, c.right)
+}
+
+func (c *ComparePath) GetParentPath() string {
+	return c.left
+}
+
+func (c *ComparePath) GetName() string {
+	return fmt.Sprintf("%s ⇄ %s", filepath.Base(c.left), filepath.Base(c.right))
+}
+
+func (c *ComparePath) Left() string {
+	return c.left
+}
+
+func (c *ComparePath) Right() string {
+	return c.right
+}
+
+// Result returns the last comparison, or nil before the first one.
+func (c *ComparePath) Result() *Result {
+	return c.manager.Get(c.left, c.right)
+}
@@ -0,0 +1,209 @@
+package dir_compare
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/checksum"
+)
+
+type Status int
+
+const (
+	Same Status = iota
+	OnlyLeft
+	OnlyRight
+	LeftNewer
+	RightNewer
+	// Different files have the same modification time but not the same
+	// size or content, so neither side can be said to be newer.
+	Different
+)
+
+func (s Status) String() string {
+	switch s {
+	case OnlyLeft:
+		return "Only on the Left"
+	case OnlyRight:
+		return "Only on the Right"
+	case LeftNewer:
+		return "Newer on the Left"
+	case RightNewer:
+		return "Newer on the Right"
+	case Different:
+		return "Different"
+	default:
+		return "Same"
+	}
+}
+
+// modifyWindow is how far apart two modification times may be and still be
+// equal. FAT drives store times in two second steps.
+const modifyWindow = 2 * time.Second
+
+type Options struct {
+	// CompareContent hashes files whose size and time match so that
+	// changes that kept both are found too.
+	CompareContent bool
+}
+
+// Side is a file or folder as found on one side.
+type Side struct {
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Entry is a path, relative to both roots, that isn't the same on both
+// sides. A folder that exists on one side only is a single entry.
+type Entry struct {
+	Rel    string
+	Status Status
+	Left   *Side
+	Right  *Side
+}
+
+type comparer struct {
+	ctx         context.Context
+	left, right string
+	opts        Options
+	entries     []Entry
+	candidates  []int
+}
+
+// Compare walks left and right together and returns the entries that
+// differ, sorted by path. progress gets the fraction of the bytes hashed
+// when contents are compared.
+func Compare(ctx context.Context, left, right string, opts Options, progress func(float64)) ([]Entry, error) {
+	c := &comparer{ctx: ctx, left: left, right: right, opts: opts}
+	if err := c.compareDir("."); err != nil {
+		return nil, err
+	}
+	if err := c.hashCandidates(progress); err != nil {
+		return nil, err
+	}
+
+	entries := c.entries[:0]
+	for _, entry := range c.entries {
+		if entry.Status != Same {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Rel < entries[j].Rel })
+	return entries, nil
+}
+
+func readSide(dir string) (map[string]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]os.FileInfo, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos[entry.Name()] = info
+	}
+	return infos, nil
+}
+
+func sideOf(info os.FileInfo) *Side {
+	return &Side{Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}
+}
+
+func (c *comparer) compareDir(rel string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	leftInfos, err := readSide(filepath.Join(c.left, rel))
+	if err != nil {
+		return err
+	}
+	rightInfos, err := readSide(filepath.Join(c.right, rel))
+	if err != nil {
+		return err
+	}
+
+	for name, leftInfo := range leftInfos {
+		path := filepath.Join(rel, name)
+		rightInfo, ok := rightInfos[name]
+		if !ok {
+			c.entries = append(c.entries, Entry{Rel: path, Status: OnlyLeft, Left: sideOf(leftInfo)})
+			continue
+		}
+		if leftInfo.IsDir() && rightInfo.IsDir() {
+			if err := c.compareDir(path); err != nil {
+				return err
+			}
+			continue
+		}
+		c.compareFiles(path, sideOf(leftInfo), sideOf(rightInfo))
+	}
+	for name, rightInfo := range rightInfos {
+		if _, ok := leftInfos[name]; !ok {
+			c.entries = append(c.entries, Entry{Rel: filepath.Join(rel, name), Status: OnlyRight, Right: sideOf(rightInfo)})
+		}
+	}
+	return nil
+}
+
+func (c *comparer) compareFiles(rel string, left, right *Side) {
+	entry := Entry{Rel: rel, Left: left, Right: right}
+	delta := left.ModTime.Sub(right.ModTime)
+	switch {
+	case left.IsDir != right.IsDir:
+		// Neither can be copied over the other, whatever their times.
+		entry.Status = Different
+	case delta > modifyWindow:
+		entry.Status = LeftNewer
+	case delta < -modifyWindow:
+		entry.Status = RightNewer
+	case left.Size != right.Size:
+		entry.Status = Different
+	default:
+		entry.Status = Same
+	}
+	// Files that only differ in time are hashed too, a copy that lost its
+	// time shouldn't be copied again.
+	if c.opts.CompareContent && !left.IsDir && !right.IsDir && left.Size == right.Size && entry.Status != Different {
+		c.candidates = append(c.candidates, len(c.entries))
+	}
+	c.entries = append(c.entries, entry)
+}
+
+// hashCandidates settles the entries whose size matches by content.
+func (c *comparer) hashCandidates(progress func(float64)) error {
+	var total, done int64
+	for _, i := range c.candidates {
+		total += c.entries[i].Left.Size * 2
+	}
+	report := func(n int64) {
+		done += n
+		if progress != nil && total > 0 {
+			progress(float64(done) / float64(total))
+		}
+	}
+	algorithms := []checksum.Algorithm{checksum.BLAKE3}
+	for _, i := range c.candidates {
+		entry := &c.entries[i]
+		leftSums, err := checksum.File(c.ctx, filepath.Join(c.left, entry.Rel), algorithms, report)
+		if err != nil {
+			return err
+		}
+		rightSums, err := checksum.File(c.ctx, filepath.Join(c.right, entry.Rel), algorithms, report)
+		if err != nil {
+			return err
+		}
+		if leftSums[checksum.BLAKE3] == rightSums[checksum.BLAKE3] {
+			entry.Status = Same
+		} else if entry.Status == Same {
+			entry.Status = Different
+		}
+	}
+	return nil
+}
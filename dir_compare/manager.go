@@ -0,0 +1,49 @@
+package dir_compare
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of comparing Left with Right.
+type Result struct {
+	Left       string
+	Right      string
+	Options    Options
+	Entries    []Entry
+	ComparedAt time.Time
+}
+
+// Entry returns the entry that path, on either side, belongs to.
+func (r *Result) Entry(path string) *Entry {
+	for i, entry := range r.Entries {
+		if filepath.Join(r.Left, entry.Rel) == path || filepath.Join(r.Right, entry.Rel) == path {
+			return &r.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Manager keeps the last comparison of every pair of folders so the
+// compare:// view can be listed again without comparing.
+type Manager struct {
+	mu      sync.Mutex
+	results map[string]*Result
+}
+
+func NewManager() *Manager {
+	return &Manager{results: make(map[string]*Result)}
+}
+
+func (m *Manager) Set(left, right string, opts Options, entries []Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[PathFor(left, right)] = &Result{Left: left, Right: right, Options: opts, Entries: entries, ComparedAt: time.Now()}
+}
+
+func (m *Manager) Get(left, right string) *Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.results[PathFor(left, right)]
+}
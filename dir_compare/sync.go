@@ -0,0 +1,151 @@
+package dir_compare
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/trash"
+)
+
+type Mode int
+
+const (
+	LeftToRight Mode = iota
+	RightToLeft
+	BothWays
+	MirrorToRight
+	MirrorToLeft
+)
+
+var Modes = []Mode{LeftToRight, RightToLeft, BothWays, MirrorToRight, MirrorToLeft}
+
+func (m Mode) String() string {
+	switch m {
+	case RightToLeft:
+		return "Sync Right to Left"
+	case BothWays:
+		return "Sync Both Ways"
+	case MirrorToRight:
+		return "Mirror Left to Right"
+	case MirrorToLeft:
+		return "Mirror Right to Left"
+	default:
+		return "Sync Left to Right"
+	}
+}
+
+type ActionKind int
+
+const (
+	CopyAction ActionKind = iota
+	DeleteAction
+)
+
+// Action is one step of a sync. Source is empty for deletions.
+type Action struct {
+	Kind        ActionKind
+	Rel         string
+	Source      string
+	Destination string
+	Size        int64
+}
+
+func (a Action) String() string {
+	if a.Kind == DeleteAction {
+		return fmt.Sprintf("Delete %s", a.Destination)
+	}
+	return fmt.Sprintf("Copy %s to %s", a.Source, a.Destination)
+}
+
+// Plan lists the actions that bring the sides in step for the entries in
+// rels, or for every entry when rels is nil. One way syncs never replace a
+// newer file and only mirrors delete. Entries that can't be settled, like
+// different files in a two way sync, are returned as skipped.
+func (r *Result) Plan(mode Mode, rels []string) (actions []Action, skipped []Entry) {
+	wanted := make(map[string]bool, len(rels))
+	for _, rel := range rels {
+		wanted[rel] = true
+	}
+
+	for _, entry := range r.Entries {
+		if rels != nil && !wanted[entry.Rel] {
+			continue
+		}
+		toRight := Action{Kind: CopyAction, Rel: entry.Rel, Source: filepath.Join(r.Left, entry.Rel), Destination: filepath.Join(r.Right, entry.Rel)}
+		toLeft := Action{Kind: CopyAction, Rel: entry.Rel, Source: filepath.Join(r.Right, entry.Rel), Destination: filepath.Join(r.Left, entry.Rel)}
+		if entry.Left != nil {
+			toRight.Size = entry.Left.Size
+		}
+		if entry.Right != nil {
+			toLeft.Size = entry.Right.Size
+		}
+
+		var action *Action
+		switch mode {
+		case LeftToRight:
+			if entry.Status == OnlyLeft || entry.Status == LeftNewer || entry.Status == Different {
+				action = &toRight
+			}
+		case RightToLeft:
+			if entry.Status == OnlyRight || entry.Status == RightNewer || entry.Status == Different {
+				action = &toLeft
+			}
+		case BothWays:
+			switch entry.Status {
+			case OnlyLeft, LeftNewer:
+				action = &toRight
+			case OnlyRight, RightNewer:
+				action = &toLeft
+			}
+		case MirrorToRight:
+			action = &toRight
+			if entry.Status == OnlyRight {
+				action = &Action{Kind: DeleteAction, Rel: entry.Rel, Destination: filepath.Join(r.Right, entry.Rel)}
+			}
+		case MirrorToLeft:
+			action = &toLeft
+			if entry.Status == OnlyLeft {
+				action = &Action{Kind: DeleteAction, Rel: entry.Rel, Destination: filepath.Join(r.Left, entry.Rel)}
+			}
+		}
+
+		if action != nil {
+			actions = append(actions, *action)
+		} else {
+			skipped = append(skipped, entry)
+		}
+	}
+	return actions, skipped
+}
+
+// Apply runs the actions of a plan. Deleted items go to the trash. progress
+// gets the fraction of the bytes copied so far.
+func Apply(ctx context.Context, actions []Action, progress func(float64)) []error {
+	var total, done int64
+	for _, action := range actions {
+		total += action.Size
+	}
+
+	var errs []error
+	for _, action := range actions {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		var err error
+		if action.Kind == DeleteAction {
+			err = trash.MoveToTrash(action.Destination)
+		} else {
+			err = fileops.SyncCopy(action.Source, action.Destination)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s failed: %w", action, err))
+		}
+		done += action.Size
+		if progress != nil && total > 0 {
+			progress(float64(done) / float64(total))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,149 @@
+package dir_watch
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const events = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_DELETE
+
+// Watcher notices files that are added to a set of folders. Folders are
+// watched on their own, not the ones below them.
+type Watcher struct {
+	fd int
+
+	mu    sync.Mutex
+	dirs  map[int32]string
+	byDir map[string]int32
+	// created holds the files created but not written and closed yet.
+	created map[string]bool
+
+	// Added is called, from another goroutine, with a file that was created
+	// and written or moved into one of the folders.
+	Added func(path string)
+}
+
+func NewWatcher() (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fd:      fd,
+		dirs:    make(map[int32]string),
+		byDir:   make(map[string]int32),
+		created: make(map[string]bool),
+	}
+	go w.watch()
+	return w, nil
+}
+
+func (w *Watcher) Add(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.byDir[dir]; ok {
+		return nil
+	}
+	wd, err := unix.InotifyAddWatch(w.fd, dir, events|unix.IN_ONLYDIR)
+	if err != nil {
+		return err
+	}
+	w.dirs[int32(wd)] = dir
+	w.byDir[dir] = int32(wd)
+	return nil
+}
+
+func (w *Watcher) Remove(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wd, ok := w.byDir[dir]
+	if !ok {
+		return
+	}
+	unix.InotifyRmWatch(w.fd, uint32(wd))
+	delete(w.dirs, wd)
+	delete(w.byDir, dir)
+}
+
+// Set watches exactly dirs.
+func (w *Watcher) Set(dirs []string) {
+	w.mu.Lock()
+	var stale []string
+	for dir := range w.byDir {
+		stale = append(stale, dir)
+	}
+	w.mu.Unlock()
+	wanted := make(map[string]bool)
+	for _, dir := range dirs {
+		wanted[dir] = true
+		if err := w.Add(dir); err != nil {
+			println("couldn't watch", dir, err.Error())
+		}
+	}
+	for _, dir := range stale {
+		if !wanted[dir] {
+			w.Remove(dir)
+		}
+	}
+}
+
+func (w *Watcher) watch() {
+	buffer := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(w.fd, buffer)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			println("stopped watching folders:", err.Error())
+			return
+		}
+		var added []string
+		w.mu.Lock()
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			start := offset + unix.SizeofInotifyEvent
+			end := start + int(event.Len)
+			offset = end
+			dir, ok := w.dirs[event.Wd]
+			if event.Mask&unix.IN_IGNORED != 0 {
+				// The folder is gone.
+				delete(w.dirs, event.Wd)
+				if ok && w.byDir[dir] == event.Wd {
+					delete(w.byDir, dir)
+				}
+				continue
+			}
+			if !ok || event.Mask&unix.IN_ISDIR != 0 {
+				continue
+			}
+			path := filepath.Join(dir, strings.TrimRight(string(buffer[start:end]), "\x00"))
+			switch {
+			case event.Mask&unix.IN_CREATE != 0:
+				w.created[path] = true
+			case event.Mask&unix.IN_CLOSE_WRITE != 0:
+				// Files that were only changed are not new.
+				if w.created[path] {
+					delete(w.created, path)
+					added = append(added, path)
+				}
+			case event.Mask&unix.IN_MOVED_TO != 0:
+				delete(w.created, path)
+				added = append(added, path)
+			case event.Mask&unix.IN_DELETE != 0:
+				delete(w.created, path)
+			}
+		}
+		w.mu.Unlock()
+		if w.Added != nil {
+			for _, path := range added {
+				w.Added(path)
+			}
+		}
+	}
+}
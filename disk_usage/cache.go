@@ -0,0 +1,107 @@
+package disk_usage
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type scan struct {
+	root      *Node
+	scannedAt time.Time
+}
+
+// Cache keeps scanned trees so that folders below a scanned root can be
+// shown without reading them again.
+type Cache struct {
+	mu       sync.Mutex
+	scans    map[string]*scan
+	scanning map[string]bool
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		scans:    make(map[string]*scan),
+		scanning: make(map[string]bool),
+	}
+}
+
+// Store adds a scanned tree, replacing scans of folders inside it.
+func (c *Cache) Store(root *Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.scans {
+		if isWithin(path, root.Path) {
+			delete(c.scans, path)
+		}
+	}
+	c.scans[root.Path] = &scan{root: root, scannedAt: time.Now()}
+	delete(c.scanning, root.Path)
+}
+
+// Lookup returns the node of path from the most specific scan that
+// contains it.
+func (c *Cache) Lookup(path string) *Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path = filepath.Clean(path)
+	var best *scan
+	for root, s := range c.scans {
+		if isWithin(path, root) && (best == nil || len(root) > len(best.root.Path)) {
+			best = s
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	node := best.root
+	rel, err := filepath.Rel(best.root.Path, path)
+	if err != nil || rel == "." {
+		return node
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if node = node.Child(name); node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Size returns the scanned size of path.
+func (c *Cache) Size(path string) (int64, bool) {
+	node := c.Lookup(path)
+	if node == nil {
+		return 0, false
+	}
+	return node.Size, true
+}
+
+// StartScan marks root as being scanned and reports false when it already
+// is.
+func (c *Cache) StartScan(root string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.scanning[root] {
+		return false
+	}
+	c.scanning[root] = true
+	return true
+}
+
+// CancelScan clears the mark of a scan that didn't finish.
+func (c *Cache) CancelScan(root string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.scanning, root)
+}
+
+func (c *Cache) IsScanning(root string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scanning[root]
+}
+
+func isWithin(path, root string) bool {
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
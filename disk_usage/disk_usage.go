@@ -0,0 +1,223 @@
+package disk_usage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// Node is a file or folder of a scanned tree. The size of a folder is the
+// apparent size of everything below it, with hard linked files counted
+// once.
+type Node struct {
+	Name     string
+	Path     string
+	Size     int64
+	IsDir    bool
+	Files    int
+	Children []*Node
+}
+
+type Options struct {
+	// OneFileSystem skips folders on other filesystems than the root, like
+	// du -x.
+	OneFileSystem bool
+}
+
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+type scanner struct {
+	ctx     context.Context
+	opts    Options
+	rootDev uint64
+	wg      sync.WaitGroup
+	slots   chan struct{}
+	mu      sync.Mutex
+	seen    map[inode]bool
+}
+
+// Scan measures the tree below root. Folders are read in parallel. progress
+// gets the fraction of the top level folders that are done.
+func Scan(ctx context.Context, root string, opts Options, progress func(float64)) (*Node, error) {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return nil, err
+	}
+	s := &scanner{
+		ctx:   ctx,
+		opts:  opts,
+		slots: make(chan struct{}, runtime.NumCPU()*2),
+		seen:  make(map[inode]bool),
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		s.rootDev = uint64(stat.Dev)
+	}
+
+	node := &Node{Name: filepath.Base(root), Path: root, IsDir: info.IsDir()}
+	if !node.IsDir {
+		node.Size = info.Size()
+		node.Files = 1
+		return node, nil
+	}
+
+	children := s.readDir(node)
+	var doneMu sync.Mutex
+	done := 0
+	for _, child := range children {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.slots <- struct{}{}
+			s.scanDir(child)
+			<-s.slots
+			doneMu.Lock()
+			done++
+			if progress != nil {
+				progress(float64(done) / float64(len(children)))
+			}
+			doneMu.Unlock()
+		}()
+	}
+	s.wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	total(node)
+	return node, nil
+}
+
+// readDir adds the entries of node as children and returns the folders
+// among them that still need to be read.
+func (s *scanner) readDir(node *Node) []*Node {
+	entries, err := os.ReadDir(node.Path)
+	if err != nil {
+		return nil
+	}
+	var dirs []*Node
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		child := &Node{Name: entry.Name(), Path: filepath.Join(node.Path, entry.Name()), IsDir: info.IsDir()}
+		stat, hasStat := info.Sys().(*syscall.Stat_t)
+		if child.IsDir {
+			if s.opts.OneFileSystem && hasStat && uint64(stat.Dev) != s.rootDev {
+				continue
+			}
+			dirs = append(dirs, child)
+		} else {
+			child.Files = 1
+			child.Size = info.Size()
+			if hasStat && stat.Nlink > 1 && s.linked(inode{dev: uint64(stat.Dev), ino: stat.Ino}) {
+				child.Size = 0
+			}
+		}
+		node.Children = append(node.Children, child)
+	}
+	return dirs
+}
+
+// linked reports whether another link to the same file was already
+// counted.
+func (s *scanner) linked(key inode) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return true
+	}
+	s.seen[key] = true
+	return false
+}
+
+// scanDir reads node and everything below it. Subfolders are handed to new
+// goroutines while there are free slots and read in place otherwise.
+func (s *scanner) scanDir(node *Node) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	for _, dir := range s.readDir(node) {
+		select {
+		case s.slots <- struct{}{}:
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.scanDir(dir)
+				<-s.slots
+			}()
+		default:
+			s.scanDir(dir)
+		}
+	}
+}
+
+// total sums the sizes of folders and sorts children largest first.
+func total(node *Node) {
+	if !node.IsDir {
+		return
+	}
+	node.Size, node.Files = 0, 0
+	for _, child := range node.Children {
+		total(child)
+		node.Size += child.Size
+		node.Files += child.Files
+	}
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Size != node.Children[j].Size {
+			return node.Children[i].Size > node.Children[j].Size
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+}
+
+// Child returns the direct child called name.
+func (n *Node) Child(name string) *Node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// LargestFiles returns up to limit files anywhere below n, largest first.
+func (n *Node) LargestFiles(limit int) []*Node {
+	var files []*Node
+	var collect func(node *Node)
+	collect = func(node *Node) {
+		for _, child := range node.Children {
+			if child.IsDir {
+				collect(child)
+				continue
+			}
+			files = append(files, child)
+		}
+	}
+	collect(n)
+	sort.Slice(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files
+}
+
+// LargestFolders returns up to limit direct subfolders of n, largest first.
+func (n *Node) LargestFolders(limit int) []*Node {
+	var folders []*Node
+	for _, child := range n.Children {
+		if child.IsDir {
+			folders = append(folders, child)
+		}
+		if len(folders) == limit {
+			break
+		}
+	}
+	return folders
+}
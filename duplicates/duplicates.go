@@ -0,0 +1,247 @@
+package duplicates
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/checksum"
+	"golang.org/x/sync/errgroup"
+)
+
+// blockSize is how much of the start and the end of a file is compared
+// before the whole file is hashed.
+const blockSize = 4096
+
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Group is a set of files with identical content.
+type Group struct {
+	Size  int64
+	Files []File
+}
+
+// Reclaimable returns the space freed by keeping a single copy.
+func (g *Group) Reclaimable() int64 {
+	return g.Size * int64(len(g.Files)-1)
+}
+
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+// Find looks for files with identical content below root. Files are first
+// grouped by size, then by a hash of their first and last block and only
+// then by a hash of their whole content. Hard links to the same file are
+// counted once. Groups are returned with the most reclaimable space first.
+func Find(ctx context.Context, root string, progress func(float64)) ([]Group, error) {
+	report := func(fraction float64) {
+		if progress != nil {
+			progress(fraction)
+		}
+	}
+
+	bySize := make(map[int64][]File)
+	seen := make(map[inode]bool)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable folders are skipped instead of ending the scan.
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 {
+			return nil
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			key := inode{dev: uint64(stat.Dev), ino: stat.Ino}
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+		}
+		bySize[info.Size()] = append(bySize[info.Size()], File{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates [][]File
+	var partialCount int
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files)
+			partialCount += len(files)
+		}
+	}
+	report(0.1)
+
+	// Files no larger than two blocks are fully hashed by the partial hash.
+	var done int
+	candidates, err = splitByHash(ctx, candidates, partialHash, func() {
+		done++
+		report(0.1 + 0.2*float64(done)/float64(partialCount))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	var large [][]File
+	var fullBytes, hashedBytes int64
+	for _, files := range candidates {
+		if files[0].Size <= 2*blockSize {
+			groups = append(groups, Group{Size: files[0].Size, Files: files})
+			continue
+		}
+		large = append(large, files)
+		fullBytes += files[0].Size * int64(len(files))
+	}
+	var mu sync.Mutex
+	large, err = splitByHash(ctx, large, func(ctx context.Context, file File) (string, error) {
+		sums, err := checksum.File(ctx, file.Path, []checksum.Algorithm{checksum.BLAKE3}, func(n int64) {
+			mu.Lock()
+			hashedBytes += n
+			fraction := float64(hashedBytes) / float64(fullBytes)
+			mu.Unlock()
+			report(0.3 + 0.7*fraction)
+		})
+		if err != nil {
+			return "", err
+		}
+		return sums[checksum.BLAKE3], nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, files := range large {
+		groups = append(groups, Group{Size: files[0].Size, Files: files})
+	}
+
+	for _, group := range groups {
+		sort.Slice(group.Files, func(i, j int) bool {
+			return group.Files[i].Path < group.Files[j].Path
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Reclaimable() != groups[j].Reclaimable() {
+			return groups[i].Reclaimable() > groups[j].Reclaimable()
+		}
+		return groups[i].Files[0].Path < groups[j].Files[0].Path
+	})
+	report(1)
+	return groups, nil
+}
+
+// splitByHash splits every set of files by hash and keeps the subsets with
+// more than one file. Files that can't be read are left out.
+func splitByHash(ctx context.Context, sets [][]File, hash func(context.Context, File) (string, error), hashed func()) ([][]File, error) {
+	type job struct {
+		set  int
+		file File
+	}
+	var jobs []job
+	for i, files := range sets {
+		for _, file := range files {
+			jobs = append(jobs, job{set: i, file: file})
+		}
+	}
+
+	hashes := make([]string, len(jobs))
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(runtime.NumCPU())
+	for i, j := range jobs {
+		group.Go(func() error {
+			sum, err := hash(groupCtx, j.file)
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
+			if err == nil {
+				hashes[i] = sum
+			}
+			if hashed != nil {
+				mu.Lock()
+				hashed()
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		set  int
+		hash string
+	}
+	var keys []key
+	byHash := make(map[key][]File)
+	for i, j := range jobs {
+		if hashes[i] == "" {
+			continue
+		}
+		k := key{set: j.set, hash: hashes[i]}
+		if _, found := byHash[k]; !found {
+			keys = append(keys, k)
+		}
+		byHash[k] = append(byHash[k], j.file)
+	}
+
+	var result [][]File
+	for _, k := range keys {
+		if len(byHash[k]) > 1 {
+			result = append(result, byHash[k])
+		}
+	}
+	return result, nil
+}
+
+func partialHash(ctx context.Context, file File) (string, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := checksum.BLAKE3.New()
+	if file.Size <= 2*blockSize {
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	buffer := make([]byte, blockSize)
+	if _, err := io.ReadFull(f, buffer); err != nil {
+		return "", err
+	}
+	h.Write(buffer)
+	if _, err := f.ReadAt(buffer, file.Size-blockSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	h.Write(buffer)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
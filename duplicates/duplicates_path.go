@@ -0,0 +1,81 @@
+package duplicates
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+const Scheme = "duplicates://"
+
+// PathFor returns the duplicates view of root.
+func PathFor(root string) string {
+	return Scheme + root
+}
+
+// DuplicatesPath lists the duplicates found by the last scan of a root,
+// one group header per set of identical files.
+type DuplicatesPath struct {
+	root    string
+	manager *Manager
+}
+
+func NewDuplicatesPath(path string, manager *Manager) *DuplicatesPath {
+	return &DuplicatesPath{
+		root:    strings.TrimPrefix(path, Scheme),
+		manager: manager,
+	}
+}
+
+func (d *DuplicatesPath) GetItems() []*types.ListItem {
+	result := d.manager.Get(d.root)
+	if result == nil {
+		return nil
+	}
+	result.Prune()
+
+	var items []*types.ListItem
+	for i, group := range result.Groups {
+		// The number keeps neighbouring groups apart when their text is
+		// the same.
+		header := fmt.Sprintf("%d. %d copies of %s, %s reclaimable", i+1, len(group.Files),
+			fileops.GetFileSizeAsString(group.Size), fileops.GetFileSizeAsString(group.Reclaimable()))
+		for _, file := range group.Files {
+			items = append(items, &types.ListItem{
+				Name:        filepath.Base(file.Path),
+				Path:        file.Path,
+				Group:       header,
+				Size:        file.Size,
+				ModTime:     file.ModTime,
+				CreatedTime: file.ModTime,
+				SpecialInfo: filepath.Dir(file.Path),
+			})
+		}
+	}
+	return items
+}
+
+func (d *DuplicatesPath) GetPath() string {
+	return PathFor(d.root)
+}
+
+func (d *DuplicatesPath) GetParentPath() string {
+	return d.root
+}
+
+func (d *DuplicatesPath) GetName() string {
+	return "Duplicates in " + filepath.Base(d.root)
+}
+
+// Root returns the folder that is scanned.
+func (d *DuplicatesPath) Root() string {
+	return d.root
+}
+
+// Result returns the last scan of the root, or nil before the first one.
+func (d *DuplicatesPath) Result() *Result {
+	return d.manager.Get(d.root)
+}
@@ -0,0 +1,200 @@
+package duplicates
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/trash"
+)
+
+// Result is the outcome of a scan of Root.
+type Result struct {
+	Root      string
+	Groups    []Group
+	ScannedAt time.Time
+}
+
+// Manager keeps the last scan of every root so the duplicates:// view can
+// be listed again without scanning.
+type Manager struct {
+	mu      sync.Mutex
+	results map[string]*Result
+}
+
+func NewManager() *Manager {
+	return &Manager{results: make(map[string]*Result)}
+}
+
+func (m *Manager) Set(root string, groups []Group) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[root] = &Result{Root: root, Groups: groups, ScannedAt: time.Now()}
+}
+
+func (m *Manager) Get(root string) *Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.results[root]
+}
+
+// Prune drops files that no longer exist and groups left with one file.
+func (r *Result) Prune() {
+	groups := r.Groups[:0]
+	for _, group := range r.Groups {
+		files := group.Files[:0]
+		for _, file := range group.Files {
+			if _, err := os.Lstat(file.Path); err == nil {
+				files = append(files, file)
+			}
+		}
+		group.Files = files
+		if len(files) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	r.Groups = groups
+}
+
+// AllButNewest returns every file except the most recently modified one of
+// each group.
+func (r *Result) AllButNewest() []string {
+	return r.allBut(func(a, b File) bool { return a.ModTime.After(b.ModTime) })
+}
+
+// AllButOldest returns every file except the least recently modified one
+// of each group.
+func (r *Result) AllButOldest() []string {
+	return r.allBut(func(a, b File) bool { return a.ModTime.Before(b.ModTime) })
+}
+
+func (r *Result) allBut(first func(a, b File) bool) []string {
+	var paths []string
+	for _, group := range r.Groups {
+		files := append([]File(nil), group.Files...)
+		sort.SliceStable(files, func(i, j int) bool { return first(files[i], files[j]) })
+		for _, file := range files[1:] {
+			paths = append(paths, file.Path)
+		}
+	}
+	return paths
+}
+
+// plan splits selected by group and picks an unselected file of each group
+// to keep. Groups whose files are all selected are refused so that no
+// content is lost.
+func (r *Result) plan(selected []string) (map[string][]string, []error) {
+	isSelected := make(map[string]bool, len(selected))
+	for _, path := range selected {
+		isSelected[path] = true
+	}
+
+	removals := make(map[string][]string)
+	var errs []error
+	for _, group := range r.Groups {
+		keep := ""
+		var kept File
+		var remove []string
+		for _, file := range group.Files {
+			if isSelected[file.Path] {
+				remove = append(remove, file.Path)
+			} else if keep == "" {
+				keep = file.Path
+				kept = file
+			}
+		}
+		if len(remove) == 0 {
+			continue
+		}
+		if keep == "" {
+			errs = append(errs, fmt.Errorf("every copy of %s is selected, leave one unselected", filepath.Base(group.Files[0].Path)))
+			continue
+		}
+		// The copy that is kept must still be the one that was compared.
+		if err := checkUnchanged(keep, kept); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removals[keep] = remove
+	}
+	return removals, errs
+}
+
+// Trash moves the selected duplicates to the trash.
+func (r *Result) Trash(selected []string) []error {
+	removals, errs := r.plan(selected)
+	for _, remove := range removals {
+		if err := trash.MoveToTrash(remove...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Hardlink replaces the selected duplicates with hard links to the copy
+// that is kept, so the content is stored once.
+func (r *Result) Hardlink(selected []string) []error {
+	removals, errs := r.plan(selected)
+	for keep, remove := range removals {
+		for _, path := range remove {
+			if err := replaceWithLink(keep, path, r.file(keep), r.file(path)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+func (r *Result) file(path string) File {
+	for _, group := range r.Groups {
+		for _, file := range group.Files {
+			if file.Path == path {
+				return file
+			}
+		}
+	}
+	return File{}
+}
+
+// replaceWithLink links path to keep through a temporary name so path is
+// never missing. Nothing is done when either changed since the scan.
+func replaceWithLink(keep, path string, kept, scanned File) error {
+	if err := checkUnchanged(keep, kept); err != nil {
+		return err
+	}
+	if err := checkUnchanged(path, scanned); err != nil {
+		return err
+	}
+
+	temp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.atilgan-link", filepath.Base(path)))
+	os.Remove(temp)
+	if err := os.Link(keep, temp); err != nil {
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			return fmt.Errorf("couldn't link %s: %w", path, linkErr.Err)
+		}
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// checkUnchanged returns an error when the file at path no longer has the
+// size and modification time it had in the scan.
+func checkUnchanged(path string, scanned File) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != scanned.Size || !info.ModTime().Equal(scanned.ModTime) {
+		return fmt.Errorf("%s changed since the scan", path)
+	}
+	return nil
+}
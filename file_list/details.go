@@ -0,0 +1,229 @@
+package file_list
+
+import (
+	"math"
+
+	"github.com/MrSametBurgazoglu/atilgan/columns"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+const (
+	columnHeaderHeight = 24
+	columnPadding      = 6
+	resizeHandleWidth  = 4
+)
+
+type columnDrag struct {
+	active     bool
+	index      int
+	resizing   bool
+	startX     float64
+	startWidth int
+}
+
+func (fl *FileList) SetDetailsMode(detailsMode bool) {
+	fl.DetailsMode = detailsMode
+	if !detailsMode {
+		fl.DrawingArea.SetContentWidth(0)
+	}
+	fl.DrawingArea.QueueDraw()
+}
+
+func (fl *FileList) SetLayout(layout columns.Layout) {
+	fl.Layout = layout
+	fl.DrawingArea.QueueDraw()
+}
+
+// contentTop is where the first row starts, below the column header in
+// details mode.
+func (fl *FileList) contentTop() int {
+	if fl.DetailsMode {
+		return columnHeaderHeight
+	}
+	return 0
+}
+
+func (fl *FileList) inColumnHeader(y float64) bool {
+	if !fl.DetailsMode {
+		return false
+	}
+	scroll := fl.VAdjustment().Value()
+	return y >= scroll && y < scroll+columnHeaderHeight
+}
+
+// columnAt returns the layout index of the column at x and whether x is on
+// the resize handle at its right edge.
+func (fl *FileList) columnAt(x float64) (int, bool) {
+	left := 0
+	for i, cl := range fl.Layout {
+		right := left + cl.Width
+		if math.Abs(x-float64(right)) <= resizeHandleWidth {
+			return i, true
+		}
+		if x >= float64(left) && x < float64(right) {
+			return i, false
+		}
+		left = right
+	}
+	return len(fl.Layout) - 1, false
+}
+
+func (fl *FileList) drawColumnHeader(cr *cairo.Context) {
+	y := fl.VAdjustment().Value()
+	width := float64(max(fl.Layout.TotalWidth(), fl.DrawingArea.Width()))
+
+	setSourceColor(cr, fl.theme.HeaderBackgroundColor)
+	cr.Rectangle(0, y, width, columnHeaderHeight)
+	cr.Fill()
+
+	left := 0
+	for i, cl := range fl.Layout {
+		if fl.columnDrag.active && !fl.columnDrag.resizing && fl.columnDrag.index == i {
+			setSourceColor(cr, fl.theme.HoverBgColor)
+			cr.Rectangle(float64(left), y, float64(cl.Width), columnHeaderHeight)
+			cr.Fill()
+		}
+
+		title := cl.Column.String()
+		if cl.Column.IsSortedBy(fl.SortOptions) {
+			if fl.SortOptions.Descending {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		setSourceColor(cr, fl.theme.HeaderTextColor)
+		fl.drawText(cr, title, fl.fonts.header, float64(left+rowTextPadding), y, float64(cl.Width-rowTextPadding-columnPadding), columnHeaderHeight, pango.EllipsizeEnd, pango.AlignLeft)
+
+		left += cl.Width
+		setSourceColor(cr, fl.theme.SelectedBgColor)
+		cr.Rectangle(float64(left-1), y+4, 1, columnHeaderHeight-8)
+		cr.Fill()
+	}
+}
+
+func (fl *FileList) drawColumns(cr *cairo.Context, item *types.ListItem, y int) {
+	if len(fl.Layout) == 0 {
+		return
+	}
+	left := fl.Layout[0].Width
+	for _, cl := range fl.Layout[1:] {
+		value, _ := fl.Columns.Value(item, cl.Column, fl.queueRedraw)
+		if value != "" {
+			fl.drawText(cr, value, fl.fonts.detail, float64(left+rowTextPadding), float64(y), float64(cl.Width-rowTextPadding-columnPadding), rowHeight, pango.EllipsizeEnd, pango.AlignLeft)
+		}
+		left += cl.Width
+	}
+}
+
+// queueRedraw is called from the column worker when a value finished
+// loading. Redraws are coalesced so a burst of values causes one draw.
+func (fl *FileList) queueRedraw() {
+	if fl.redrawQueued.Swap(true) {
+		return
+	}
+	glib.IdleAdd(func() {
+		fl.redrawQueued.Store(false)
+		fl.DrawingArea.QueueDraw()
+	})
+}
+
+func (fl *FileList) newColumnHeaderDrag(da *gtk.DrawingArea) *gtk.GestureDrag {
+	drag := gtk.NewGestureDrag()
+	drag.ConnectDragBegin(func(startX, startY float64) {
+		if !fl.inColumnHeader(startY) {
+			drag.SetState(gtk.EventSequenceDenied)
+			return
+		}
+		index, edge := fl.columnAt(startX)
+		fl.columnDrag = columnDrag{
+			active:     true,
+			index:      index,
+			resizing:   edge,
+			startX:     startX,
+			startWidth: fl.Layout[index].Width,
+		}
+		da.QueueDraw()
+	})
+	drag.ConnectDragUpdate(func(offsetX, offsetY float64) {
+		if !fl.columnDrag.active || !fl.columnDrag.resizing {
+			return
+		}
+		fl.Layout = fl.Layout.Resize(fl.columnDrag.index, fl.columnDrag.startWidth+int(offsetX))
+		da.QueueDraw()
+	})
+	drag.ConnectDragEnd(func(offsetX, offsetY float64) {
+		if !fl.columnDrag.active {
+			return
+		}
+		state := fl.columnDrag
+		fl.columnDrag = columnDrag{}
+		defer da.QueueDraw()
+
+		if state.resizing {
+			fl.layoutChanged()
+			return
+		}
+		if math.Abs(offsetX) < resizeHandleWidth {
+			if fl.ColumnClicked != nil {
+				fl.ColumnClicked(fl.Layout[state.index].Column)
+			}
+			return
+		}
+		target, _ := fl.columnAt(state.startX + offsetX)
+		fl.Layout = fl.Layout.Move(state.index, target)
+		fl.layoutChanged()
+	})
+	return drag
+}
+
+func (fl *FileList) showColumnChooser(da *gtk.DrawingArea, x, y float64) {
+	pop := gtk.NewPopover()
+	popoverBox := gtk.NewBox(gtk.OrientationVertical, 6)
+	pop.SetChild(popoverBox)
+
+	for _, column := range columns.AllColumns {
+		if column == columns.ColumnName {
+			continue
+		}
+		checkButton := gtk.NewCheckButtonWithLabel(column.String())
+		checkButton.SetActive(fl.Layout.Contains(column))
+		col := column
+		checkButton.ConnectToggled(func() {
+			fl.Layout = fl.Layout.Toggle(col)
+			fl.layoutChanged()
+			da.QueueDraw()
+		})
+		popoverBox.Append(checkButton)
+	}
+
+	resetButton := gtk.NewButtonWithLabel("Reset Columns")
+	resetButton.ConnectClicked(func() {
+		fl.Layout = columns.DefaultLayout()
+		fl.layoutChanged()
+		da.QueueDraw()
+		pop.Popdown()
+	})
+	popoverBox.Append(resetButton)
+
+	pop.SetHasArrow(true)
+	rect := gdk.NewRectangle(int(x), int(y), 1, 1)
+	pop.SetPointingTo(&rect)
+	pop.SetParent(da)
+	pop.Popup()
+}
+
+func (fl *FileList) layoutChanged() {
+	if fl.LayoutChanged != nil {
+		fl.LayoutChanged(fl.Layout)
+	}
+}
+
+func setSourceColor(cr *cairo.Context, color gdk.RGBA) {
+	cr.SetSourceRGBA(float64(color.Red()), float64(color.Green()), float64(color.Blue()), float64(color.Alpha()))
+}
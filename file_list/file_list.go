@@ -2,18 +2,26 @@ package file_list
 
 import (
 	"fmt"
+	"math"
 	"os/exec"
 	"slices"
-	"strings"
+	"sort"
+	"sync/atomic"
 
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/cache"
+	"github.com/MrSametBurgazoglu/atilgan/columns"
 	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
 	"github.com/MrSametBurgazoglu/atilgan/special_path"
+	"github.com/MrSametBurgazoglu/atilgan/tag"
 	"github.com/MrSametBurgazoglu/atilgan/tag_popup"
 	"github.com/MrSametBurgazoglu/atilgan/types"
 	"github.com/diamondburned/gotk4/pkg/cairo"
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
 )
 
 type FileListTheme struct {
@@ -25,6 +33,7 @@ type FileListTheme struct {
 	HeaderTextColor       gdk.RGBA
 	CopyCutBgColor        gdk.RGBA
 	HoverBgColor          gdk.RGBA
+	BrokenLinkTextColor   gdk.RGBA
 }
 
 func NewFileListTheme() *FileListTheme {
@@ -37,12 +46,16 @@ func NewFileListTheme() *FileListTheme {
 		HeaderTextColor:       gdk.NewRGBA(245.0/255, 245.0/255, 245.0/255, 1),
 		CopyCutBgColor:        gdk.NewRGBA(50.0/255, 70.0/255, 90.0/255, 1),
 		HoverBgColor:          gdk.NewRGBA(55.0/255, 55.0/255, 55.0/255, 1),
+		BrokenLinkTextColor:   gdk.NewRGBA(230.0/255, 100.0/255, 100.0/255, 1),
 	}
 }
 
 const (
 	rowHeight    = 36
 	headerHeight = 20
+	badgeSize    = 12
+	tagDotRadius = 2.5
+	maxTagDots   = 4
 )
 
 type FileList struct {
@@ -57,11 +70,43 @@ type FileList struct {
 	theme              *FileListTheme
 	specialPathManager *special_path.SpecialPathManager
 	parent             *gtk.Window
+	DetailsMode        bool
+	Layout             columns.Layout
+	Columns            *columns.Provider
+	SortOptions        sorter.SortOptions
+	columnDrag         columnDrag
+	redrawQueued       atomic.Bool
+	fonts              *textFonts
+	rowTops            []int
+	contentHeight      int
+	overlay            *gtk.Overlay
+	renameEditor       *renameEditor
+	typeAhead          typeAhead
+	selection          map[int]bool
+	anchorIDX          int
 
 	SelectionChanged func(index int)
 	PathChanged      func(path string)
 	KeyRightPressed  func()
 	KeyLeftPressed   func()
+	LayoutChanged    func(columns.Layout)
+	ColumnClicked    func(columns.Column)
+	Renamed          func(oldPath, newPath string)
+	BulkRename       func(items []*types.ListItem)
+	CompressItems    func(items []*types.ListItem)
+	ExtractItem      func(item *types.ListItem, chooseDestination bool)
+	Checksums        func(items []*types.ListItem)
+	FindDuplicates   func(item *types.ListItem)
+	// ApplyTagRules runs the auto-tag rules over items and what is below
+	// them.
+	ApplyTagRules func(items []*types.ListItem)
+	// CompareFolders is called with the folders to compare, the first
+	// one and, if another folder is selected with it, the second one.
+	CompareFolders func(left, right string)
+	ShowProperties func(item *types.ListItem)
+	GoToLinkTarget func(item *types.ListItem)
+	// TagColors returns the colors of the tags of path, drawn as dots.
+	TagColors func(path string) []string
 }
 
 func NewFileList(canSelect bool, specialPathManager *special_path.SpecialPathManager, parent *gtk.Window) *FileList {
@@ -75,11 +120,28 @@ func NewFileList(canSelect bool, specialPathManager *special_path.SpecialPathMan
 		theme:              NewFileListTheme(),
 		specialPathManager: specialPathManager,
 		parent:             parent,
+		Layout:             columns.DefaultLayout(),
+		Columns:            columns.NewProvider(),
+		fonts:              newTextFonts(),
+		overlay:            gtk.NewOverlay(),
+		selection:          make(map[int]bool),
+	}
+	fl.renameEditor = fl.newRenameEditor()
+	if specialPathManager != nil {
+		fl.Columns.TagLookup = specialPathManager.GetTagManager().GetTags
+		fl.TagColors = specialPathManager.GetTagManager().PathColors
 	}
 
 	fl.DrawingArea.SetDrawFunc(fl.onDraw)
+	fl.DrawingArea.SetHasTooltip(true)
+	fl.DrawingArea.ConnectQueryTooltip(fl.onQueryTooltip)
+	fl.VAdjustment().ConnectValueChanged(func() {
+		fl.DrawingArea.QueueDraw()
+	})
 
-	fl.SetChild(fl.DrawingArea)
+	fl.overlay.SetChild(fl.DrawingArea)
+	fl.overlay.AddOverlay(fl.renameEditor)
+	fl.SetChild(fl.overlay)
 	fl.SetVExpand(true)
 
 	fl.SetMinContentWidth(600)
@@ -89,23 +151,34 @@ func NewFileList(canSelect bool, specialPathManager *special_path.SpecialPathMan
 	if canSelect {
 		key := gtk.NewEventControllerKey()
 		key.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+			if state&(gdk.ControlMask|gdk.AltMask|gdk.SuperMask) == 0 {
+				if fl.typeAheadKey(rune(gdk.KeyvalToUnicode(keyval))) {
+					return true
+				}
+			}
+			fl.typeAhead.reset()
+
 			switch keyval {
 			case gdk.KEY_Up:
 				if fl.SelectedIDX > 0 {
-					fl.SelectedIDX--
-					fl.DrawingArea.QueueDraw()
+					fl.selectTo(fl.SelectedIDX-1, state)
 					fl.SelectionChanged(fl.SelectedIDX)
 				}
 				return true
 
 			case gdk.KEY_Down:
 				if fl.SelectedIDX < len(fl.Items)-1 {
-					fl.SelectedIDX++
-					fl.DrawingArea.QueueDraw()
+					fl.selectTo(fl.SelectedIDX+1, state)
 					fl.SelectionChanged(fl.SelectedIDX)
 				}
 				return true
 
+			case gdk.KEY_a:
+				if state&gdk.ControlMask != 0 {
+					fl.SelectAll()
+					return true
+				}
+
 			case gdk.KEY_Left:
 				fl.KeyLeftPressed()
 				return true
@@ -116,9 +189,8 @@ func NewFileList(canSelect bool, specialPathManager *special_path.SpecialPathMan
 
 			case gdk.KEY_Return:
 				if fl.SelectedIDX >= 0 && fl.Items[fl.SelectedIDX] != nil {
-					if !fl.Items[fl.SelectedIDX].IsDir {
-						cmd := exec.Command("xdg-open", fl.Items[fl.SelectedIDX].Path)
-						cmd.Start()
+					if !CanEnter(fl.Items[fl.SelectedIDX]) {
+						fl.openFile(fl.Items[fl.SelectedIDX].Path)
 					} else {
 						fl.KeyRightPressed()
 					}
@@ -135,6 +207,7 @@ func NewFileList(canSelect bool, specialPathManager *special_path.SpecialPathMan
 		fl.DrawingArea.AddController(fl.newGestureClick(fl.DrawingArea))
 
 		fl.DrawingArea.AddController(fl.newContextMenuController(fl.DrawingArea))
+		fl.DrawingArea.AddController(fl.newColumnHeaderDrag(fl.DrawingArea))
 
 		dragSource := gtk.NewDragSource()
 		dragSource.SetActions(gdk.ActionCopy)
@@ -165,28 +238,58 @@ func NewFileList(canSelect bool, specialPathManager *special_path.SpecialPathMan
 }
 
 func (fl *FileList) SetItems(items []*types.ListItem) {
+	fl.CancelRename()
 	fl.Items = items
 	fl.SelectedIDX = 0
+	fl.anchorIDX = 0
+	clear(fl.selection)
+	fl.rowTops = fl.rowTops[:0]
+	fl.contentHeight = 0
+	fl.updateRowTops()
 	fl.DrawingArea.QueueDraw()
 }
 
 func (fl *FileList) AddItem(item *types.ListItem) {
 	fl.Items = append(fl.Items, item)
+	fl.updateRowTops()
 	fl.DrawingArea.QueueDraw()
 }
 
-func (fl *FileList) SetSelectedItemWithLetter(letter string) {
-	for i, item := range fl.Items {
-		if strings.HasPrefix(strings.ToLower(item.Name), strings.ToLower(letter)) {
-			fl.SetItem(i)
-			break
+// updateRowTops extends rowTops to cover every item. Rows are laid out
+// once per change of the items so drawing and hit testing only touch the
+// rows that are on screen.
+func (fl *FileList) updateRowTops() {
+	for i := len(fl.rowTops); i < len(fl.Items); i++ {
+		if fl.hasHeader(i) {
+			fl.contentHeight += headerHeight
 		}
+		fl.rowTops = append(fl.rowTops, fl.contentHeight)
+		fl.contentHeight += rowHeight
+	}
+}
+
+// hasHeader reports whether a group header is drawn above the item at idx.
+func (fl *FileList) hasHeader(idx int) bool {
+	previousGroup := ""
+	if idx > 0 {
+		previousGroup = fl.Items[idx-1].Group
 	}
+	return fl.Items[idx].Group != previousGroup
+}
+
+// rowAtOrBelow returns the first row whose bottom is below y, where y is
+// relative to contentTop.
+func (fl *FileList) rowAtOrBelow(y int) int {
+	return sort.Search(len(fl.rowTops), func(i int) bool {
+		return fl.rowTops[i]+rowHeight > y
+	})
 }
 
 func (fl *FileList) SetItem(index int) {
 	if index >= 0 && index < len(fl.Items) {
 		fl.SelectedIDX = index
+		fl.anchorIDX = index
+		clear(fl.selection)
 		fl.DrawingArea.QueueDraw()
 	}
 }
@@ -206,125 +309,160 @@ func (fl *FileList) CleanCopyCutItems() {
 }
 
 func (fl *FileList) onDraw(da *gtk.DrawingArea, cr *cairo.Context, w, h int) {
-	y := 0
-	currentGroup := ""
-
-	for i, item := range fl.Items {
-		if item.Group != currentGroup {
-			fl.drawHeader(cr, item.Group, y)
-			y += headerHeight
-			currentGroup = item.Group
-		}
+	top := fl.contentTop()
+	fl.DrawingArea.SetContentHeight(top + fl.contentHeight)
+
+	adj := fl.VAdjustment()
+	viewTop := int(adj.Value()) - top
+	viewBottom := viewTop + h
+	if pageSize := int(adj.PageSize()); pageSize > 0 {
+		viewBottom = viewTop + pageSize
+	}
 
-		fl.drawRow(cr, i, item, y)
-		y += rowHeight
+	for i := fl.rowAtOrBelow(viewTop); i < len(fl.Items); i++ {
+		rowTop := fl.rowTops[i]
+		if fl.hasHeader(i) {
+			rowTop -= headerHeight
+		}
+		if rowTop >= viewBottom {
+			break
+		}
+		y := top + fl.rowTops[i]
+		if fl.hasHeader(i) {
+			fl.drawHeader(cr, fl.Items[i].Group, y-headerHeight)
+		}
+		fl.drawRow(cr, i, fl.Items[i], y)
+	}
+	if fl.DetailsMode {
+		fl.drawColumnHeader(cr)
+		fl.DrawingArea.SetContentWidth(fl.Layout.TotalWidth())
 	}
-	fl.DrawingArea.SetContentHeight(y)
 	fl.ensureVisible()
 }
 
 func (fl *FileList) drawHeader(cr *cairo.Context, text string, y int) {
 	cr.SetSourceRGBA(float64(fl.theme.HeaderBackgroundColor.Red()), float64(fl.theme.HeaderBackgroundColor.Green()), float64(fl.theme.HeaderBackgroundColor.Blue()), float64(fl.theme.HeaderBackgroundColor.Alpha()))
-	cr.Rectangle(0, float64(y), 1200, float64(headerHeight))
+	cr.Rectangle(0, float64(y), fl.rowWidth(), float64(headerHeight))
 	cr.Fill()
 
 	cr.SetSourceRGBA(float64(fl.theme.HeaderTextColor.Red()), float64(fl.theme.HeaderTextColor.Green()), float64(fl.theme.HeaderTextColor.Blue()), float64(fl.theme.HeaderTextColor.Alpha()))
-	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
-	cr.SetFontSize(10)
-	cr.MoveTo(8, float64(y+15))
-	cr.ShowText(text)
+	fl.drawText(cr, text, fl.fonts.header, rowTextPadding, float64(y), fl.rowWidth()-2*rowTextPadding, headerHeight, pango.EllipsizeEnd, pango.AlignLeft)
 }
 
 func (fl *FileList) drawRow(cr *cairo.Context, idx int, item *types.ListItem, y int) {
-	if idx == fl.SelectedIDX && fl.canSelect {
+	if fl.IsSelected(idx) && fl.canSelect {
 		cr.SetSourceRGBA(float64(fl.theme.SelectedBgColor.Red()), float64(fl.theme.SelectedBgColor.Green()), float64(fl.theme.SelectedBgColor.Blue()), float64(fl.theme.SelectedBgColor.Alpha()))
-		cr.Rectangle(0, float64(y), 1200, float64(rowHeight))
+		cr.Rectangle(0, float64(y), fl.rowWidth(), float64(rowHeight))
 		cr.Fill()
 	} else if slices.Contains(fl.CopyCutPaths, item.Path) {
 		cr.SetSourceRGBA(float64(fl.theme.CopyCutBgColor.Red()), float64(fl.theme.CopyCutBgColor.Green()), float64(fl.theme.CopyCutBgColor.Blue()), float64(fl.theme.CopyCutBgColor.Alpha()))
-		cr.Rectangle(0, float64(y), 1200, float64(rowHeight))
+		cr.Rectangle(0, float64(y), fl.rowWidth(), float64(rowHeight))
 		cr.Fill()
 	} else {
 		cr.SetSourceRGBA(float64(fl.theme.BackgroundColor.Red()), float64(fl.theme.BackgroundColor.Green()), float64(fl.theme.BackgroundColor.Blue()), float64(fl.theme.BackgroundColor.Alpha()))
-		cr.Rectangle(0, float64(y), 1200, float64(rowHeight))
+		cr.Rectangle(0, float64(y), fl.rowWidth(), float64(rowHeight))
 		cr.Fill()
 	}
 
+	fl.drawIcon(cr, item, y)
+
+	if item.BrokenLink {
+		setSourceColor(cr, fl.theme.BrokenLinkTextColor)
+	} else if fl.IsSelected(idx) && fl.canSelect {
+		cr.SetSourceRGBA(float64(fl.theme.SelectedTextColor.Red()), float64(fl.theme.SelectedTextColor.Green()), float64(fl.theme.SelectedTextColor.Blue()), float64(fl.theme.SelectedTextColor.Alpha()))
+	} else {
+		cr.SetSourceRGBA(float64(fl.theme.TextColor.Red()), float64(fl.theme.TextColor.Green()), float64(fl.theme.TextColor.Blue()), float64(fl.theme.TextColor.Alpha()))
+	}
+	fl.drawText(cr, item.Name, fl.fonts.name, nameX, float64(y), fl.nameWidth(), rowHeight, pango.EllipsizeMiddle, pango.AlignLeft)
+	if item.SpecialInfo != "" {
+		fl.drawSpecialInfo(cr, item, y)
+	}
+	if fl.DetailsMode {
+		fl.drawColumns(cr, item, y)
+		return
+	}
+
+	sizeText := ""
+	if item.IsDir {
+		sizeText = fmt.Sprintf("%d item", item.ItemCount)
+	} else if item.Size > 0 {
+		sizeText = fileops.GetFileSizeAsString(item.Size)
+	}
+	sizeX := float64(fl.DrawingArea.Width() - sizeTextWidth - rowTextPadding)
+	fl.drawText(cr, sizeText, fl.fonts.detail, sizeX, float64(y), sizeTextWidth, rowHeight, pango.EllipsizeEnd, pango.AlignRight)
+}
+
+func (fl *FileList) drawIcon(cr *cairo.Context, item *types.ListItem, y int) {
 	iconSize := 24
 	iconName := fileops.GetIconForFile(item.Name)
 	if item.IsDir {
 		iconName = fileops.GetIconForFolder(item.Path)
 	}
 
-	paintable := fl.iconTheme.LookupIcon(iconName, nil, iconSize, 1, gtk.TextDirNone, 0)
-	if paintable != nil {
-		file := paintable.File()
-		if file != nil {
-			path := file.Path()
-			if path != "" {
-				texture, err := gdk.NewTextureFromFile(file)
-				if err == nil {
-					pixbuf := gdk.PixbufGetFromTexture(texture)
-					if pixbuf != nil {
-						gdk.CairoSetSourcePixbuf(cr, pixbuf, 8, float64(y+(rowHeight-iconSize)/2))
-						cr.Paint()
-					}
-				}
-			}
+	iconY := y + (rowHeight-iconSize)/2
+	fl.paintIcon(cr, iconName, iconSize, 8, iconY)
+	if item.IsSymlink {
+		badgeName := "emblem-symbolic-link"
+		if item.BrokenLink {
+			badgeName = "emblem-unreadable"
 		}
+		fl.paintIcon(cr, badgeName, badgeSize, 8+iconSize-badgeSize+2, iconY+iconSize-badgeSize+2)
+	}
+	if fl.TagColors != nil {
+		fl.drawTagDots(cr, fl.TagColors(item.Path), y)
 	}
+}
 
-	if idx == fl.SelectedIDX && fl.canSelect {
-		cr.SetSourceRGBA(float64(fl.theme.SelectedTextColor.Red()), float64(fl.theme.SelectedTextColor.Green()), float64(fl.theme.SelectedTextColor.Blue()), float64(fl.theme.SelectedTextColor.Alpha()))
-	} else {
-		cr.SetSourceRGBA(float64(fl.theme.TextColor.Red()), float64(fl.theme.TextColor.Green()), float64(fl.theme.TextColor.Blue()), float64(fl.theme.TextColor.Alpha()))
+// drawTagDots draws a column of dots in the colors of the tags of an item
+// in the margin left of its icon.
+func (fl *FileList) drawTagDots(cr *cairo.Context, colors []string, y int) {
+	colors = colors[:min(len(colors), maxTagDots)]
+	spacing := 2*tagDotRadius + 2
+	top := float64(y) + (rowHeight-spacing*float64(len(colors)))/2 + spacing/2
+	for i, color := range colors {
+		r, g, b, ok := tag.RGB(color)
+		if !ok {
+			continue
+		}
+		cr.SetSourceRGB(r, g, b)
+		cr.Arc(4, top+float64(i)*spacing, tagDotRadius, 0, 2*math.Pi)
+		cr.Fill()
 	}
-	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightBold)
-	cr.SetFontSize(14)
-	cr.MoveTo(40, float64(y+23))
-	cr.ShowText(item.Name)
+}
 
-	if idx == fl.SelectedIDX && fl.canSelect {
-		cr.SetSourceRGBA(float64(fl.theme.SelectedTextColor.Red()), float64(fl.theme.SelectedTextColor.Green()), float64(fl.theme.SelectedTextColor.Blue()), float64(fl.theme.SelectedTextColor.Alpha()))
-	} else {
-		cr.SetSourceRGBA(float64(fl.theme.TextColor.Red()), float64(fl.theme.TextColor.Green()), float64(fl.theme.TextColor.Blue()), float64(fl.theme.TextColor.Alpha()))
+func (fl *FileList) paintIcon(cr *cairo.Context, iconName string, size, x, y int) {
+	pixbuf := cache.GetIcon(fl.iconTheme, iconName, size, fl.DrawingArea.ScaleFactor())
+	if pixbuf == nil {
+		return
 	}
-	cr.SelectFontFace("Sans", cairo.FontSlantNormal, cairo.FontWeightNormal)
-	cr.SetFontSize(11)
-	cr.MoveTo(520, float64(y+20))
-	if item.IsDir {
-		cr.ShowText(fmt.Sprintf("%d item", item.ItemCount))
-	} else if item.Size > 0 {
-		cr.ShowText(fileops.GetFileSizeAsString(item.Size))
+	cr.Save()
+	cr.Translate(float64(x), float64(y))
+	cr.Scale(float64(size)/float64(pixbuf.Width()), float64(size)/float64(pixbuf.Height()))
+	gdk.CairoSetSourcePixbuf(cr, pixbuf, 0, 0)
+	cr.Paint()
+	cr.Restore()
+}
+
+func (fl *FileList) rowWidth() float64 {
+	if fl.DetailsMode {
+		return float64(max(fl.DrawingArea.Width(), fl.Layout.TotalWidth()))
 	}
+	return float64(fl.DrawingArea.Width())
 }
 
 func (fl *FileList) getItemBounds(idx int) (top, bottom int) {
-	if idx < 0 || idx >= len(fl.Items) {
+	if idx < 0 || idx >= len(fl.rowTops) {
 		return 0, 0
 	}
-
-	pos := 0
-	currentGroup := ""
-
-	for i, item := range fl.Items {
-		if item.Group != currentGroup {
-			pos += headerHeight
-			currentGroup = item.Group
-		}
-
-		if i == idx {
-			return pos, pos + rowHeight
-		}
-		pos += rowHeight
-	}
-	return 0, 0
+	top = fl.contentTop() + fl.rowTops[idx]
+	return top, top + rowHeight
 }
 
 func (fl *FileList) ensureVisible() {
 	adj := fl.VAdjustment()
-	scrollPos := adj.Value()
-	visibleHeight := float64(fl.Height())
+	top := float64(fl.contentTop())
+	scrollPos := adj.Value() + top
+	visibleHeight := float64(fl.Height()) - top
 
 	itemTop, itemBottom := fl.getItemBounds(fl.SelectedIDX)
 
@@ -338,14 +476,14 @@ func (fl *FileList) ensureVisible() {
 			targetIdx = 0
 		}
 		targetTop, _ := fl.getItemBounds(targetIdx)
-		adj.SetValue(float64(targetTop))
+		adj.SetValue(float64(targetTop) - top)
 	} else {
 		targetIdx := fl.SelectedIDX + 5
 		if targetIdx >= len(fl.Items) {
 			targetIdx = len(fl.Items) - 1
 		}
 		_, targetBottom := fl.getItemBounds(targetIdx)
-		newValue := float64(targetBottom) - visibleHeight
+		newValue := float64(targetBottom) - visibleHeight - top
 		if newValue < 0 {
 			newValue = 0
 		}
@@ -358,16 +496,14 @@ func (fl *FileList) newGestureClick(da *gtk.DrawingArea) *gtk.GestureClick {
 	click.ConnectPressed(func(n int, x, y float64) {
 		idx := fl.ItemAt(int(y))
 		if idx >= 0 {
-			fl.SelectedIDX = idx
+			fl.selectTo(idx, click.CurrentEventState())
 			fl.SelectionChanged(fl.SelectedIDX)
-			da.QueueDraw()
 
 			if click.CurrentButton() == gdk.BUTTON_PRIMARY && n == 2 {
-				if !fl.Items[idx].IsDir {
-					cmd := exec.Command("xdg-open", fl.Items[idx].Path)
-					cmd.Start()
+				if !CanEnter(fl.Items[idx]) {
+					fl.openFile(fl.Items[idx].Path)
 				} else {
-					fl.PathChanged(fl.Items[idx].Path)
+					fl.PathChanged(EnterPath(fl.Items[idx]))
 				}
 			}
 		}
@@ -379,6 +515,10 @@ func (fl *FileList) newContextMenuController(da *gtk.DrawingArea) *gtk.GestureCl
 	click := gtk.NewGestureClick()
 	click.SetButton(gdk.BUTTON_SECONDARY)
 	click.ConnectPressed(func(n int, x, y float64) {
+		if fl.inColumnHeader(y) {
+			fl.showColumnChooser(da, x, y)
+			return
+		}
 		idx := fl.ItemAt(int(y))
 		if idx < 0 {
 			return
@@ -390,14 +530,23 @@ func (fl *FileList) newContextMenuController(da *gtk.DrawingArea) *gtk.GestureCl
 
 		open := gtk.NewButtonWithLabel("Open")
 		open.Connect("clicked", func() {
-			if !fl.Items[idx].IsDir {
-				cmd := exec.Command("xdg-open", fl.Items[idx].Path)
-				cmd.Start()
+			if !CanEnter(fl.Items[idx]) {
+				fl.openFile(fl.Items[idx].Path)
 			} else {
-				fl.PathChanged(fl.Items[idx].Path)
+				fl.PathChanged(EnterPath(fl.Items[idx]))
 				pop.Popdown()
 			}
 		})
+		popoverBox.Append(open)
+
+		if archive.IsArchive(fl.Items[idx].Name) && CanEnter(fl.Items[idx]) {
+			openExternally := gtk.NewButtonWithLabel("Open With Default Application")
+			openExternally.Connect("clicked", func() {
+				fl.openFile(fl.Items[idx].Path)
+				pop.Popdown()
+			})
+			popoverBox.Append(openExternally)
+		}
 
 		delete := gtk.NewButtonWithLabel("Delete")
 		delete.Connect("clicked", func() {
@@ -417,16 +566,132 @@ func (fl *FileList) newContextMenuController(da *gtk.DrawingArea) *gtk.GestureCl
 			print("Delete clicked")
 		})
 
-		addTag := gtk.NewButtonWithLabel("Add Tag")
+		rename := gtk.NewButtonWithLabel("Rename")
+		rename.Connect("clicked", func() {
+			pop.Popdown()
+			if !fl.IsSelected(idx) {
+				fl.SetItem(idx)
+				fl.SelectionChanged(idx)
+			}
+			fl.Rename()
+		})
+
+		addTag := gtk.NewButtonWithLabel("Tags…")
 		addTag.Connect("clicked", func() {
 			tagPopup := tag_popup.NewTagPopup(fl.parent, fl.specialPathManager.GetTagManager(), fl.Items[idx].Path)
 			tagPopup.Show()
 			pop.Popdown()
 		})
 
-		popoverBox.Append(open)
-		popoverBox.Append(delete)
-		popoverBox.Append(addTag)
+		compress := gtk.NewButtonWithLabel("Compress…")
+		compress.Connect("clicked", func() {
+			pop.Popdown()
+			items := []*types.ListItem{fl.Items[idx]}
+			if fl.IsSelected(idx) {
+				items = fl.SelectedItems()
+			}
+			fl.CompressItems(items)
+		})
+
+		checksums := gtk.NewButtonWithLabel("Checksums…")
+		if fl.Items[idx].IsDir {
+			checksums.SetLabel("Create Checksum Manifest…")
+		}
+		checksums.Connect("clicked", func() {
+			pop.Popdown()
+			items := []*types.ListItem{fl.Items[idx]}
+			if !fl.Items[idx].IsDir && fl.IsSelected(idx) {
+				items = items[:0]
+				for _, item := range fl.SelectedItems() {
+					if !item.IsDir {
+						items = append(items, item)
+					}
+				}
+			}
+			fl.Checksums(items)
+		})
+
+		// Items inside an archive are read only.
+		if !archive.IsArchivePath(fl.Items[idx].Path) {
+			popoverBox.Append(delete)
+			popoverBox.Append(rename)
+			popoverBox.Append(addTag)
+			if fl.CompressItems != nil {
+				popoverBox.Append(compress)
+			}
+			if fl.Checksums != nil {
+				popoverBox.Append(checksums)
+			}
+			if fl.ApplyTagRules != nil {
+				applyTagRules := gtk.NewButtonWithLabel("Apply Tag Rules")
+				applyTagRules.Connect("clicked", func() {
+					pop.Popdown()
+					items := []*types.ListItem{fl.Items[idx]}
+					if fl.IsSelected(idx) {
+						items = fl.SelectedItems()
+					}
+					fl.ApplyTagRules(items)
+				})
+				popoverBox.Append(applyTagRules)
+			}
+			if fl.FindDuplicates != nil && fl.Items[idx].IsDir {
+				findDuplicates := gtk.NewButtonWithLabel("Find Duplicates…")
+				findDuplicates.Connect("clicked", func() {
+					pop.Popdown()
+					fl.FindDuplicates(fl.Items[idx])
+				})
+				popoverBox.Append(findDuplicates)
+			}
+			if fl.CompareFolders != nil && fl.Items[idx].IsDir {
+				compareFolders := gtk.NewButtonWithLabel("Compare With…")
+				compareFolders.Connect("clicked", func() {
+					pop.Popdown()
+					left, right := fl.Items[idx].Path, ""
+					if fl.IsSelected(idx) {
+						for _, item := range fl.SelectedItems() {
+							if item.IsDir && item.Path != left {
+								right = item.Path
+								break
+							}
+						}
+					}
+					fl.CompareFolders(left, right)
+				})
+				popoverBox.Append(compareFolders)
+			}
+		}
+
+		if fl.ExtractItem != nil && !fl.Items[idx].IsDir && CanEnter(fl.Items[idx]) {
+			extractHere := gtk.NewButtonWithLabel("Extract Here")
+			extractHere.Connect("clicked", func() {
+				pop.Popdown()
+				fl.ExtractItem(fl.Items[idx], false)
+			})
+			popoverBox.Append(extractHere)
+
+			extractTo := gtk.NewButtonWithLabel("Extract To…")
+			extractTo.Connect("clicked", func() {
+				pop.Popdown()
+				fl.ExtractItem(fl.Items[idx], true)
+			})
+			popoverBox.Append(extractTo)
+		}
+		if fl.GoToLinkTarget != nil && fl.Items[idx].IsSymlink {
+			goToTarget := gtk.NewButtonWithLabel("Go to Link Target")
+			goToTarget.Connect("clicked", func() {
+				pop.Popdown()
+				fl.GoToLinkTarget(fl.Items[idx])
+			})
+			popoverBox.Append(goToTarget)
+		}
+		if fl.ShowProperties != nil && !archive.IsArchivePath(fl.Items[idx].Path) {
+			properties := gtk.NewButtonWithLabel("Properties")
+			properties.Connect("clicked", func() {
+				pop.Popdown()
+				fl.ShowProperties(fl.Items[idx])
+			})
+			popoverBox.Append(properties)
+		}
 		pop.SetHasArrow(true)
 		rect := gdk.NewRectangle(int(x), int(y), 1, 1)
 		pop.SetPointingTo(&rect)
@@ -438,22 +703,13 @@ func (fl *FileList) newContextMenuController(da *gtk.DrawingArea) *gtk.GestureCl
 }
 
 func (fl *FileList) ItemAt(y int) int {
-	currentGroup := ""
-	pos := 0
-
-	for i, item := range fl.Items {
-		if item.Group != currentGroup {
-			if y >= pos && y < pos+headerHeight {
-				return -1
-			}
-			pos += headerHeight
-			currentGroup = item.Group
-		}
-
-		if y >= pos && y < pos+rowHeight {
-			return i
-		}
-		pos += rowHeight
+	if fl.inColumnHeader(float64(y)) {
+		return -1
+	}
+	y -= fl.contentTop()
+	i := fl.rowAtOrBelow(y)
+	if i < len(fl.rowTops) && fl.rowTops[i] <= y {
+		return i
 	}
 	return -1
 }
@@ -461,7 +717,7 @@ func (fl *FileList) ItemAt(y int) int {
 func (fl *FileList) newMouseController(da *gtk.DrawingArea) *gtk.EventControllerMotion {
 	ctrl := gtk.NewEventControllerMotion()
 	ctrl.ConnectMotion(func(x, y float64) {
-		if fl.CanFocus {
+		if fl.CanFocus && !fl.IsRenaming() {
 			fl.DrawingArea.GrabFocus()
 		}
 	})
@@ -0,0 +1,47 @@
+package file_list
+
+import (
+	"os/exec"
+
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+// CanEnter reports whether opening item lists its contents, which is the
+// case for folders and for archives on disk.
+func CanEnter(item *types.ListItem) bool {
+	return item.IsDir || (archive.IsArchive(item.Name) && !archive.IsArchivePath(item.Path))
+}
+
+// EnterPath returns the path that is listed when item is opened.
+func EnterPath(item *types.ListItem) string {
+	if item.IsDir {
+		return item.Path
+	}
+	return archive.PathFor(item.Path, "")
+}
+
+// openFile opens path with its default application and records it among
+// the recent files. Files inside an archive are extracted to the cache
+// first, and aren't recorded.
+func (fl *FileList) openFile(path string) {
+	go func() {
+		inArchive := archive.IsArchivePath(path)
+		if inArchive {
+			local, err := archive.ExtractToCache(path)
+			if err != nil {
+				println("couldn't extract file:", err.Error())
+				return
+			}
+			path = local
+		}
+		cmd := exec.Command("xdg-open", path)
+		if err := cmd.Start(); err != nil {
+			println("couldn't open file:", err.Error())
+			return
+		}
+		if !inArchive && fl.specialPathManager != nil {
+			fl.specialPathManager.GetRecentManager().Opened(path)
+		}
+	}()
+}
@@ -0,0 +1,170 @@
+package file_list
+
+import (
+	"github.com/MrSametBurgazoglu/atilgan/rename_popup"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// renameEditor is the entry shown over a row while its item is renamed in
+// place. It lives in an overlay above the DrawingArea so it scrolls with
+// the rows.
+type renameEditor struct {
+	*gtk.Box
+	entry      *gtk.Entry
+	errorLabel *gtk.Label
+	path       string
+	active     bool
+}
+
+func (fl *FileList) newRenameEditor() *renameEditor {
+	re := &renameEditor{
+		Box:        gtk.NewBox(gtk.OrientationVertical, 2),
+		entry:      gtk.NewEntry(),
+		errorLabel: gtk.NewLabel(""),
+	}
+	re.SetHAlign(gtk.AlignStart)
+	re.SetVAlign(gtk.AlignStart)
+	re.SetVisible(false)
+
+	re.errorLabel.SetXAlign(0)
+	re.errorLabel.AddCSSClass("rename-error")
+	re.errorLabel.SetVisible(false)
+
+	re.Append(re.entry)
+	re.Append(re.errorLabel)
+
+	re.entry.ConnectChanged(func() {
+		if !re.active {
+			return
+		}
+		fl.validateRename()
+	})
+	re.entry.ConnectActivate(fl.commitRename)
+
+	key := gtk.NewEventControllerKey()
+	key.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		if keyval == gdk.KEY_Escape {
+			fl.CancelRename()
+			return true
+		}
+		return false
+	})
+	re.entry.AddController(key)
+
+	focus := gtk.NewEventControllerFocus()
+	focus.ConnectLeave(func() {
+		if re.active {
+			fl.CancelRename()
+		}
+	})
+	re.entry.AddController(focus)
+
+	return re
+}
+
+// Rename renames the selected items, in place for a single item or with
+// BulkRename when several items are selected.
+func (fl *FileList) Rename() {
+	items := fl.SelectedItems()
+	if len(items) > 1 && fl.BulkRename != nil {
+		fl.BulkRename(items)
+		return
+	}
+	fl.StartRename()
+}
+
+// StartRename opens the rename entry over the selected row with the name
+// without its extension selected.
+func (fl *FileList) StartRename() {
+	if fl.SelectedIDX < 0 || fl.SelectedIDX >= len(fl.Items) {
+		return
+	}
+	item := fl.Items[fl.SelectedIDX]
+	re := fl.renameEditor
+
+	fl.ensureVisible()
+	top, _ := fl.getItemBounds(fl.SelectedIDX)
+	re.SetMarginStart(nameX - rowTextPadding)
+	re.SetMarginTop(top + 4)
+	re.entry.SetSizeRequest(int(fl.nameWidth())+2*rowTextPadding, rowHeight-8)
+
+	re.path = item.Path
+	re.entry.SetText(item.Name)
+	re.active = true
+	fl.validateRename()
+	re.SetVisible(true)
+
+	re.entry.GrabFocus()
+	re.entry.SelectRegion(0, rename_popup.StemLength(item.Name, item.IsDir))
+}
+
+// IsRenaming reports whether the rename entry is open.
+func (fl *FileList) IsRenaming() bool {
+	return fl.renameEditor.active
+}
+
+// CancelRename closes the rename entry without renaming.
+func (fl *FileList) CancelRename() {
+	re := fl.renameEditor
+	if !re.active {
+		return
+	}
+	re.active = false
+	re.SetVisible(false)
+	fl.DrawingArea.GrabFocus()
+}
+
+func (fl *FileList) validateRename() bool {
+	re := fl.renameEditor
+	err := rename_popup.ValidateName(re.path, re.entry.Text())
+	if err != nil {
+		re.errorLabel.SetText(err.Error())
+		re.entry.AddCSSClass("error")
+	} else {
+		re.entry.RemoveCSSClass("error")
+	}
+	re.errorLabel.SetVisible(err != nil)
+	return err == nil
+}
+
+func (fl *FileList) commitRename() {
+	re := fl.renameEditor
+	if !re.active || !fl.validateRename() {
+		return
+	}
+	oldPath := re.path
+	newPath, err := rename_popup.Rename(oldPath, re.entry.Text())
+	if err != nil {
+		re.errorLabel.SetText(err.Error())
+		re.errorLabel.SetVisible(true)
+		re.entry.AddCSSClass("error")
+		return
+	}
+	fl.CancelRename()
+	if newPath == oldPath {
+		return
+	}
+
+	if fl.Renamed != nil {
+		fl.Renamed(oldPath, newPath)
+	} else if fl.PathChanged != nil {
+		fl.PathChanged("")
+	}
+	fl.SelectPath(newPath)
+}
+
+// SelectPath selects the item with the given path and reports whether it
+// was found.
+func (fl *FileList) SelectPath(path string) bool {
+	for i, item := range fl.Items {
+		if item.Path == path {
+			fl.SetItem(i)
+			if fl.SelectionChanged != nil {
+				fl.SelectionChanged(i)
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package file_list
+
+import (
+	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+)
+
+// IsSelected reports whether the item at idx is part of the multi-item
+// selection, or is the cursor item when there is none.
+func (fl *FileList) IsSelected(idx int) bool {
+	if len(fl.selection) > 0 {
+		return fl.selection[idx]
+	}
+	return idx == fl.SelectedIDX
+}
+
+// SelectedItems returns the selected items in list order. Without a
+// multi-item selection this is the cursor item.
+func (fl *FileList) SelectedItems() []*types.ListItem {
+	items := make([]*types.ListItem, 0, len(fl.selection)+1)
+	for i, item := range fl.Items {
+		if fl.IsSelected(i) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ClearSelection drops the multi-item selection and keeps the cursor item.
+func (fl *FileList) ClearSelection() {
+	fl.selection = make(map[int]bool)
+	fl.anchorIDX = fl.SelectedIDX
+	fl.DrawingArea.QueueDraw()
+}
+
+// SelectAll adds every item to the selection.
+func (fl *FileList) SelectAll() {
+	for i := range fl.Items {
+		fl.selection[i] = true
+	}
+	fl.DrawingArea.QueueDraw()
+}
+
+// SelectPaths selects the items with the given paths and moves the cursor
+// to the first of them.
+func (fl *FileList) SelectPaths(paths []string) {
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	fl.selection = make(map[int]bool)
+	first := -1
+	for i, item := range fl.Items {
+		if wanted[item.Path] {
+			fl.selection[i] = true
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first >= 0 {
+		fl.SelectedIDX = first
+		fl.anchorIDX = first
+	}
+	fl.DrawingArea.QueueDraw()
+}
+
+// selectTo moves the cursor to idx. Shift extends the selection from the
+// anchor and Control toggles the item, otherwise the selection is dropped.
+func (fl *FileList) selectTo(idx int, state gdk.ModifierType) {
+	switch {
+	case state&gdk.ShiftMask != 0:
+		fl.selection = make(map[int]bool)
+		from, to := min(fl.anchorIDX, idx), max(fl.anchorIDX, idx)
+		for i := from; i <= to; i++ {
+			fl.selection[i] = true
+		}
+	case state&gdk.ControlMask != 0:
+		if len(fl.selection) == 0 {
+			fl.selection[fl.SelectedIDX] = true
+		}
+		if fl.selection[idx] {
+			delete(fl.selection, idx)
+		} else {
+			fl.selection[idx] = true
+		}
+		fl.anchorIDX = idx
+	default:
+		fl.selection = make(map[int]bool)
+		fl.anchorIDX = idx
+	}
+	fl.SelectedIDX = idx
+	fl.DrawingArea.QueueDraw()
+}
@@ -0,0 +1,114 @@
+package file_list
+
+import (
+	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+	"github.com/diamondburned/gotk4/pkg/pangocairo"
+)
+
+const (
+	nameX          = 40
+	sizeTextWidth  = 110
+	rowTextPadding = 8
+)
+
+// minSpecialInfoWidth is the least room special info is drawn in.
+const minSpecialInfoWidth = 60
+
+type textFonts struct {
+	name   *pango.FontDescription
+	detail *pango.FontDescription
+	header *pango.FontDescription
+}
+
+// newTextFonts only sets weight and size so the family, fallback fonts and
+// resolution come from the widget's Pango context.
+func newTextFonts() *textFonts {
+	return &textFonts{
+		name:   pango.FontDescriptionFromString("Bold 10.5"),
+		detail: pango.FontDescriptionFromString("8.5"),
+		header: pango.FontDescriptionFromString("Bold 7.5"),
+	}
+}
+
+// newTextLayout returns a single line layout of text that is ellipsized to
+// width pixels.
+func (fl *FileList) newTextLayout(text string, font *pango.FontDescription, width float64, ellipsize pango.EllipsizeMode) *pango.Layout {
+	layout := fl.DrawingArea.CreatePangoLayout(text)
+	layout.SetFontDescription(font)
+	layout.SetSingleParagraphMode(true)
+	layout.SetWidth(pango.UnitsFromDouble(max(width, 0)))
+	layout.SetEllipsize(ellipsize)
+	return layout
+}
+
+// drawText draws text vertically centered in the box at x, y and reports
+// whether it had to be ellipsized.
+func (fl *FileList) drawText(cr *cairo.Context, text string, font *pango.FontDescription, x, y, width, height float64, ellipsize pango.EllipsizeMode, alignment pango.Alignment) bool {
+	layout := fl.newTextLayout(text, font, width, ellipsize)
+	layout.SetAlignment(alignment)
+	_, textHeight := layout.PixelSize()
+	cr.MoveTo(x, y+(height-float64(textHeight))/2)
+	pangocairo.ShowLayout(cr, layout)
+	return layout.IsEllipsized()
+}
+
+// nameWidth is the space the name of a row gets at the current allocation.
+func (fl *FileList) nameWidth() float64 {
+	if fl.DetailsMode && len(fl.Layout) > 0 {
+		return float64(fl.Layout[0].Width - nameX - columnPadding)
+	}
+	return float64(fl.DrawingArea.Width() - nameX - sizeTextWidth - 2*rowTextPadding)
+}
+
+// drawSpecialInfo puts the special info of item, like the folder of a
+// recent file, faded in the space its name leaves free.
+func (fl *FileList) drawSpecialInfo(cr *cairo.Context, item *types.ListItem, y int) {
+	nameWidth, _ := fl.newTextLayout(item.Name, fl.fonts.name, fl.nameWidth(), pango.EllipsizeMiddle).PixelSize()
+	x := float64(nameX + nameWidth + 2*rowTextPadding)
+	width := nameX + fl.nameWidth() - x
+	if width < minSpecialInfoWidth {
+		return
+	}
+	cr.PushGroup()
+	fl.drawText(cr, item.SpecialInfo, fl.fonts.detail, x, float64(y), width, rowHeight, pango.EllipsizeStart, pango.AlignLeft)
+	cr.PopGroupToSource()
+	cr.PaintWithAlpha(0.6)
+}
+
+func (fl *FileList) isNameTruncated(item *types.ListItem) bool {
+	layout := fl.newTextLayout(item.Name, fl.fonts.name, fl.nameWidth(), pango.EllipsizeMiddle)
+	return layout.IsEllipsized()
+}
+
+// onQueryTooltip shows the full name of rows whose name is ellipsized, the
+// special info of items and where links point to.
+func (fl *FileList) onQueryTooltip(x, y int, keyboardMode bool, tooltip *gtk.Tooltip) bool {
+	idx := fl.ItemAt(y)
+	if keyboardMode {
+		idx = fl.SelectedIDX
+	}
+	if idx < 0 || idx >= len(fl.Items) {
+		return false
+	}
+	item := fl.Items[idx]
+	if item.IsSymlink {
+		text := item.Name + " → " + item.LinkTarget
+		if item.BrokenLink {
+			text += "\nThe target doesn't exist"
+		}
+		tooltip.SetText(text)
+		return true
+	}
+	if item.SpecialInfo != "" {
+		tooltip.SetText(item.Name + "\n" + item.SpecialInfo)
+		return true
+	}
+	if !fl.isNameTruncated(item) {
+		return false
+	}
+	tooltip.SetText(item.Name)
+	return true
+}
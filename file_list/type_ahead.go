@@ -0,0 +1,73 @@
+package file_list
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// typeAheadTimeout is how long the typed text is kept between key presses.
+const typeAheadTimeout = time.Second
+
+type typeAhead struct {
+	text     string
+	lastTime time.Time
+}
+
+// add appends r to the typed text, starting over when the previous key was
+// pressed too long ago.
+func (ta *typeAhead) add(r rune, now time.Time) string {
+	if now.Sub(ta.lastTime) > typeAheadTimeout {
+		ta.text = ""
+	}
+	ta.lastTime = now
+	ta.text += string(unicode.ToLower(r))
+	return ta.text
+}
+
+func (ta *typeAhead) reset() {
+	ta.text = ""
+}
+
+// isRepeat reports whether the text is one character typed several times,
+// which cycles through the items starting with that character.
+func isRepeat(text string) bool {
+	first, size := utf8.DecodeRuneInString(text)
+	return len(text) > size && strings.Trim(text, string(first)) == ""
+}
+
+// typeAheadKey handles a printable key pressed over the list and reports
+// whether it was used.
+func (fl *FileList) typeAheadKey(r rune) bool {
+	if !unicode.IsPrint(r) || (r == ' ' && fl.typeAhead.text == "") {
+		return false
+	}
+	text := fl.typeAhead.add(r, time.Now())
+	if len(fl.Items) == 0 {
+		return true
+	}
+
+	// A new prefix may still match the selected item, a repeated character
+	// moves on to the next match.
+	start := fl.SelectedIDX
+	if isRepeat(text) {
+		_, size := utf8.DecodeRuneInString(text)
+		text = text[:size]
+		start++
+	} else if utf8.RuneCountInString(text) == 1 {
+		start++
+	}
+
+	for n := 0; n < len(fl.Items); n++ {
+		i := (start + n) % len(fl.Items)
+		if strings.HasPrefix(strings.ToLower(fl.Items[i].Name), text) {
+			if i != fl.SelectedIDX {
+				fl.SetItem(i)
+				fl.SelectionChanged(i)
+			}
+			break
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package fileops
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetCreationTime returns the birth time of path. Filesystems that don't
+// record it fall back to the modification time.
+func GetCreationTime(path string) (time.Time, error) {
+	var stat unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME|unix.STATX_MTIME, &stat)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if stat.Mask&unix.STATX_BTIME != 0 {
+		return time.Unix(stat.Btime.Sec, int64(stat.Btime.Nsec)), nil
+	}
+	return time.Unix(stat.Mtime.Sec, int64(stat.Mtime.Nsec)), nil
+}
@@ -0,0 +1,127 @@
+package fileops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagDateTimeOriginal = 0x9003
+	exifTypeASCII           = 2
+)
+
+var errNoExifDate = errors.New("no exif date")
+
+// GetExifDate returns the date a JPEG photo was taken, read from its EXIF
+// DateTimeOriginal tag or the DateTime tag when that is missing.
+func GetExifDate(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	tiff, err := readExifSegment(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseExifDate(tiff)
+}
+
+// readExifSegment returns the TIFF data of the APP1 Exif segment.
+func readExifSegment(r io.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return nil, errNoExifDate
+	}
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil || header[0] != 0xFF {
+			return nil, errNoExifDate
+		}
+		marker := header[1]
+		length := int(binary.BigEndian.Uint16(header[2:])) - 2
+		if marker == 0xDA || length < 0 {
+			return nil, errNoExifDate
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, err
+		}
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+	}
+}
+
+func parseExifDate(tiff []byte) (time.Time, error) {
+	if len(tiff) < 8 {
+		return time.Time{}, errNoExifDate
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, errNoExifDate
+	}
+
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:]))
+	if offset, ok := ifd0[exifTagExifIFD]; ok {
+		exifIFD := readIFD(tiff, order, order.Uint32(offset))
+		if value, ok := exifIFD[exifTagDateTimeOriginal]; ok {
+			return parseExifTime(value)
+		}
+	}
+	if value, ok := ifd0[exifTagDateTime]; ok {
+		return parseExifTime(value)
+	}
+	return time.Time{}, errNoExifDate
+}
+
+// readIFD returns the raw values of the tags in the IFD at offset. ASCII
+// values are resolved, other values are the four bytes of the entry.
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16][]byte {
+	entries := make(map[uint16][]byte)
+	if int(offset)+2 > len(tiff) {
+		return entries
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		start := int(offset) + 2 + i*12
+		if start+12 > len(tiff) {
+			break
+		}
+		entry := tiff[start : start+12]
+		tag := order.Uint16(entry)
+		value := entry[8:12]
+		if order.Uint16(entry[2:]) == exifTypeASCII {
+			size := int(order.Uint32(entry[4:]))
+			if size > 4 {
+				valueOffset := int(order.Uint32(entry[8:]))
+				if valueOffset+size > len(tiff) {
+					continue
+				}
+				value = tiff[valueOffset : valueOffset+size]
+			} else {
+				value = entry[8 : 8+size]
+			}
+		}
+		entries[tag] = value
+	}
+	return entries
+}
+
+func parseExifTime(value []byte) (time.Time, error) {
+	text := strings.TrimRight(string(value), "\x00 ")
+	return time.ParseInLocation("2006:01:02 15:04:05", text, time.Local)
+}
@@ -5,9 +5,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/archive"
 )
 
 func Copy(sourcePath, destinationDir string) error {
+	if archive.IsArchivePath(sourcePath) {
+		return archive.Extract(sourcePath, destinationDir)
+	}
+
 	info, err := os.Stat(sourcePath)
 	if err != nil {
 		return err
@@ -85,9 +91,18 @@ func CutFiles(sourcePaths []string, destinationDir string) []error {
 		fileName := filepath.Base(sourcePath)
 		destinationPath := filepath.Join(destinationDir, fileName)
 
+		// Items inside an archive can only be copied out.
+		if archive.IsArchivePath(sourcePath) {
+			if err := archive.Extract(sourcePath, destinationPath); err != nil {
+				errors = append(errors, fmt.Errorf("error extracting %s: %w", sourcePath, err))
+			}
+			continue
+		}
 		if err := os.Rename(sourcePath, destinationPath); err != nil {
 			errors = append(errors, fmt.Errorf("error cutting %s: %w", sourcePath, err))
+			continue
 		}
+		Moved([]string{sourcePath}, []string{destinationPath})
 	}
 
 	return errors
@@ -0,0 +1,85 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+)
+
+type LinkMode int
+
+const (
+	SymlinkAbsolute LinkMode = iota
+	SymlinkRelative
+	Hardlink
+)
+
+var LinkModes = []LinkMode{SymlinkAbsolute, SymlinkRelative, Hardlink}
+
+func (m LinkMode) String() string {
+	switch m {
+	case SymlinkRelative:
+		return "Relative symbolic link"
+	case Hardlink:
+		return "Hard link"
+	default:
+		return "Symbolic link"
+	}
+}
+
+// LinkFiles creates a link to each of sourcePaths in destinationDir. A
+// name that is taken gets a number added, like pasting a copy does.
+func LinkFiles(sourcePaths []string, destinationDir string, mode LinkMode) []error {
+	var errors []error
+	for _, sourcePath := range sourcePaths {
+		destinationPath := archive.UniquePath(filepath.Join(destinationDir, filepath.Base(sourcePath)))
+		if err := Link(sourcePath, destinationPath, mode); err != nil {
+			errors = append(errors, fmt.Errorf("error linking %s: %w", sourcePath, err))
+		}
+	}
+	return errors
+}
+
+// Link creates a link to source at destination.
+func Link(source, destination string, mode LinkMode) error {
+	if archive.IsArchivePath(source) {
+		return fmt.Errorf("items inside an archive can't be linked")
+	}
+	switch mode {
+	case Hardlink:
+		info, err := os.Lstat(source)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return fmt.Errorf("folders can't be hard linked")
+		}
+		return os.Link(source, destination)
+	case SymlinkRelative:
+		target, err := filepath.Rel(filepath.Dir(destination), source)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, destination)
+	default:
+		target, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, destination)
+	}
+}
+
+// LinkTarget returns the absolute path a symbolic link at path points to.
+func LinkTarget(path string) (string, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return filepath.Clean(target), nil
+}
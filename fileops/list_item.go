@@ -0,0 +1,61 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+func NewListItem(dirPath string, entry os.DirEntry) *types.ListItem {
+	fullPath := filepath.Join(dirPath, entry.Name())
+	listItem := &types.ListItem{
+		Name:  entry.Name(),
+		Path:  fullPath,
+		IsDir: entry.IsDir(),
+	}
+	info, err := entry.Info()
+	if err == nil {
+		listItem.ModTime = info.ModTime()
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			listItem.ChangeTime = time.Unix(stat.Ctim.Unix())
+		}
+		if !listItem.IsDir {
+			listItem.Size = info.Size()
+		}
+	}
+	if entry.Type()&os.ModeSymlink != 0 {
+		// A link is shown with the size of its target, and a link to a
+		// folder opens like the folder.
+		listItem.IsSymlink = true
+		listItem.LinkTarget, _ = os.Readlink(fullPath)
+		listItem.Size = 0
+		if target, err := os.Stat(fullPath); err == nil {
+			listItem.IsDir = target.IsDir()
+			if !listItem.IsDir {
+				listItem.Size = target.Size()
+			}
+		} else {
+			listItem.BrokenLink = true
+		}
+	}
+	if created, err := GetCreationTime(fullPath); err == nil {
+		listItem.CreatedTime = created
+	} else {
+		listItem.CreatedTime = listItem.ModTime
+	}
+	if listItem.IsDir {
+		listItem.ItemCount = GetDirItemCount(fullPath)
+	}
+	return listItem
+}
+
+func GetDirItemCount(dirPath string) int {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
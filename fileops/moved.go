@@ -0,0 +1,18 @@
+package fileops
+
+var moveHooks []func(oldPaths, newPaths []string)
+
+// OnMove registers hook to be called after items are moved or renamed, so
+// that data kept by path, like tags, can follow them.
+func OnMove(hook func(oldPaths, newPaths []string)) {
+	moveHooks = append(moveHooks, hook)
+}
+
+// Moved reports that the items at oldPaths are now at the matching
+// newPaths. The moves are taken as one step, so an item may take the place
+// of another one that moved away.
+func Moved(oldPaths, newPaths []string) {
+	for _, hook := range moveHooks {
+		hook(oldPaths, newPaths)
+	}
+}
@@ -0,0 +1,94 @@
+package fileops
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// SyncCopy copies source over destination keeping the mode and modification
+// time, so that a later comparison sees both as equal. Files are written to
+// a temporary name first and a folder is copied with everything below it.
+func SyncCopy(source, destination string) error {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case info.IsDir():
+		if err := os.MkdirAll(destination, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := SyncCopy(filepath.Join(source, entry.Name()), filepath.Join(destination, entry.Name())); err != nil {
+				return err
+			}
+		}
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(source)
+		if err != nil {
+			return err
+		}
+		os.Remove(destination)
+		if err := os.Symlink(target, destination); err != nil {
+			return err
+		}
+		// The time is set on the link itself, never on what it points to,
+		// which may be outside both trees or missing.
+		times := []unix.Timespec{unix.NsecToTimespec(info.ModTime().UnixNano()), unix.NsecToTimespec(info.ModTime().UnixNano())}
+		return unix.UtimesNanoAt(unix.AT_FDCWD, destination, times, unix.AT_SYMLINK_NOFOLLOW)
+	default:
+		if err := syncFile(source, destination, info); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(destination, info.ModTime(), info.ModTime())
+}
+
+func syncFile(source, destination string, info os.FileInfo) error {
+	if existing, err := os.Lstat(destination); err == nil && existing.IsDir() {
+		return fmt.Errorf("%s is a folder", destination)
+	}
+
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	temp := filepath.Join(filepath.Dir(destination), "."+filepath.Base(destination)+".part")
+	tempFile, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tempFile, sourceFile); err != nil {
+		tempFile.Close()
+		os.Remove(temp)
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		os.Remove(temp)
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	if err := os.Rename(temp, destination); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package fileops
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/checksum"
+)
+
+// VerifyCopy compares every file below source with its copy below
+// destination by checksum.
+func VerifyCopy(source, destination string) error {
+	if archive.IsArchivePath(source) {
+		return nil
+	}
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		copied := filepath.Join(destination, rel)
+		algorithms := []checksum.Algorithm{checksum.BLAKE3}
+		want, err := checksum.File(context.Background(), path, algorithms, nil)
+		if err != nil {
+			return err
+		}
+		got, err := checksum.File(context.Background(), copied, algorithms, nil)
+		if err != nil {
+			return err
+		}
+		if want[checksum.BLAKE3] != got[checksum.BLAKE3] {
+			return fmt.Errorf("%s differs from %s after copying", copied, path)
+		}
+		return nil
+	})
+}
+
+// VerifyCopies checks the copies CopyFiles made of sourcePaths in
+// destinationDir.
+func VerifyCopies(sourcePaths []string, destinationDir string, progress func(float64)) []error {
+	var errors []error
+	for i, sourcePath := range sourcePaths {
+		destinationPath := filepath.Join(destinationDir, filepath.Base(sourcePath))
+		if err := VerifyCopy(sourcePath, destinationPath); err != nil {
+			errors = append(errors, err)
+		}
+		if progress != nil {
+			progress(float64(i+1) / float64(len(sourcePaths)))
+		}
+	}
+	return errors
+}
@@ -4,13 +4,28 @@ go 1.25.3
 
 require (
 	github.com/adrg/xdg v0.5.3
+	github.com/bodgit/sevenzip v1.6.5
 	github.com/diamondburned/gotk4-sourceview/pkg v0.0.0-20240312005410-8276faa7949c
 	github.com/diamondburned/gotk4/pkg v0.3.2-0.20250703063411-16654385f59a
+	github.com/klauspost/compress v1.20.1
+	github.com/nwaples/rardecode/v2 v2.4.1
+	github.com/ulikunitz/xz v0.5.17
+	golang.org/x/sync v0.22.0
+	golang.org/x/sys v0.40.0
+	golang.org/x/text v0.40.0
+	lukechampine.com/blake3 v1.4.1
 )
 
 require (
 	github.com/KarpelesLab/weak v0.1.1 // indirect
+	github.com/andybalholm/brotli v1.2.2 // indirect
+	github.com/bodgit/plumbing v1.3.0 // indirect
+	github.com/bodgit/windows v1.0.1 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.27 // indirect
+	github.com/spf13/afero v1.15.0 // indirect
+	github.com/stangelandcl/ppmd v0.1.1 // indirect
+	go4.org v0.0.0-20260112195520-a5071408f32f // indirect
 	go4.org/unsafe/assume-no-moving-gc v0.0.0-20231121144256-b99613f794b6 // indirect
-	golang.org/x/sync v0.7.0 // indirect
-	golang.org/x/sys v0.26.0 // indirect
 )
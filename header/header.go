@@ -10,6 +10,9 @@ type HeaderBar struct {
 	SearchButton         *gtk.Button
 	PreviewerPanelButton *gtk.Button
 	CircularProgressBar  *CircularProgressBar
+	JobsButton           *gtk.MenuButton
+	jobList              *gtk.Box
+	jobs                 []*job
 }
 
 func NewHeaderBar(mainWindow *gtk.ApplicationWindow) *HeaderBar {
@@ -26,6 +29,17 @@ func NewHeaderBar(mainWindow *gtk.ApplicationWindow) *HeaderBar {
 	circularProgressBar.SetVisible(false)
 	headerBar.PackStart(circularProgressBar)
 
+	jobList := gtk.NewBox(gtk.OrientationVertical, 6)
+	jobList.SetSizeRequest(260, -1)
+	jobsPopover := gtk.NewPopover()
+	jobsPopover.SetChild(jobList)
+	jobsButton := gtk.NewMenuButton()
+	jobsButton.SetIconName("view-list-symbolic")
+	jobsButton.SetTooltipText("Running jobs")
+	jobsButton.SetPopover(jobsPopover)
+	jobsButton.SetVisible(false)
+	headerBar.PackStart(jobsButton)
+
 	aboutButton := gtk.NewButtonFromIconName("help-about-symbolic")
 	aboutButton.ConnectClicked(func() {
 		aboutDialog := gtk.NewAboutDialog()
@@ -49,6 +63,8 @@ func NewHeaderBar(mainWindow *gtk.ApplicationWindow) *HeaderBar {
 		ShortcutsButton:      shortcutsButton,
 		SearchButton:         searchButton,
 		CircularProgressBar:  circularProgressBar,
+		JobsButton:           jobsButton,
+		jobList:              jobList,
 		PreviewerPanelButton: previewerPanelButton,
 	}
 }
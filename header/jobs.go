@@ -0,0 +1,116 @@
+package header
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// Progress is shown when it moved by minProgressStep or after
+// progressInterval, work reports it far more often than that.
+const (
+	minProgressStep  = 0.01
+	progressInterval = 100 * time.Millisecond
+)
+
+// job is a background task listed in the jobs popover.
+type job struct {
+	fraction    float64
+	cancel      context.CancelFunc
+	row         *gtk.Box
+	progressBar *gtk.ProgressBar
+}
+
+// RunJob runs work on a goroutine with a cancellable context and shows its
+// progress in the header bar. done is called on the main loop with the
+// result, which is context.Canceled when the user cancelled the job.
+func (h *HeaderBar) RunJob(name string, work func(ctx context.Context, progress func(float64)) error, done func(err error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{cancel: cancel}
+
+	j.row = gtk.NewBox(gtk.OrientationHorizontal, 6)
+	labels := gtk.NewBox(gtk.OrientationVertical, 2)
+	labels.SetHExpand(true)
+	label := gtk.NewLabel(name)
+	label.SetXAlign(0)
+	labels.Append(label)
+	j.progressBar = gtk.NewProgressBar()
+	labels.Append(j.progressBar)
+	j.row.Append(labels)
+	cancelButton := gtk.NewButtonFromIconName("process-stop-symbolic")
+	cancelButton.SetTooltipText("Cancel")
+	cancelButton.ConnectClicked(func() {
+		cancel()
+		cancelButton.SetSensitive(false)
+	})
+	j.row.Append(cancelButton)
+
+	h.jobs = append(h.jobs, j)
+	h.jobList.Append(j.row)
+	h.updateJobs()
+
+	var mu sync.Mutex
+	var shown float64
+	var shownAt time.Time
+	go func() {
+		err := work(ctx, func(fraction float64) {
+			mu.Lock()
+			now := time.Now()
+			if fraction < 1 && fraction-shown < minProgressStep && now.Sub(shownAt) < progressInterval {
+				mu.Unlock()
+				return
+			}
+			shown, shownAt = fraction, now
+			mu.Unlock()
+			glib.IdleAdd(func() {
+				j.fraction = fraction
+				j.progressBar.SetFraction(fraction)
+				h.updateJobs()
+			})
+		})
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		glib.IdleAdd(func() {
+			cancel()
+			h.removeJob(j)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				println(name, "failed:", err.Error())
+			}
+			if done != nil {
+				done(err)
+			}
+		})
+	}()
+}
+
+func (h *HeaderBar) removeJob(j *job) {
+	for i, existing := range h.jobs {
+		if existing == j {
+			h.jobs = append(h.jobs[:i], h.jobs[i+1:]...)
+			break
+		}
+	}
+	h.jobList.Remove(j.row)
+	h.updateJobs()
+}
+
+// updateJobs shows the average progress of the running jobs.
+func (h *HeaderBar) updateJobs() {
+	h.JobsButton.SetVisible(len(h.jobs) > 0)
+	if len(h.jobs) == 0 {
+		h.HideProgress()
+		h.JobsButton.Popdown()
+		return
+	}
+	total := 0.0
+	for _, j := range h.jobs {
+		total += j.fraction
+	}
+	h.ShowProgress()
+	h.SetProgress(total / float64(len(h.jobs)))
+}
@@ -0,0 +1,261 @@
+package json_store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// saveDelay batches the changes made in quick succession into one write.
+const saveDelay = 500 * time.Millisecond
+
+// Store is a JSON file in the atilgan config directory that several running
+// instances share. Writes happen under a lock by replacing the file, so a
+// reader never sees half of one, and every instance reloads the file when
+// another one replaces it.
+type Store struct {
+	path     string
+	lockPath string
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending func()
+	written os.FileInfo
+
+	// Changed is called, from another goroutine, after another program
+	// replaced the file.
+	Changed func()
+}
+
+func Open(name string) (*Store, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(configDir, "atilgan")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		path:     filepath.Join(dir, name),
+		lockPath: filepath.Join(dir, "."+name+".lock"),
+	}, nil
+}
+
+// OpenFile is a Store for a file at path that other programs share too,
+// like the desktop's recently used files. The lock only keeps atilgan
+// instances from writing it at once, and is kept in the atilgan config
+// directory rather than next to the file.
+func OpenFile(path string) (*Store, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(configDir, "atilgan")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		path:     path,
+		lockPath: filepath.Join(dir, "."+filepath.Base(path)+".lock"),
+	}, nil
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+// lock holds an flock of the kind how on the lock file until the returned
+// function is called. The data file itself is replaced on each write, so it
+// can't carry the lock.
+func (s *Store) lock(how int) (func(), error) {
+	file, err := os.OpenFile(s.lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err = unix.Flock(int(file.Fd()), how)
+		if !errors.Is(err, unix.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		unix.Flock(int(file.Fd()), unix.LOCK_UN)
+		file.Close()
+	}, nil
+}
+
+// Read returns the contents of the file, nil when there is none yet.
+func (s *Store) Read() ([]byte, error) {
+	unlock, err := s.lock(unix.LOCK_SH)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Update replaces the file with what merge makes of its current contents,
+// nil when there is no file yet. No other instance writes in between.
+func (s *Store) Update(merge func(data []byte) ([]byte, error)) error {
+	unlock, err := s.lock(unix.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data, err = merge(data)
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// write puts data in place through a temporary file so that the file is
+// either the old or the new version after a crash.
+func (s *Store) write(data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	// The file keeps its mode, shared files like the desktop's recent files
+	// are private. New files are too.
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(s.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	// The file keeps its inode and time through the rename, so the watcher
+	// can tell it apart right away.
+	info, err := os.Stat(temp.Name())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	previous := s.written
+	s.written = info
+	s.mu.Unlock()
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		s.mu.Lock()
+		s.written = previous
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Schedule calls flush once no other change came for a moment. flush
+// replaces any call scheduled before.
+func (s *Store) Schedule(flush func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = flush
+	if s.timer == nil {
+		s.timer = time.AfterFunc(saveDelay, s.Flush)
+	}
+}
+
+// Flush makes the scheduled call now, if there is one.
+func (s *Store) Flush() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	flush := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if flush != nil {
+		flush()
+	}
+}
+
+// Watch starts calling Changed when the file is replaced by another
+// program.
+func (s *Store) Watch() error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	if _, err := unix.InotifyAddWatch(fd, filepath.Dir(s.path), unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO); err != nil {
+		unix.Close(fd)
+		return err
+	}
+	go s.watch(fd)
+	return nil
+}
+
+func (s *Store) watch(fd int) {
+	defer unix.Close(fd)
+	name := filepath.Base(s.path)
+	buffer := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(fd, buffer)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			println("stopped watching", s.path, err.Error())
+			return
+		}
+		changed := false
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			start := offset + unix.SizeofInotifyEvent
+			end := start + int(event.Len)
+			if strings.TrimRight(string(buffer[start:end]), "\x00") == name {
+				changed = true
+			}
+			offset = end
+		}
+		if changed && s.replacedByOther() {
+			if s.Changed != nil {
+				s.Changed()
+			}
+		}
+	}
+}
+
+// replacedByOther reports whether the file is no longer the one this
+// instance wrote last.
+func (s *Store) replacedByOther() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written == nil || !os.SameFile(info, s.written) || !info.ModTime().Equal(s.written.ModTime())
+}
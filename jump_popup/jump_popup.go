@@ -0,0 +1,190 @@
+package jump_popup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/recent"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+const maxMatches = 8
+
+// JumpPopup goes to the best ranked recent folder that matches a few
+// keywords, like zoxide does in a shell.
+type JumpPopup struct {
+	*gtk.Window
+	// Jumped is called with the folder to go to.
+	Jumped func(path string)
+
+	recentManager *recent.RecentManager
+	current       string
+	entry         *gtk.Entry
+	matches       *gtk.ListBox
+	statusLabel   *gtk.Label
+	goButton      *gtk.Button
+}
+
+func NewJumpPopup(parent *gtk.Window, recentManager *recent.RecentManager, current string) *JumpPopup {
+	popup := &JumpPopup{
+		Window:        gtk.NewWindow(),
+		recentManager: recentManager,
+		current:       current,
+		entry:         gtk.NewEntry(),
+		matches:       gtk.NewListBox(),
+		statusLabel:   gtk.NewLabel(""),
+		goButton:      gtk.NewButtonWithLabel("Go"),
+	}
+
+	popup.SetTransientFor(parent)
+	popup.SetModal(true)
+	popup.SetTitle("Jump to Folder")
+	popup.SetDefaultSize(460, -1)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	popup.SetChild(box)
+
+	popup.entry.SetPlaceholderText("Keywords, like proj api")
+	box.Append(popup.entry)
+
+	popup.matches.AddCSSClass("boxed-list")
+	popup.matches.ConnectRowActivated(func(row *gtk.ListBoxRow) {
+		popup.jump(row.Name())
+	})
+	box.Append(popup.matches)
+
+	popup.statusLabel.SetXAlign(0)
+	popup.statusLabel.SetWrap(true)
+	popup.statusLabel.AddCSSClass("dim-label")
+	box.Append(popup.statusLabel)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	importButton := popup.newImportButton()
+	importButton.SetHExpand(true)
+	importButton.SetHAlign(gtk.AlignStart)
+	buttonBox.Append(importButton)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(popup.Close)
+	buttonBox.Append(cancelButton)
+	popup.goButton.AddCSSClass("suggested-action")
+	popup.goButton.ConnectClicked(func() {
+		if row := popup.matches.SelectedRow(); row != nil {
+			popup.jump(row.Name())
+		}
+	})
+	buttonBox.Append(popup.goButton)
+	box.Append(buttonBox)
+
+	popup.entry.ConnectActivate(func() {
+		popup.goButton.Activate()
+	})
+	popup.entry.ConnectChanged(popup.update)
+	// The arrow keys pick a match while the keywords keep the focus.
+	keyController := gtk.NewEventControllerKey()
+	keyController.ConnectKeyPressed(func(keyval, keycode uint, state gdk.ModifierType) bool {
+		switch keyval {
+		case gdk.KEY_Down:
+			popup.moveSelection(1)
+			return true
+		case gdk.KEY_Up:
+			popup.moveSelection(-1)
+			return true
+		}
+		return false
+	})
+	popup.entry.AddController(keyController)
+
+	popup.update()
+	return popup
+}
+
+// update lists the folders the keywords match, the best ranked selected.
+func (popup *JumpPopup) update() {
+	popup.matches.RemoveAll()
+	matches := popup.recentManager.Jump(strings.Fields(popup.entry.Text()), popup.current, maxMatches)
+	for _, match := range matches {
+		label := gtk.NewLabel(shortenHome(match.Path))
+		label.SetXAlign(0)
+		label.SetEllipsize(pango.EllipsizeStart)
+		label.SetMarginTop(4)
+		label.SetMarginBottom(4)
+		label.SetMarginStart(8)
+		label.SetMarginEnd(8)
+		row := gtk.NewListBoxRow()
+		row.SetName(match.Path)
+		row.SetChild(label)
+		popup.matches.Append(row)
+	}
+	popup.matches.SelectRow(popup.matches.RowAtIndex(0))
+	popup.matches.SetVisible(len(matches) > 0)
+	popup.goButton.SetSensitive(len(matches) > 0)
+	if len(matches) == 0 {
+		popup.statusLabel.SetText("No visited folder matches. Folders are remembered as you open them.")
+	} else {
+		popup.statusLabel.SetText("")
+	}
+}
+
+func (popup *JumpPopup) moveSelection(step int) {
+	index := 0
+	if row := popup.matches.SelectedRow(); row != nil {
+		index = row.Index() + step
+	}
+	if row := popup.matches.RowAtIndex(index); row != nil {
+		popup.matches.SelectRow(row)
+	}
+}
+
+func (popup *JumpPopup) jump(path string) {
+	if popup.Jumped != nil {
+		popup.Jumped(path)
+	}
+	popup.Close()
+}
+
+// newImportButton offers to take in the folders another jump tool ranked.
+func (popup *JumpPopup) newImportButton() *gtk.MenuButton {
+	menu := gtk.NewPopover()
+	box := gtk.NewBox(gtk.OrientationVertical, 0)
+	for _, tool := range recent.JumpTools {
+		button := gtk.NewButtonWithLabel("From " + string(tool))
+		button.AddCSSClass("flat")
+		button.ConnectClicked(func() {
+			menu.Popdown()
+			count, err := popup.recentManager.Import(tool)
+			if err != nil {
+				popup.statusLabel.SetText(fmt.Sprintf("Couldn't import from %s: %s", tool, err))
+				return
+			}
+			popup.update()
+			popup.statusLabel.SetText(fmt.Sprintf("Imported %d folders from %s.", count, tool))
+		})
+		box.Append(button)
+	}
+	menu.SetChild(box)
+
+	button := gtk.NewMenuButton()
+	button.SetLabel("Import…")
+	button.SetTooltipText("Import the folders of zoxide, autojump or z")
+	button.SetPopover(menu)
+	return button
+}
+
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && filepath.IsLocal(rel) {
+		return filepath.Join("~", rel)
+	}
+	return path
+}
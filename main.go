@@ -1,20 +1,40 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/archive_popup"
+	"github.com/MrSametBurgazoglu/atilgan/checksum_popup"
 	"github.com/MrSametBurgazoglu/atilgan/clipboard"
+	"github.com/MrSametBurgazoglu/atilgan/compare_popup"
+	"github.com/MrSametBurgazoglu/atilgan/dir_compare"
+	"github.com/MrSametBurgazoglu/atilgan/disk_usage"
+	"github.com/MrSametBurgazoglu/atilgan/duplicates"
+	"github.com/MrSametBurgazoglu/atilgan/file_list"
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
 	"github.com/MrSametBurgazoglu/atilgan/header"
+	"github.com/MrSametBurgazoglu/atilgan/jump_popup"
 	"github.com/MrSametBurgazoglu/atilgan/pathbar"
 	"github.com/MrSametBurgazoglu/atilgan/previewer"
 	"github.com/MrSametBurgazoglu/atilgan/previewer_panel"
+	"github.com/MrSametBurgazoglu/atilgan/properties"
+	"github.com/MrSametBurgazoglu/atilgan/properties_popup"
 	"github.com/MrSametBurgazoglu/atilgan/rename_popup"
 	"github.com/MrSametBurgazoglu/atilgan/search"
 	"github.com/MrSametBurgazoglu/atilgan/shortcut_popup"
 	"github.com/MrSametBurgazoglu/atilgan/sidebar"
 	"github.com/MrSametBurgazoglu/atilgan/special_path"
+	"github.com/MrSametBurgazoglu/atilgan/tag"
+	"github.com/MrSametBurgazoglu/atilgan/tag_popup"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/MrSametBurgazoglu/atilgan/undo"
+	"github.com/MrSametBurgazoglu/atilgan/view_state"
 	"github.com/MrSametBurgazoglu/atilgan/viewer"
 	"github.com/MrSametBurgazoglu/atilgan/viewer_panel"
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
@@ -35,11 +55,14 @@ type MainBox struct {
 	SpecialPaths   *special_path.SpecialPathManager
 	Search         *search.Search
 	SideBar        *sidebar.Sidebar
+	ViewStates     *view_state.ViewStateManager
+	History        *undo.History
+	scanDiskUsage  func(root string, opts disk_usage.Options)
 }
 
 func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 	mainVBox := gtk.NewBox(gtk.OrientationVertical, 6)
-	mainBox := &MainBox{Box: mainVBox}
+	mainBox := &MainBox{Box: mainVBox, History: undo.NewHistory()}
 
 	curdir, err := os.Getwd()
 	if err != nil {
@@ -67,6 +90,11 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 		println(err.Error())
 	}
 
+	mainBox.ViewStates, err = view_state.NewViewStateManager()
+	if err != nil {
+		println(err.Error())
+	}
+
 	mainBox.Path = curdir
 	mainBox.Pathbar = pathbar.NewPathBar(mainBox.pathChanged)
 	mainBox.Pathbar.UpdatePathBar(curdir)
@@ -84,7 +112,7 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 
 	mainHBox.Append(mainBox.SideBar)
 
-	mainBox.ViewerPanel = viewer_panel.NewPanel(mainWindow, curdir, mainBox.pathChanged, mainBox.SpecialPaths)
+	mainBox.ViewerPanel = viewer_panel.NewPanel(mainWindow, curdir, mainBox.pathChanged, mainBox.SpecialPaths, mainBox.ViewStates)
 	mainBox.ViewerPanel.FileViewer.Box.Append(mainBox.Pathbar)
 	mainHBox.Append(mainBox.ViewerPanel)
 
@@ -94,7 +122,7 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 	rightBox := gtk.NewBox(gtk.OrientationVertical, 6)
 	mainHBox.Append(rightBox)
 
-	mainBox.PreviewerPanel = previewer_panel.NewPreviewPanel(curdir, mainBox.pathChanged, mainBox.SpecialPaths)
+	mainBox.PreviewerPanel = previewer_panel.NewPreviewPanel(curdir, mainBox.pathChanged, mainBox.SpecialPaths, mainBox.ViewStates)
 	rightBox.Append(mainBox.PreviewerPanel)
 
 	copyCutPreviewer := previewer.NewCopyCutPreviewer()
@@ -106,11 +134,297 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 	}
 
 	mainBox.ViewerPanel.FileViewer.FileViewerList.PathChanged = mainBox.pathChanged
+	mainBox.ViewerPanel.FileViewer.FileViewerList.Renamed = func(oldPath, newPath string) {
+		mainBox.History.Push(rename_popup.NewRenameBatch(oldPath, newPath))
+		mainBox.pathChanged(mainBox.Path)
+	}
+	mainBox.ViewerPanel.FileViewer.FileViewerList.BulkRename = func(items []*types.ListItem) {
+		bulkRenameWindow := rename_popup.NewBulkRenameWindow(items)
+		bulkRenameWindow.Applied = func(batch *rename_popup.Batch) {
+			mainBox.History.Push(batch)
+			mainBox.pathChanged(mainBox.Path)
+			mainBox.ViewerPanel.FileViewer.FileViewerList.SelectPaths(batch.NewPaths())
+		}
+		bulkRenameWindow.SetTransientFor(mainWindow)
+		bulkRenameWindow.SetVisible(true)
+	}
+	mainBox.ViewerPanel.FileViewer.FileViewerList.CompressItems = func(items []*types.ListItem) {
+		sources := make([]string, len(items))
+		for i, item := range items {
+			sources[i] = item.Path
+		}
+		compressWindow := archive_popup.NewCompressWindow(sources)
+		compressWindow.Applied = func(destination string, opts archive.CompressOptions) {
+			headerBar.RunJob("Compressing "+filepath.Base(destination), func(ctx context.Context, progress func(float64)) error {
+				return archive.Compress(ctx, sources, destination, opts, progress)
+			}, func(err error) {
+				mainBox.pathChanged(mainBox.Path)
+				if err == nil {
+					mainBox.ViewerPanel.FileViewer.FileViewerList.SelectPaths([]string{destination})
+				}
+			})
+		}
+		compressWindow.SetTransientFor(mainWindow)
+		compressWindow.SetVisible(true)
+	}
+	findDuplicates := func(root string) {
+		var groups []duplicates.Group
+		headerBar.RunJob("Finding duplicates in "+filepath.Base(root), func(ctx context.Context, progress func(float64)) error {
+			var err error
+			groups, err = duplicates.Find(ctx, root, progress)
+			return err
+		}, func(err error) {
+			if err != nil {
+				return
+			}
+			mainBox.SpecialPaths.GetDuplicates().Set(root, groups)
+			mainBox.pathChanged(duplicates.PathFor(root))
+		})
+	}
+	mainBox.scanDiskUsage = func(root string, opts disk_usage.Options) {
+		cache := mainBox.SpecialPaths.GetDiskUsage()
+		if !cache.StartScan(root) {
+			return
+		}
+		var node *disk_usage.Node
+		headerBar.RunJob("Measuring "+filepath.Base(root), func(ctx context.Context, progress func(float64)) error {
+			var err error
+			node, err = disk_usage.Scan(ctx, root, opts, progress)
+			return err
+		}, func(err error) {
+			if err != nil {
+				cache.CancelScan(root)
+				return
+			}
+			cache.Store(node)
+			mainBox.ViewerPanel.FileViewer.Refresh(false)
+			mainBox.updatePreviewer()
+		})
+	}
+	mainBox.ViewerPanel.FileViewer.DiskUsageToggled = func(active bool) {
+		mainBox.updatePreviewer()
+	}
+	mainBox.PreviewerPanel.RescanDiskUsage = mainBox.scanDiskUsage
+	mainBox.ViewerPanel.FileViewer.RunJob = headerBar.RunJob
+	mainBox.ViewerPanel.FileViewer.FindDuplicates = findDuplicates
+	mainBox.ViewerPanel.FileViewer.FileViewerList.FindDuplicates = func(item *types.ListItem) {
+		findDuplicates(item.Path)
+	}
+	compareFolders := func(left, right string, opts dir_compare.Options) {
+		var entries []dir_compare.Entry
+		headerBar.RunJob(fmt.Sprintf("Comparing %s and %s", filepath.Base(left), filepath.Base(right)), func(ctx context.Context, progress func(float64)) error {
+			var err error
+			entries, err = dir_compare.Compare(ctx, left, right, opts, progress)
+			return err
+		}, func(err error) {
+			if err != nil {
+				return
+			}
+			mainBox.SpecialPaths.GetComparisons().Set(left, right, opts, entries)
+			mainBox.pathChanged(dir_compare.PathFor(left, right))
+		})
+	}
+	mainBox.ViewerPanel.FileViewer.CompareFolders = compareFolders
+	mainBox.ViewerPanel.FileViewer.FileViewerList.CompareFolders = func(left, right string) {
+		compareWindow := compare_popup.NewCompareWindow(left, right)
+		compareWindow.Applied = compareFolders
+		compareWindow.SetTransientFor(mainWindow)
+		compareWindow.SetVisible(true)
+	}
+	mainBox.ViewerPanel.FileViewer.SyncFolders = func(result *dir_compare.Result, selected []string) {
+		syncWindow := compare_popup.NewSyncWindow(result, selected)
+		syncWindow.Applied = func(actions []dir_compare.Action) {
+			headerBar.RunJob(fmt.Sprintf("Syncing %s and %s", filepath.Base(result.Left), filepath.Base(result.Right)), func(ctx context.Context, progress func(float64)) error {
+				errs := dir_compare.Apply(ctx, actions, progress)
+				for _, err := range errs {
+					println(err.Error())
+				}
+				if len(errs) > 0 {
+					return errs[0]
+				}
+				return nil
+			}, func(err error) {
+				compareFolders(result.Left, result.Right, result.Options)
+			})
+		}
+		syncWindow.SetTransientFor(mainWindow)
+		syncWindow.SetVisible(true)
+	}
+	showProperties := func(path string) {
+		propertiesWindow, err := properties_popup.NewPropertiesWindow(path)
+		if err != nil {
+			println("couldn't read properties:", err.Error())
+			return
+		}
+		propertiesWindow.Applied = func(change properties.Change) {
+			headerBar.RunJob("Changing permissions of "+filepath.Base(path), func(ctx context.Context, progress func(float64)) error {
+				errs := properties.Apply(ctx, path, change, progress)
+				for _, err := range errs {
+					println(err.Error())
+				}
+				if len(errs) > 0 {
+					return errs[0]
+				}
+				return nil
+			}, func(err error) {
+				mainBox.pathChanged(mainBox.Path)
+			})
+		}
+		propertiesWindow.SetTransientFor(mainWindow)
+		propertiesWindow.SetVisible(true)
+	}
+	mainBox.ViewerPanel.FileViewer.FileViewerList.ShowProperties = func(item *types.ListItem) {
+		showProperties(item.Path)
+	}
+	mainBox.ViewerPanel.FileViewer.FileViewerList.GoToLinkTarget = func(item *types.ListItem) {
+		target, err := fileops.LinkTarget(item.Path)
+		if err != nil {
+			println("couldn't read link:", err.Error())
+			return
+		}
+		if _, err := os.Stat(filepath.Dir(target)); err != nil {
+			println("link target folder doesn't exist:", filepath.Dir(target))
+			return
+		}
+		mainBox.pathChanged(filepath.Dir(target))
+		mainBox.ViewerPanel.FileViewer.FileViewerList.SelectPaths([]string{target})
+		mainBox.updatePreviewer()
+	}
+	if mainBox.SpecialPaths != nil {
+		tagManager := mainBox.SpecialPaths.GetTagManager()
+		mainBox.Pathbar.IsTag = tagManager.IsTag
+		tagManager.Changed = func() {
+			mainBox.SideBar.SetTagColors(tagManager.UsedColors())
+			mainBox.SideBar.SetPinnedQueries(tagManager.PinnedQueries())
+			mainBox.ViewerPanel.FileViewer.Refresh(false)
+		}
+		// Another window changed tags.json or recent.json.
+		tagManager.Reloaded = func() {
+			glib.IdleAdd(tagManager.Changed)
+		}
+		mainBox.SpecialPaths.GetRecentManager().Reloaded = func() {
+			glib.IdleAdd(func() {
+				if strings.HasPrefix(mainBox.Path, "recent://") {
+					mainBox.ViewerPanel.FileViewer.Refresh(false)
+				}
+			})
+		}
+		mainBox.SideBar.SetTagColors(tagManager.UsedColors())
+		mainBox.SideBar.SetPinnedQueries(tagManager.PinnedQueries())
+		mainBox.SideBar.UnpinQuery = tagManager.UnpinQuery
+		showTagsWindow := func(manageWindow *tag_popup.ManageWindow) {
+			manageWindow.SetTransientFor(mainWindow)
+			manageWindow.SetVisible(true)
+		}
+		mainBox.ViewerPanel.FileViewer.RenameTag = func(t string) {
+			showTagsWindow(tag_popup.NewRenameTagWindow(tagManager, t))
+		}
+		mainBox.ViewerPanel.FileViewer.MergeTags = func(tags []string) {
+			showTagsWindow(tag_popup.NewMergeTagsWindow(tagManager, tags))
+		}
+		mainBox.ViewerPanel.FileViewer.DeleteTags = func(tags []string) {
+			showTagsWindow(tag_popup.NewDeleteTagsWindow(tagManager, tags))
+		}
+		autoTagger := mainBox.SpecialPaths.GetAutoTagger()
+		autoTagger.Tagged = func(path string) {
+			glib.IdleAdd(tagManager.Changed)
+		}
+		mainBox.ViewerPanel.FileViewer.EditTagRules = func() {
+			rulesWindow := tag_popup.NewRulesWindow(autoTagger)
+			rulesWindow.SetTransientFor(mainWindow)
+			rulesWindow.SetVisible(true)
+		}
+		runExchange := func(name string, ex tag.Exchange, run func(ctx context.Context, ex tag.Exchange, progress func(float64)) (int, error)) {
+			headerBar.RunJob(name, func(ctx context.Context, progress func(float64)) error {
+				_, err := run(ctx, ex, progress)
+				return err
+			}, func(err error) {
+				tagManager.Changed()
+			})
+		}
+		mainBox.ViewerPanel.FileViewer.ImportTags = func() {
+			importWindow := tag_popup.NewImportWindow()
+			importWindow.Applied = func(ex tag.Exchange) {
+				runExchange("Importing tags", ex, tagManager.Import)
+			}
+			importWindow.SetTransientFor(mainWindow)
+			importWindow.SetVisible(true)
+		}
+		mainBox.ViewerPanel.FileViewer.ExportTags = func() {
+			exportWindow := tag_popup.NewExportWindow()
+			exportWindow.Applied = func(ex tag.Exchange) {
+				runExchange("Exporting tags", ex, tagManager.Export)
+			}
+			exportWindow.SetTransientFor(mainWindow)
+			exportWindow.SetVisible(true)
+		}
+		mainBox.ViewerPanel.FileViewer.FileViewerList.ApplyTagRules = func(items []*types.ListItem) {
+			roots := make([]string, len(items))
+			for i, item := range items {
+				roots[i] = item.Path
+			}
+			name := fmt.Sprintf("Tagging %d items", len(roots))
+			if len(roots) == 1 {
+				name = "Tagging " + filepath.Base(roots[0])
+			}
+			headerBar.RunJob(name, func(ctx context.Context, progress func(float64)) error {
+				_, err := autoTagger.Apply(ctx, roots, progress)
+				return err
+			}, func(err error) {
+				tagManager.Changed()
+			})
+		}
+	}
+	showChecksums := func(paths []string) {
+		checksumWindow := checksum_popup.NewChecksumWindow(paths)
+		checksumWindow.ManifestCreated = func(path string) {
+			mainBox.pathChanged(mainBox.Path)
+		}
+		checksumWindow.SetTransientFor(mainWindow)
+		checksumWindow.SetVisible(true)
+	}
+	mainBox.ViewerPanel.FileViewer.FileViewerList.Checksums = func(items []*types.ListItem) {
+		paths := make([]string, len(items))
+		for i, item := range items {
+			paths[i] = item.Path
+		}
+		showChecksums(paths)
+	}
+	mainBox.PreviewerPanel.ChecksumsClicked = func(path string) {
+		showChecksums([]string{path})
+	}
+	mainBox.ViewerPanel.FileViewer.FileViewerList.ExtractItem = func(item *types.ListItem, chooseDestination bool) {
+		extract := func(destination string, conflict archive.ConflictMode) {
+			headerBar.RunJob("Extracting "+item.Name, func(ctx context.Context, progress func(float64)) error {
+				return archive.ExtractArchive(ctx, item.Path, destination, conflict, progress)
+			}, func(err error) {
+				mainBox.pathChanged(mainBox.Path)
+			})
+		}
+		if !chooseDestination {
+			destination, err := archive.DefaultExtractDir(item.Path)
+			if err != nil {
+				println("couldn't read archive:", err.Error())
+				return
+			}
+			extract(destination, archive.ConflictRename)
+			return
+		}
+		extractWindow := archive_popup.NewExtractWindow(item.Path)
+		extractWindow.Applied = extract
+		extractWindow.SetTransientFor(mainWindow)
+		extractWindow.SetVisible(true)
+	}
 
 	mainBox.ViewerPanel.FileViewer.FileViewerList.KeyLeftPressed = func() {
-		specialPath := mainBox.SpecialPaths.GetPath(mainBox.ViewerPanel.FileViewer.Path)
+		currentPath := mainBox.ViewerPanel.FileViewer.Path
+		specialPath := mainBox.SpecialPaths.GetPath(currentPath)
 		if specialPath != nil {
 			mainBox.pathChanged(specialPath.GetParentPath())
+			if archiveFile, inner, ok := archive.SplitPath(currentPath); ok && inner == "" {
+				currentPath = archiveFile
+			}
+			mainBox.ViewerPanel.FileViewer.FileViewerList.SelectPath(currentPath)
 		} else {
 			parentDir := filepath.Dir(mainBox.ViewerPanel.FileViewer.Path)
 			mainBox.pathChanged(parentDir)
@@ -129,13 +443,51 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 	)
 
 	renameShortcut := gtk.NewShortcut(renameTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
-		selectedItem := mainBox.ViewerPanel.FileViewer.FileViewerList.Items[mainBox.ViewerPanel.FileViewer.FileViewerList.SelectedIDX]
-		renameWindow := rename_popup.NewRenameWindow(mainBox.Path, selectedItem.Path)
-		renameWindow.SetTransientFor(mainWindow)
-		renameWindow.SetVisible(true)
+		if mainBox.SpecialPaths.GetPath(mainBox.Path) != nil {
+			return true
+		}
+		mainBox.ViewerPanel.FileViewer.FileViewerList.Rename()
 		return true
 	}))
 	controller.AddShortcut(renameShortcut)
+	controller.AddShortcut(gtk.NewShortcut(gtk.NewKeyvalTrigger(gdk.KEY_F2, 0), renameShortcut.Action()))
+
+	undoTrigger := gtk.NewKeyvalTrigger(gdk.KEY_z, gdk.ControlMask)
+	undoShortcut := gtk.NewShortcut(undoTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
+		if !mainBox.History.CanUndo() {
+			return true
+		}
+		if op, err := mainBox.History.Undo(); err != nil {
+			println("couldn't undo", op.Name()+":", err.Error())
+		}
+		mainBox.pathChanged(mainBox.Path)
+		return true
+	}))
+	controller.AddShortcut(undoShortcut)
+
+	propertiesTrigger := gtk.NewKeyvalTrigger(gdk.KEY_Return, gdk.AltMask)
+	propertiesShortcut := gtk.NewShortcut(propertiesTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
+		fileList := mainBox.ViewerPanel.FileViewer.FileViewerList
+		if len(fileList.Items) == 0 || archive.IsArchivePath(fileList.Items[fileList.SelectedIDX].Path) {
+			return true
+		}
+		showProperties(fileList.Items[fileList.SelectedIDX].Path)
+		return true
+	}))
+	controller.AddShortcut(propertiesShortcut)
+
+	redoTrigger := gtk.NewKeyvalTrigger(gdk.KEY_z, gdk.ControlMask|gdk.ShiftMask)
+	redoShortcut := gtk.NewShortcut(redoTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
+		if !mainBox.History.CanRedo() {
+			return true
+		}
+		if op, err := mainBox.History.Redo(); err != nil {
+			println("couldn't redo", op.Name()+":", err.Error())
+		}
+		mainBox.pathChanged(mainBox.Path)
+		return true
+	}))
+	controller.AddShortcut(redoShortcut)
 
 	searchTrigger := gtk.NewKeyvalTrigger(
 		gdk.KEY_f,
@@ -158,7 +510,8 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 
 	copyTrigger := gtk.NewKeyvalTrigger(gdk.KEY_c, gdk.ControlMask)
 	copyShortcut := gtk.NewShortcut(copyTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
-		if mainBox.SpecialPaths.Paths[mainBox.Path] != nil {
+		inArchive := archive.IsArchivePath(mainBox.Path)
+		if mainBox.SpecialPaths.GetPath(mainBox.Path) != nil && !inArchive {
 			return true
 		}
 		mainBox.ViewerPanel.FileViewer.IsCopy = true
@@ -167,6 +520,9 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 		copyCutPreviewer.IsCut = false
 		copyCutPreviewer.SetFiles(mainBox.ViewerPanel.FileViewer.CopiedCuttedFiles)
 		copyCutPreviewer.SetVisible(true)
+		if inArchive {
+			return true
+		}
 		clipboard.CopyFileToClipboard(gio.NewFileForPath(mainBox.ViewerPanel.FileViewer.FileViewerList.Items[mainBox.ViewerPanel.FileViewer.FileViewerList.SelectedIDX].Path))
 		return true
 	}))
@@ -174,7 +530,7 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 
 	cutTrigger := gtk.NewKeyvalTrigger(gdk.KEY_x, gdk.ControlMask)
 	cutShortcut := gtk.NewShortcut(cutTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
-		if mainBox.SpecialPaths.Paths[mainBox.Path] != nil {
+		if mainBox.SpecialPaths.GetPath(mainBox.Path) != nil {
 			return true
 		}
 		mainBox.ViewerPanel.FileViewer.IsCopy = true
@@ -187,11 +543,13 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 	}))
 	controller.AddShortcut(cutShortcut)
 
-	pasteTrigger := gtk.NewKeyvalTrigger(gdk.KEY_v, gdk.ControlMask)
-	pasteShortcut := gtk.NewShortcut(pasteTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
-		if mainBox.SpecialPaths.Paths[mainBox.Path] != nil {
-			return true
+	paste := func(link bool) {
+		if mainBox.SpecialPaths.GetPath(mainBox.Path) != nil {
+			return
 		}
+		mainBox.ViewerPanel.FileViewer.VerifyCopies = copyCutPreviewer.VerifyCheck.Active()
+		mainBox.ViewerPanel.FileViewer.IsLink = link
+		mainBox.ViewerPanel.FileViewer.LinkMode = copyCutPreviewer.LinkMode()
 		headerBar.ShowProgress()
 		go func() error {
 			if err := mainBox.ViewerPanel.FileViewer.ExecuteCopyPaste(func(f float64) {
@@ -207,13 +565,27 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 					copyCutPreviewer.SetVisible(false)
 					headerBar.HideProgress()
 				})
+			} else {
+				glib.IdleAdd(headerBar.HideProgress)
 			}
 			return nil
 		}()
+	}
+
+	pasteTrigger := gtk.NewKeyvalTrigger(gdk.KEY_v, gdk.ControlMask)
+	pasteShortcut := gtk.NewShortcut(pasteTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
+		paste(false)
 		return true
 	}))
 	controller.AddShortcut(pasteShortcut)
 
+	pasteLinkTrigger := gtk.NewKeyvalTrigger(gdk.KEY_v, gdk.ControlMask|gdk.ShiftMask)
+	pasteLinkShortcut := gtk.NewShortcut(pasteLinkTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
+		paste(true)
+		return true
+	}))
+	controller.AddShortcut(pasteLinkShortcut)
+
 	escapeTrigger := gtk.NewKeyvalTrigger(gdk.KEY_Escape, 0)
 	escapeShortcut := gtk.NewShortcut(escapeTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
 		mainBox.ViewerPanel.FileViewer.CleanCopyCutFiles()
@@ -231,22 +603,14 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 	}))
 	controller.AddShortcut(helpShortcut)
 
-	for r := 'A'; r <= 'Z'; r++ {
-		s := string(r)
-		keyval := gdk.KeyvalFromName(s)
-
-		trigger := gtk.NewKeyvalTrigger(
-			keyval,
-			gdk.ShiftMask,
-		)
-
-		letterShortcut := gtk.NewShortcut(trigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
-			mainBox.ViewerPanel.FileViewer.FileViewerList.SetSelectedItemWithLetter(s)
-			mainBox.updatePreviewer()
-			return true
-		}))
-		controller.AddShortcut(letterShortcut)
-	}
+	jumpTrigger := gtk.NewKeyvalTrigger(gdk.KEY_j, gdk.ControlMask)
+	jumpShortcut := gtk.NewShortcut(jumpTrigger, gtk.NewCallbackAction(func(widget gtk.Widgetter, args *glib.Variant) (ok bool) {
+		jumpPopup := jump_popup.NewJumpPopup(mainWindow, mainBox.SpecialPaths.GetRecentManager(), mainBox.Path)
+		jumpPopup.Jumped = mainBox.pathChanged
+		jumpPopup.SetVisible(true)
+		return true
+	}))
+	controller.AddShortcut(jumpShortcut)
 
 	mainWindow.AddController(controller)
 
@@ -257,14 +621,14 @@ func NewMainBox(mainWindow *gtk.Window, headerBar *header.HeaderBar) *MainBox {
 			Path:  selectedItem.Path,
 			Index: selectedIndex,
 		}
-		if selectedItem.IsDir {
-			mainBox.pathChanged(selectedItem.Path)
+		if file_list.CanEnter(selectedItem) {
+			mainBox.pathChanged(file_list.EnterPath(selectedItem))
 		}
 	}
 
 	keyController := gtk.NewEventControllerKey()
 	keyController.ConnectKeyReleased(func(keyval uint, keycode uint, state gdk.ModifierType) {
-		if keyval == gdk.KEY_space {
+		if keyval == gdk.KEY_space && !mainBox.ViewerPanel.FileViewer.FileViewerList.IsRenaming() {
 			mainBox.PreviewerPanel.ShowSpecificPreviewer()
 		}
 	})
@@ -315,6 +679,15 @@ func activate(app *gtk.Application) {
 	mainBox := NewMainBox(&window.Window, headerBar)
 	window.SetTitlebar(headerBar)
 	window.SetChild(mainBox)
+	window.ConnectCloseRequest(func() bool {
+		if mainBox.SpecialPaths != nil {
+			mainBox.SpecialPaths.Flush()
+		}
+		if mainBox.ViewStates != nil {
+			mainBox.ViewStates.Flush()
+		}
+		return false
+	})
 
 	window.SetVisible(true)
 	mainBox.ViewerPanel.FileViewer.FileViewerList.DrawingArea.GrabFocus()
@@ -326,22 +699,47 @@ func (m *MainBox) pathChanged(path string) {
 	}
 	specialPath := m.SpecialPaths.GetPath(path)
 	if specialPath != nil {
-		items := specialPath.GetItems()
-		m.ViewerPanel.FileViewer.FileViewerList.SetItems(items)
 		m.Path = specialPath.GetPath()
+		m.ViewerPanel.FileViewer.Path = m.Path
+		m.ViewerPanel.FileViewer.Refresh(false)
 		m.ViewerPanel.FileViewer.SetFolderName(path)
 	} else {
 		m.Path = path
 		m.ViewerPanel.FileViewer.SetPath(path)
 		m.Search.SetPath(path)
-		m.SpecialPaths.AddRecentPath(path)
+		m.SpecialPaths.AddRecentPath(path, true)
+		m.SpecialPaths.GetAutoTagger().SetFolder(path)
+		m.rescanTags(path)
 	}
 	m.updatePreviewer()
 	m.Pathbar.UpdatePathBar(path)
 	m.SideBar.SetPath(path)
 }
 
+// rescanTags indexes the tags the files in dir got from other programs,
+// redrawing them when there were new ones.
+func (m *MainBox) rescanTags(dir string) {
+	tagManager := m.SpecialPaths.GetTagManager()
+	go func() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		paths := make([]string, len(entries))
+		for i, entry := range entries {
+			paths[i] = filepath.Join(dir, entry.Name())
+		}
+		if tagManager.Rescan(paths) && tagManager.Changed != nil {
+			glib.IdleAdd(tagManager.Changed)
+		}
+	}()
+}
+
 func (m *MainBox) updatePreviewer() {
+	if m.ViewerPanel.FileViewer.DiskUsageMode && m.SpecialPaths.GetPath(m.Path) == nil {
+		m.showDiskUsage()
+		return
+	}
 	if len(m.ViewerPanel.FileViewer.FileViewerList.Items) == 0 {
 		m.PreviewerPanel.Update("")
 		return
@@ -349,3 +747,19 @@ func (m *MainBox) updatePreviewer() {
 	selected := m.ViewerPanel.FileViewer.FileViewerList.Items[m.ViewerPanel.FileViewer.FileViewerList.SelectedIDX]
 	m.PreviewerPanel.Update(selected.Path)
 }
+
+// showDiskUsage charts the current folder, scanning it first when no earlier
+// scan covers it.
+func (m *MainBox) showDiskUsage() {
+	cache := m.SpecialPaths.GetDiskUsage()
+	node := cache.Lookup(m.Path)
+	if node == nil {
+		m.scanDiskUsage(m.Path, m.PreviewerPanel.DiskUsageOptions())
+	}
+	selected := ""
+	fileList := m.ViewerPanel.FileViewer.FileViewerList
+	if len(fileList.Items) > 0 {
+		selected = fileList.Items[fileList.SelectedIDX].Path
+	}
+	m.PreviewerPanel.ShowDiskUsage(m.Path, node, selected)
+}
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/MrSametBurgazoglu/atilgan/tag"
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
@@ -16,6 +17,8 @@ type PathBar struct {
 	previousPath    string
 
 	SetPath func(string)
+	// IsTag tells a tag whose name looks like a query apart from one.
+	IsTag func(name string) bool
 }
 
 type PathBarEntryBox struct {
@@ -70,16 +73,42 @@ func (pb *PathBar) UpdatePathBar(path string) {
 	}
 
 	if strings.Contains(path, "://") {
-		name := strings.Split(path, "://")[0]
-		name = strings.ToUpper(name[:1]) + name[1:]
-		button := gtk.NewToggleButtonWithLabel(name)
-		button.SetActive(true)
-		button.AddCSSClass("selected")
-		button.ConnectToggled(func() {
-			pb.PathBarEntryBox.PathEntry.SetText(pb.currentPath)
-			pb.SetVisibleChildName("pathentry")
-		})
-		pb.PathbarBox.Append(button)
+		scheme, rest, _ := strings.Cut(path, "://")
+		names := []string{strings.ToUpper(scheme[:1]) + scheme[1:]}
+		paths := []string{scheme + "://"}
+		// Hierarchical tags are browsed like folders.
+		if scheme == "tags" && (!tag.IsQuery(rest) || pb.IsTag != nil && pb.IsTag(strings.Trim(rest, "/"))) {
+			levels := strings.Split(strings.Trim(rest, "/"), "/")
+			for i, level := range levels {
+				if level != "" {
+					names = append(names, level)
+					paths = append(paths, "tags://"+strings.Join(levels[:i+1], "/"))
+				}
+			}
+		}
+		for i, name := range names {
+			button := gtk.NewToggleButtonWithLabel(name)
+			last := i == len(names)-1
+			if last {
+				button.SetActive(true)
+				button.AddCSSClass("selected")
+			}
+			levelPath := paths[i]
+			button.ConnectToggled(func() {
+				if !last {
+					pb.SetPath(levelPath)
+					return
+				}
+				pb.PathBarEntryBox.PathEntry.SetText(pb.currentPath)
+				pb.SetVisibleChildName("pathentry")
+			})
+			pb.PathbarBox.Append(button)
+			if !last {
+				separator := gtk.NewLabel(">")
+				separator.AddCSSClass("path-separator")
+				pb.PathbarBox.Append(separator)
+			}
+		}
 		pb.SetVisibleChildName("pathbar")
 		return
 	}
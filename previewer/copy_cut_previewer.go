@@ -1,6 +1,7 @@
 package previewer
 
 import (
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
@@ -10,6 +11,8 @@ type CopyCutPreviewer struct {
 	IsCut          bool
 	Paths          *gtk.Box
 	SizeLabel      *gtk.Label
+	VerifyCheck    *gtk.CheckButton
+	LinkDropDown   *gtk.DropDown
 }
 
 func NewCopyCutPreviewer() *CopyCutPreviewer {
@@ -19,17 +22,33 @@ func NewCopyCutPreviewer() *CopyCutPreviewer {
 	operationLabel := gtk.NewLabel("")
 	paths := gtk.NewBox(gtk.OrientationVertical, 0)
 	sizeLabel := gtk.NewLabel("")
+	verifyCheck := gtk.NewCheckButtonWithLabel("Verify copies")
+	verifyCheck.SetTooltipText("Compare checksums of the copies with the originals")
+
+	linkNames := make([]string, len(fileops.LinkModes))
+	for i, mode := range fileops.LinkModes {
+		linkNames[i] = mode.String()
+	}
+	linkDropDown := gtk.NewDropDownFromStrings(linkNames)
+	linkBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	linkLabel := gtk.NewLabel("Ctrl+Shift+V pastes a")
+	linkBox.Append(linkLabel)
+	linkBox.Append(linkDropDown)
 
 	box.Append(nameLabel)
 	box.Append(operationLabel)
 	box.Append(paths)
 	box.Append(sizeLabel)
+	box.Append(verifyCheck)
+	box.Append(linkBox)
 
 	return &CopyCutPreviewer{
 		Box:            box,
 		OperationLabel: operationLabel,
 		Paths:          paths,
 		SizeLabel:      sizeLabel,
+		VerifyCheck:    verifyCheck,
+		LinkDropDown:   linkDropDown,
 	}
 }
 
@@ -39,6 +58,7 @@ func (cp *CopyCutPreviewer) SetFiles(paths []string) {
 	} else {
 		cp.OperationLabel.SetText("Copying File/Directory")
 	}
+	cp.VerifyCheck.SetVisible(!cp.IsCut)
 	for child := cp.Paths.FirstChild(); child != nil; child = cp.Paths.FirstChild() {
 		cp.Paths.Remove(child)
 	}
@@ -47,3 +67,8 @@ func (cp *CopyCutPreviewer) SetFiles(paths []string) {
 		cp.Paths.Append(pathLabel)
 	}
 }
+
+// LinkMode returns the kind of link Ctrl+Shift+V creates.
+func (cp *CopyCutPreviewer) LinkMode() fileops.LinkMode {
+	return fileops.LinkModes[cp.LinkDropDown.Selected()]
+}
@@ -6,25 +6,20 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/MrSametBurgazoglu/atilgan/file_list"
 	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/sort_popup"
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
 	"github.com/MrSametBurgazoglu/atilgan/special_path"
 	"github.com/MrSametBurgazoglu/atilgan/thumbnail"
 	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/MrSametBurgazoglu/atilgan/view_state"
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
-type SortOrder int
-
-const (
-	SortByName SortOrder = iota
-	SortByTime
-)
-
 type FileType int
 
 const (
@@ -40,26 +35,30 @@ const (
 type DirPreviewer struct {
 	*gtk.Box
 	Path               string
-	SortOrder          SortOrder
+	SortOptions        sorter.SortOptions
 	Filters            []string
 	DefaultFilters     []string
 	FiltersMap         map[string]bool
 	popover            *gtk.Popover
+	sortButton         *gtk.MenuButton
+	sortPopover        *sort_popup.SortPopover
 	changePath         func(string)
 	FileViewerList     *file_list.FileList
 	gridView           *gtk.GridView
+	gridViewButton     *gtk.Button
 	stack              *gtk.Stack
 	store              *gio.ListStore
 	folderIcon         *gtk.Image
 	folderName         *gtk.Label
 	specialPathManager *special_path.SpecialPathManager
+	viewStateManager   *view_state.ViewStateManager
 }
 
-func NewDirPreviewer(path string, changePath func(string), specialPathManager *special_path.SpecialPathManager) *DirPreviewer {
+func NewDirPreviewer(path string, changePath func(string), specialPathManager *special_path.SpecialPathManager, viewStateManager *view_state.ViewStateManager) *DirPreviewer {
 	viewer := &DirPreviewer{
 		Box:                gtk.NewBox(gtk.OrientationVertical, 6),
 		Path:               path,
-		SortOrder:          SortByName,
+		SortOptions:        sorter.DefaultSortOptions(),
 		FiltersMap:         make(map[string]bool),
 		changePath:         changePath,
 		FileViewerList:     file_list.NewFileList(false, nil, nil),
@@ -68,6 +67,7 @@ func NewDirPreviewer(path string, changePath func(string), specialPathManager *s
 		folderIcon:         gtk.NewImageFromIconName("folder-symbolic"),
 		folderName:         gtk.NewLabel(""),
 		specialPathManager: specialPathManager,
+		viewStateManager:   viewStateManager,
 	}
 	//viewer.Box.SetVExpand(true)
 
@@ -85,11 +85,21 @@ func NewDirPreviewer(path string, changePath func(string), specialPathManager *s
 	rightBox := gtk.NewBox(gtk.OrientationHorizontal, 0)
 	headerBox.Append(rightBox)
 
-	sortButton := gtk.NewButtonFromIconName("view-sort-descending-symbolic")
+	viewer.sortPopover = sort_popup.NewSortPopover(func(opts sorter.SortOptions) {
+		viewer.SortOptions = opts
+		viewer.viewStateManager.SetSortOptions(viewer.Path, opts)
+		viewer.sortButton.SetIconName(viewer.sortPopover.IconName())
+		viewer.sortButton.SetTooltipText(opts.String())
+		viewer.Refresh(false)
+	})
+	viewer.sortButton = gtk.NewMenuButton()
+	viewer.sortButton.SetIconName(viewer.sortPopover.IconName())
+	viewer.sortButton.SetTooltipText(viewer.SortOptions.String())
+	viewer.sortButton.SetPopover(viewer.sortPopover)
 
-	gridViewButton := gtk.NewButtonFromIconName("view-grid-symbolic")
-	rightBox.Append(gridViewButton)
-	rightBox.Append(sortButton)
+	viewer.gridViewButton = gtk.NewButtonFromIconName("view-grid-symbolic")
+	rightBox.Append(viewer.gridViewButton)
+	rightBox.Append(viewer.sortButton)
 
 	filterButton := gtk.NewMenuButton()
 	filterButton.SetIconName("preferences-system-symbolic")
@@ -158,24 +168,10 @@ func NewDirPreviewer(path string, changePath func(string), specialPathManager *s
 	viewer.stack.SetVisibleChildName("list")
 	viewer.Box.Append(viewer.stack)
 
-	gridViewButton.ConnectClicked(func() {
-		if viewer.stack.VisibleChildName() == "list" {
-			viewer.gridView.SetVisible(true)
-			viewer.stack.SetVisibleChildName("grid")
-			gridViewButton.SetIconName("view-list-symbolic")
-		} else {
-			viewer.stack.SetVisibleChildName("list")
-			gridViewButton.SetIconName("view-grid-symbolic")
-		}
-	})
-
-	sortButton.ConnectClicked(func() {
-		if viewer.SortOrder == SortByTime {
-			viewer.SortOrder = SortByName
-		} else {
-			viewer.SortOrder = SortByTime
-		}
-		viewer.Refresh(false)
+	viewer.gridViewButton.ConnectClicked(func() {
+		gridMode := viewer.stack.VisibleChildName() == "list"
+		viewer.setGridMode(gridMode)
+		viewer.viewStateManager.SetGridMode(viewer.Path, gridMode)
 	})
 
 	viewer.Refresh(false)
@@ -249,6 +245,7 @@ func (viewer *DirPreviewer) Refresh(newFilter bool) {
 		}
 		sort.Strings(extensions)
 		viewer.Filters = append(viewer.Filters, extensions...)
+		viewer.applyViewState()
 		viewer.UpdateFilterPopover()
 	}
 
@@ -282,55 +279,15 @@ func (viewer *DirPreviewer) Refresh(newFilter bool) {
 		filteredEntries = append(filteredEntries, entry)
 	}
 
-	sort.Slice(filteredEntries, func(i, j int) bool {
-		switch viewer.SortOrder {
-		case SortByTime:
-			infoI, errI := filteredEntries[i].Info()
-			infoJ, errJ := filteredEntries[j].Info()
-			if errI != nil || errJ != nil {
-				return false
-			}
-			return infoI.ModTime().After(infoJ.ModTime())
-		default:
-			return strings.Title(filteredEntries[i].Name()) < strings.Title(filteredEntries[j].Name())
-		}
-	})
-
-	newFiles := make([]*types.ListItem, 0)
-	viewer.store.RemoveAll()
+	newFiles := make([]*types.ListItem, 0, len(filteredEntries))
 	for _, entry := range filteredEntries {
-		fullPath := filepath.Join(viewer.Path, entry.Name())
-		var group string
-		if viewer.SortOrder == SortByTime {
-			info, err := entry.Info()
-			if err != nil {
-				group = "Unknown"
-			} else {
-				group = getGroupForTime(info.ModTime())
-			}
-		} else {
-			name := entry.Name()
-			runes := []rune(strings.Title(name))
-			firstRune := runes[0]
-			group = string(firstRune)
-		}
-		listItem := &types.ListItem{
-			Name:  entry.Name(),
-			Path:  fullPath,
-			Group: group,
-			IsDir: entry.IsDir(),
-		}
-		if listItem.IsDir {
-			listItem.ItemCount = getDirItemCount(fullPath)
-		} else {
-			info, err := entry.Info()
-			if err == nil {
-				listItem.Size = info.Size()
-			}
+		newFiles = append(newFiles, fileops.NewListItem(viewer.Path, entry))
+	}
+	sorter.Sort(newFiles, viewer.SortOptions)
 
-		}
-		newFiles = append(newFiles, listItem)
-		viewer.store.Append(gtk.NewStringObject(entry.Name()).Object)
+	viewer.store.RemoveAll()
+	for _, item := range newFiles {
+		viewer.store.Append(gtk.NewStringObject(item.Name).Object)
 	}
 	viewer.FileViewerList.SetItems(newFiles)
 	viewer.folderIcon.SetFromIconName(fileops.GetIconForFolderSymbolic(viewer.Path))
@@ -348,6 +305,7 @@ func (viewer *DirPreviewer) UpdateFilterPopover() {
 		filterName := filter
 		checkButton.ConnectToggled(func() {
 			viewer.FiltersMap[filterName] = checkButton.Active()
+			viewer.saveFilter(filterName, checkButton.Active())
 			viewer.UpdateFilterPopover()
 			viewer.Refresh(false)
 		})
@@ -362,51 +320,73 @@ func (viewer *DirPreviewer) UpdateFilterPopover() {
 		filterName := filter
 		checkButton.ConnectToggled(func() {
 			viewer.FiltersMap[filterName] = checkButton.Active()
+			viewer.saveFilter(filterName, checkButton.Active())
 			viewer.UpdateFilterPopover()
 			viewer.Refresh(false)
 		})
 		popoverBox.Append(checkButton)
 	}
-}
 
-func isImage(fileName string) bool {
-	fileName = strings.ToLower(fileName)
-	return strings.HasSuffix(fileName, ".png") ||
-		strings.HasSuffix(fileName, ".jpg") ||
-		strings.HasSuffix(fileName, ".jpeg") ||
-		strings.HasSuffix(fileName, ".gif")
-}
+	popoverBox.Append(gtk.NewSeparator(gtk.OrientationHorizontal))
 
-func getGroupForTime(modTime time.Time) string {
-	now := time.Now()
-	duration := now.Sub(modTime)
+	defaultButton := gtk.NewButtonWithLabel("Use as Default")
+	defaultButton.ConnectClicked(func() {
+		viewer.viewStateManager.SetDefault(viewer.Path)
+		viewer.popover.Popdown()
+	})
+	popoverBox.Append(defaultButton)
 
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	if modTime.After(todayStart) {
-		return "Today"
-	}
+	resetButton := gtk.NewButtonWithLabel("Reset Folder View")
+	resetButton.ConnectClicked(func() {
+		viewer.viewStateManager.Reset(viewer.Path)
+		viewer.popover.Popdown()
+		viewer.Refresh(true)
+	})
+	popoverBox.Append(resetButton)
+}
 
-	if duration.Hours() <= 24 {
-		return "Last 24 hours"
+func (viewer *DirPreviewer) applyViewState() {
+	view := viewer.viewStateManager.Get(viewer.Path)
+	for filter, enabled := range view.Filters {
+		if _, ok := viewer.FiltersMap[filter]; ok {
+			viewer.FiltersMap[filter] = enabled
+		}
 	}
-
-	if duration.Hours() <= 24*7 {
-		return "Last Week"
+	if _, ok := viewer.FiltersMap["Hidden"]; ok {
+		viewer.FiltersMap["Hidden"] = view.ShowHidden
 	}
+	viewer.SortOptions = view.SortOptions
+	viewer.sortPopover.SetOptions(view.SortOptions)
+	viewer.sortButton.SetIconName(viewer.sortPopover.IconName())
+	viewer.sortButton.SetTooltipText(view.SortOptions.String())
+	viewer.setGridMode(view.GridMode)
+}
 
-	if duration.Hours() <= 24*30 {
-		return "Last Month"
+func (viewer *DirPreviewer) setGridMode(gridMode bool) {
+	if gridMode {
+		viewer.gridView.SetVisible(true)
+		viewer.stack.SetVisibleChildName("grid")
+		viewer.gridViewButton.SetIconName("view-list-symbolic")
+	} else {
+		viewer.stack.SetVisibleChildName("list")
+		viewer.gridViewButton.SetIconName("view-grid-symbolic")
 	}
-
-	return "Later"
 }
 
-func getDirItemCount(dirPath string) int {
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return 0
+func (viewer *DirPreviewer) saveFilter(filter string, enabled bool) {
+	if filter == "Hidden" {
+		viewer.viewStateManager.SetShowHidden(viewer.Path, enabled)
+	} else {
+		viewer.viewStateManager.SetFilter(viewer.Path, filter, enabled)
 	}
-	return len(entries)
+}
+
+func isImage(fileName string) bool {
+	fileName = strings.ToLower(fileName)
+	return strings.HasSuffix(fileName, ".png") ||
+		strings.HasSuffix(fileName, ".jpg") ||
+		strings.HasSuffix(fileName, ".jpeg") ||
+		strings.HasSuffix(fileName, ".gif")
 }
 
 func getFileType(entry os.DirEntry) FileType {
@@ -0,0 +1,489 @@
+package previewer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/disk_usage"
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+const (
+	sunburstDepth   = 3
+	treemapHeader   = 16
+	minTreemapLabel = 40
+	largestCount    = 8
+)
+
+// region is the part of the chart a node was drawn in, a rectangle for the
+// treemap and a ring segment for the sunburst.
+type region struct {
+	node           *disk_usage.Node
+	x, y, w, h     float64
+	r0, r1, a0, a1 float64
+	ring           bool
+}
+
+func (r region) contains(x, y, cx, cy float64) bool {
+	if !r.ring {
+		return x >= r.x && x < r.x+r.w && y >= r.y && y < r.y+r.h
+	}
+	dx, dy := x-cx, y-cy
+	distance := math.Hypot(dx, dy)
+	if distance < r.r0 || distance >= r.r1 {
+		return false
+	}
+	angle := math.Atan2(dy, dx) + math.Pi/2
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return angle >= r.a0 && angle < r.a1
+}
+
+// DiskUsagePreviewer draws the scanned sizes of a folder as a treemap or a
+// sunburst and lists its largest files and folders. Clicking a folder
+// opens it.
+type DiskUsagePreviewer struct {
+	*gtk.Box
+	PathChanged func(path string)
+	Rescan      func(root string, opts disk_usage.Options)
+
+	node               *disk_usage.Node
+	root               string
+	selected           string
+	scanning           bool
+	sunburst           bool
+	regions            []region
+	hovered            *disk_usage.Node
+	titleLabel         *gtk.Label
+	hoverLabel         *gtk.Label
+	drawingArea        *gtk.DrawingArea
+	oneFileSystemCheck *gtk.CheckButton
+	largestFolders     *gtk.Box
+	largestFiles       *gtk.Box
+}
+
+func NewDiskUsagePreviewer() *DiskUsagePreviewer {
+	dp := &DiskUsagePreviewer{
+		Box:                gtk.NewBox(gtk.OrientationVertical, 6),
+		titleLabel:         gtk.NewLabel(""),
+		hoverLabel:         gtk.NewLabel(""),
+		drawingArea:        gtk.NewDrawingArea(),
+		oneFileSystemCheck: gtk.NewCheckButtonWithLabel("This filesystem only"),
+		largestFolders:     gtk.NewBox(gtk.OrientationVertical, 0),
+		largestFiles:       gtk.NewBox(gtk.OrientationVertical, 0),
+	}
+	dp.SetMarginTop(6)
+	dp.SetMarginBottom(6)
+	dp.SetMarginStart(6)
+	dp.SetMarginEnd(6)
+
+	toolbar := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	upButton := gtk.NewButtonFromIconName("go-up-symbolic")
+	upButton.SetTooltipText("Parent Folder")
+	upButton.ConnectClicked(func() {
+		if dp.root != "" && dp.PathChanged != nil {
+			dp.PathChanged(filepath.Dir(dp.root))
+		}
+	})
+	toolbar.Append(upButton)
+	dp.titleLabel.SetXAlign(0)
+	dp.titleLabel.SetHExpand(true)
+	dp.titleLabel.SetEllipsize(pango.EllipsizeMiddle)
+	toolbar.Append(dp.titleLabel)
+
+	treemapButton := gtk.NewToggleButtonWithLabel("Treemap")
+	sunburstButton := gtk.NewToggleButtonWithLabel("Sunburst")
+	sunburstButton.SetGroup(treemapButton)
+	treemapButton.SetActive(true)
+	sunburstButton.ConnectToggled(func() {
+		dp.sunburst = sunburstButton.Active()
+		dp.drawingArea.QueueDraw()
+	})
+	toolbar.Append(treemapButton)
+	toolbar.Append(sunburstButton)
+
+	rescanButton := gtk.NewButtonFromIconName("view-refresh-symbolic")
+	rescanButton.SetTooltipText("Scan Again")
+	rescanButton.ConnectClicked(func() {
+		if dp.root != "" && dp.Rescan != nil {
+			dp.Rescan(dp.root, dp.Options())
+		}
+	})
+	toolbar.Append(dp.oneFileSystemCheck)
+	toolbar.Append(rescanButton)
+	dp.Append(toolbar)
+
+	dp.drawingArea.SetVExpand(true)
+	dp.drawingArea.SetHExpand(true)
+	dp.drawingArea.SetSizeRequest(-1, 240)
+	dp.drawingArea.SetDrawFunc(dp.draw)
+	dp.Append(dp.drawingArea)
+
+	click := gtk.NewGestureClick()
+	click.ConnectPressed(func(n int, x, y float64) {
+		dp.clicked(x, y)
+	})
+	dp.drawingArea.AddController(click)
+
+	motion := gtk.NewEventControllerMotion()
+	motion.ConnectMotion(func(x, y float64) {
+		node := dp.nodeAt(x, y)
+		if node == dp.hovered {
+			return
+		}
+		dp.hovered = node
+		if node == nil {
+			dp.hoverLabel.SetText("")
+		} else {
+			dp.hoverLabel.SetText(fmt.Sprintf("%s, %s", node.Name, fileops.GetFileSizeAsString(node.Size)))
+		}
+		dp.drawingArea.QueueDraw()
+	})
+	motion.ConnectLeave(func() {
+		dp.hovered = nil
+		dp.hoverLabel.SetText("")
+		dp.drawingArea.QueueDraw()
+	})
+	dp.drawingArea.AddController(motion)
+
+	dp.hoverLabel.SetXAlign(0)
+	dp.hoverLabel.SetEllipsize(pango.EllipsizeMiddle)
+	dp.Append(dp.hoverLabel)
+
+	lists := gtk.NewBox(gtk.OrientationHorizontal, 12)
+	lists.SetHomogeneous(true)
+	lists.Append(listSection("Largest folders", dp.largestFolders))
+	lists.Append(listSection("Largest files", dp.largestFiles))
+	dp.Append(lists)
+
+	return dp
+}
+
+func listSection(title string, list *gtk.Box) *gtk.Box {
+	box := gtk.NewBox(gtk.OrientationVertical, 2)
+	label := gtk.NewLabel(title)
+	label.SetXAlign(0)
+	label.AddCSSClass("heading")
+	box.Append(label)
+	box.Append(list)
+	return box
+}
+
+// Options returns the scan options chosen in the previewer.
+func (dp *DiskUsagePreviewer) Options() disk_usage.Options {
+	return disk_usage.Options{OneFileSystem: dp.oneFileSystemCheck.Active()}
+}
+
+// SetNode shows the scanned node of root, or a scanning note while node is
+// nil. selected is outlined in the chart.
+func (dp *DiskUsagePreviewer) SetNode(root string, node *disk_usage.Node, selected string) {
+	dp.root = root
+	dp.node = node
+	dp.selected = selected
+	dp.scanning = node == nil
+	dp.hovered = nil
+	dp.hoverLabel.SetText("")
+
+	if node == nil {
+		dp.titleLabel.SetText(filepath.Base(root))
+	} else {
+		dp.titleLabel.SetText(fmt.Sprintf("%s, %s in %d files", node.Name, fileops.GetFileSizeAsString(node.Size), node.Files))
+	}
+
+	var folders, files []*disk_usage.Node
+	if node != nil {
+		folders = node.LargestFolders(largestCount)
+		files = node.LargestFiles(largestCount)
+	}
+	dp.fillList(dp.largestFolders, folders, false)
+	dp.fillList(dp.largestFiles, files, true)
+	dp.drawingArea.QueueDraw()
+}
+
+// SetSelected outlines the node at path.
+func (dp *DiskUsagePreviewer) SetSelected(path string) {
+	dp.selected = path
+	dp.drawingArea.QueueDraw()
+}
+
+func (dp *DiskUsagePreviewer) fillList(list *gtk.Box, nodes []*disk_usage.Node, relative bool) {
+	for child := list.FirstChild(); child != nil; child = list.FirstChild() {
+		list.Remove(child)
+	}
+	for _, node := range nodes {
+		name := node.Name
+		if relative {
+			if rel, err := filepath.Rel(dp.root, node.Path); err == nil {
+				name = rel
+			}
+		}
+		row := gtk.NewBox(gtk.OrientationHorizontal, 6)
+		nameLabel := gtk.NewLabel(name)
+		nameLabel.SetXAlign(0)
+		nameLabel.SetHExpand(true)
+		nameLabel.SetEllipsize(pango.EllipsizeMiddle)
+		row.Append(nameLabel)
+		row.Append(gtk.NewLabel(fileops.GetFileSizeAsString(node.Size)))
+
+		button := gtk.NewButton()
+		button.AddCSSClass("flat")
+		button.SetChild(row)
+		target := node
+		button.ConnectClicked(func() {
+			if dp.PathChanged == nil {
+				return
+			}
+			if target.IsDir {
+				dp.PathChanged(target.Path)
+			} else {
+				dp.PathChanged(filepath.Dir(target.Path))
+			}
+		})
+		list.Append(button)
+	}
+}
+
+func (dp *DiskUsagePreviewer) center() (float64, float64) {
+	return float64(dp.drawingArea.Width()) / 2, float64(dp.drawingArea.Height()) / 2
+}
+
+func (dp *DiskUsagePreviewer) nodeAt(x, y float64) *disk_usage.Node {
+	cx, cy := dp.center()
+	// Nested regions are added after their parents, so the last match is
+	// the innermost one.
+	for i := len(dp.regions) - 1; i >= 0; i-- {
+		if dp.regions[i].contains(x, y, cx, cy) {
+			return dp.regions[i].node
+		}
+	}
+	return nil
+}
+
+func (dp *DiskUsagePreviewer) clicked(x, y float64) {
+	node := dp.nodeAt(x, y)
+	if node == nil || dp.PathChanged == nil {
+		return
+	}
+	if node == dp.node {
+		dp.PathChanged(filepath.Dir(node.Path))
+	} else if node.IsDir {
+		dp.PathChanged(node.Path)
+	}
+}
+
+func (dp *DiskUsagePreviewer) draw(area *gtk.DrawingArea, cr *cairo.Context, width, height int) {
+	dp.regions = dp.regions[:0]
+	w, h := float64(width), float64(height)
+
+	if dp.node == nil || dp.node.Size == 0 {
+		text := "Nothing to show"
+		if dp.scanning {
+			text = "Scanning…"
+		}
+		cr.SetSourceRGBA(0.5, 0.5, 0.5, 1)
+		drawCenteredText(cr, text, w/2, h/2, 14)
+		return
+	}
+
+	if dp.sunburst {
+		radius := math.Min(w, h)/2 - 4
+		ring := radius / (sunburstDepth + 1)
+		cx, cy := w/2, h/2
+		dp.regions = append(dp.regions, region{node: dp.node, ring: true, r0: 0, r1: ring, a0: 0, a1: 2 * math.Pi})
+		cr.SetSourceRGBA(0.5, 0.5, 0.5, 0.3)
+		cr.Arc(cx, cy, ring, 0, 2*math.Pi)
+		cr.Fill()
+		cr.SetSourceRGBA(0.1, 0.1, 0.1, 1)
+		drawCenteredText(cr, fileops.GetFileSizeAsString(dp.node.Size), cx, cy, 11)
+		dp.drawRings(cr, dp.node, cx, cy, ring, 1, 0, 2*math.Pi)
+		return
+	}
+	dp.drawTreemap(cr, dp.node.Children, dp.node.Size, 2, 2, w-4, h-4, 1)
+}
+
+func (dp *DiskUsagePreviewer) drawRings(cr *cairo.Context, node *disk_usage.Node, cx, cy, ring float64, depth int, a0, a1 float64) {
+	if depth > sunburstDepth || node.Size == 0 {
+		return
+	}
+	angle := a0
+	for _, child := range node.Children {
+		span := (a1 - a0) * float64(child.Size) / float64(node.Size)
+		if span < 0.005 {
+			// Children are sorted largest first, so the rest are smaller.
+			break
+		}
+		r0, r1 := ring*float64(depth), ring*float64(depth+1)
+		start, end := angle-math.Pi/2, angle+span-math.Pi/2
+
+		cr.NewPath()
+		cr.Arc(cx, cy, r1, start, end)
+		cr.ArcNegative(cx, cy, r0, end, start)
+		cr.ClosePath()
+		dp.setNodeColor(cr, child, depth)
+		cr.FillPreserve()
+		dp.strokeNode(cr, child)
+
+		dp.regions = append(dp.regions, region{node: child, ring: true, r0: r0, r1: r1, a0: angle, a1: angle + span})
+		if child.IsDir {
+			dp.drawRings(cr, child, cx, cy, ring, depth+1, angle, angle+span)
+		}
+		angle += span
+	}
+}
+
+func (dp *DiskUsagePreviewer) drawTreemap(cr *cairo.Context, nodes []*disk_usage.Node, total int64, x, y, w, h float64, depth int) {
+	if total == 0 || w < 2 || h < 2 {
+		return
+	}
+	for _, tile := range squarify(nodes, total, x, y, w, h) {
+		node := tile.node
+		cr.Rectangle(tile.x, tile.y, tile.w, tile.h)
+		dp.setNodeColor(cr, node, depth)
+		cr.FillPreserve()
+		dp.strokeNode(cr, node)
+		dp.regions = append(dp.regions, region{node: node, x: tile.x, y: tile.y, w: tile.w, h: tile.h})
+
+		if tile.w >= minTreemapLabel && tile.h >= treemapHeader {
+			cr.Save()
+			cr.Rectangle(tile.x, tile.y, tile.w, tile.h)
+			cr.Clip()
+			cr.SetSourceRGBA(0.1, 0.1, 0.1, 1)
+			cr.SelectFontFace("sans-serif", cairo.FontSlantNormal, cairo.FontWeightNormal)
+			cr.SetFontSize(10)
+			cr.MoveTo(tile.x+3, tile.y+11)
+			cr.ShowText(node.Name)
+			cr.Restore()
+		}
+		// The second level is drawn inside the first below its label.
+		if depth == 1 && node.IsDir && tile.w > 24 && tile.h > treemapHeader+8 {
+			dp.drawTreemap(cr, node.Children, node.Size, tile.x+2, tile.y+treemapHeader, tile.w-4, tile.h-treemapHeader-2, depth+1)
+		}
+	}
+}
+
+func (dp *DiskUsagePreviewer) setNodeColor(cr *cairo.Context, node *disk_usage.Node, depth int) {
+	hash := fnv.New32a()
+	hash.Write([]byte(node.Name))
+	hue := float64(hash.Sum32()%360) / 360
+	lightness := 0.55 + 0.08*float64(depth)
+	if !node.IsDir {
+		lightness += 0.1
+	}
+	r, g, b := hslToRGB(hue, 0.45, math.Min(lightness, 0.9))
+	alpha := 1.0
+	if dp.hovered != nil && dp.hovered != node {
+		alpha = 0.85
+	}
+	cr.SetSourceRGBA(r, g, b, alpha)
+}
+
+func (dp *DiskUsagePreviewer) strokeNode(cr *cairo.Context, node *disk_usage.Node) {
+	if node.Path == dp.selected || node == dp.hovered {
+		cr.SetSourceRGBA(0.1, 0.1, 0.1, 1)
+		cr.SetLineWidth(2)
+	} else {
+		cr.SetSourceRGBA(1, 1, 1, 0.8)
+		cr.SetLineWidth(1)
+	}
+	cr.Stroke()
+}
+
+func drawCenteredText(cr *cairo.Context, text string, x, y, size float64) {
+	cr.SelectFontFace("sans-serif", cairo.FontSlantNormal, cairo.FontWeightBold)
+	cr.SetFontSize(size)
+	extents := cr.TextExtents(text)
+	cr.MoveTo(x-(extents.Width/2+extents.XBearing), y-(extents.Height/2+extents.YBearing))
+	cr.ShowText(text)
+}
+
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	channel := func(t float64) float64 {
+		t -= math.Floor(t)
+		switch {
+		case t < 1.0/6:
+			return p + (q-p)*6*t
+		case t < 0.5:
+			return q
+		case t < 2.0/3:
+			return p + (q-p)*(2.0/3-t)*6
+		default:
+			return p
+		}
+	}
+	return channel(h + 1.0/3), channel(h), channel(h - 1.0/3)
+}
+
+type tile struct {
+	node       *disk_usage.Node
+	x, y, w, h float64
+}
+
+// squarify lays nodes out in rows along the shorter side of the rectangle,
+// adding to a row while that keeps its tiles closer to squares.
+func squarify(nodes []*disk_usage.Node, total int64, x, y, w, h float64) []tile {
+	var tiles []tile
+	scale := w * h / float64(total)
+	area := func(node *disk_usage.Node) float64 { return float64(node.Size) * scale }
+
+	worst := func(row []*disk_usage.Node, side float64) float64 {
+		var sum, largest, smallest float64
+		smallest = math.Inf(1)
+		for _, node := range row {
+			a := area(node)
+			sum += a
+			largest = math.Max(largest, a)
+			smallest = math.Min(smallest, a)
+		}
+		if sum == 0 || smallest == 0 {
+			return math.Inf(1)
+		}
+		return math.Max(side*side*largest/(sum*sum), sum*sum/(side*side*smallest))
+	}
+
+	for len(nodes) > 0 && w > 0 && h > 0 {
+		if nodes[0].Size == 0 {
+			break
+		}
+		side := math.Min(w, h)
+		row := nodes[:1]
+		for len(row) < len(nodes) && nodes[len(row)].Size > 0 && worst(nodes[:len(row)+1], side) <= worst(row, side) {
+			row = nodes[:len(row)+1]
+		}
+		nodes = nodes[len(row):]
+
+		var rowArea float64
+		for _, node := range row {
+			rowArea += area(node)
+		}
+		thickness := rowArea / side
+		offset := 0.0
+		for _, node := range row {
+			length := area(node) / thickness
+			if w >= h {
+				tiles = append(tiles, tile{node: node, x: x, y: y + offset, w: thickness, h: length})
+			} else {
+				tiles = append(tiles, tile{node: node, x: x + offset, y: y, w: length, h: thickness})
+			}
+			offset += length
+		}
+		if w >= h {
+			x += thickness
+			w -= thickness
+		} else {
+			y += thickness
+			h -= thickness
+		}
+	}
+	return tiles
+}
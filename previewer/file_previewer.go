@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/MrSametBurgazoglu/atilgan/archive"
 	"github.com/MrSametBurgazoglu/atilgan/fileops"
 	"github.com/MrSametBurgazoglu/atilgan/thumbnail"
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
@@ -21,6 +22,9 @@ type FilePreviewer struct {
 	ModifiedLabel  *gtk.Label
 	PathLabel      *gtk.Label
 	ThumbnailImage *gtk.Image
+	// ChecksumsClicked opens the checksums of the previewed file.
+	ChecksumsClicked func(path string)
+	checksumsButton  *gtk.Button
 }
 
 func NewFilePreviewer() *FilePreviewer {
@@ -98,8 +102,17 @@ func NewFilePreviewer() *FilePreviewer {
 	modifiedBox.Append(modifiedLabel)
 	infoBox.Append(modifiedBox)
 
+	checksumsButton := gtk.NewButtonWithLabel("Checksums…")
+	checksumsButton.SetHAlign(gtk.AlignCenter)
+	checksumsButton.Connect("clicked", func() {
+		if fp.ChecksumsClicked != nil {
+			fp.ChecksumsClicked(fp.Path)
+		}
+	})
+
 	box.Append(thumbnailImage)
 	box.Append(infoBox)
+	box.Append(checksumsButton)
 
 	fp.Box = box
 	fp.NameLabel = nameLabel
@@ -107,12 +120,14 @@ func NewFilePreviewer() *FilePreviewer {
 	fp.PathLabel = pathLabel
 	fp.ModifiedLabel = modifiedLabel
 	fp.ThumbnailImage = thumbnailImage
+	fp.checksumsButton = checksumsButton
 
 	return fp
 }
 
 func (fp *FilePreviewer) SetFile(filePath string, fileInfo os.FileInfo) {
 	fp.Path = filePath
+	fp.checksumsButton.SetVisible(!archive.IsArchivePath(filePath))
 	fp.NameLabel.SetText(fileInfo.Name())
 	path := filePath
 	pathLength := len(path)
@@ -4,8 +4,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/disk_usage"
 	"github.com/MrSametBurgazoglu/atilgan/previewer"
 	"github.com/MrSametBurgazoglu/atilgan/special_path"
+	"github.com/MrSametBurgazoglu/atilgan/view_state"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
@@ -19,14 +22,17 @@ type PreviewPanel struct {
 	mediaPreviewer     *previewer.MediaPreviewer
 	documentPreviewer  *previewer.DocumentPreviewer
 	trashPreviewer     *previewer.TrashPreviewer
+	diskUsagePreviewer *previewer.DiskUsagePreviewer
 	filePath           string
 	specialPathManager *special_path.SpecialPathManager
+	ChecksumsClicked   func(path string)
+	RescanDiskUsage    func(root string, opts disk_usage.Options)
 }
 
-func NewPreviewPanel(path string, changePath func(string), specialPathManager *special_path.SpecialPathManager) *PreviewPanel {
+func NewPreviewPanel(path string, changePath func(string), specialPathManager *special_path.SpecialPathManager, viewStateManager *view_state.ViewStateManager) *PreviewPanel {
 	pp := &PreviewPanel{
 		Stack:              gtk.NewStack(),
-		dirPreviewer:       previewer.NewDirPreviewer(path, changePath, specialPathManager),
+		dirPreviewer:       previewer.NewDirPreviewer(path, changePath, specialPathManager, viewStateManager),
 		filePreviewer:      previewer.NewFilePreviewer(),
 		imagePreviewer:     previewer.NewImagePreviewer(),
 		textPreviewer:      previewer.NewTextPreviewer(),
@@ -34,8 +40,20 @@ func NewPreviewPanel(path string, changePath func(string), specialPathManager *s
 		mediaPreviewer:     previewer.NewMediaPreviewer(),
 		documentPreviewer:  previewer.NewDocumentPreviewer(),
 		trashPreviewer:     previewer.NewTrashPreviewer(func() { changePath("trash://") }),
+		diskUsagePreviewer: previewer.NewDiskUsagePreviewer(),
 		specialPathManager: specialPathManager,
 	}
+	pp.filePreviewer.ChecksumsClicked = func(path string) {
+		if pp.ChecksumsClicked != nil {
+			pp.ChecksumsClicked(path)
+		}
+	}
+	pp.diskUsagePreviewer.PathChanged = changePath
+	pp.diskUsagePreviewer.Rescan = func(root string, opts disk_usage.Options) {
+		if pp.RescanDiskUsage != nil {
+			pp.RescanDiskUsage(root, opts)
+		}
+	}
 	pp.AddCSSClass("preview-panel")
 	pp.SetHExpand(true)
 
@@ -50,6 +68,7 @@ func NewPreviewPanel(path string, changePath func(string), specialPathManager *s
 	pp.AddTitled(pp.mediaPreviewer, "mediapreviewer", "Media Previewer")
 	pp.AddTitled(pp.documentPreviewer, "documentpreviewer", "Document Previewer")
 	pp.AddTitled(pp.trashPreviewer, "trashpreviewer", "Trash Previewer")
+	pp.AddTitled(pp.diskUsagePreviewer, "diskusagepreviewer", "Disk Usage Previewer")
 
 	pp.SetVExpand(true)
 	return pp
@@ -75,6 +94,21 @@ func (pp *PreviewPanel) Update(filePath string) {
 		pp.SetVisibleChildName("dirviewer")
 		return
 	}
+	if archive.IsArchivePath(filePath) {
+		entry, err := archive.Lookup(filePath)
+		if err != nil {
+			pp.SetVisibleChildName("emptypreviewer")
+			return
+		}
+		if entry.IsDir {
+			pp.dirPreviewer.SetPath(filePath)
+			pp.SetVisibleChildName("dirviewer")
+		} else {
+			pp.filePreviewer.SetFile(filePath, entry.FileInfo())
+			pp.SetVisibleChildName("filepreviewer")
+		}
+		return
+	}
 
 	info, err := os.Stat(filePath)
 	if err == nil {
@@ -84,16 +118,41 @@ func (pp *PreviewPanel) Update(filePath string) {
 		} else {
 			pp.filePreviewer.SetFile(filePath, info)
 			pp.SetVisibleChildName("filepreviewer")
-			pp.specialPathManager.AddRecentPath(filePath)
+			pp.specialPathManager.AddRecentPath(filePath, false)
 		}
 	}
 }
 
+// ShowDiskUsage shows the scanned sizes of root with selected outlined. A nil
+// node means root is still being scanned.
+func (pp *PreviewPanel) ShowDiskUsage(root string, node *disk_usage.Node, selected string) {
+	pp.mediaPreviewer.Close()
+	pp.documentPreviewer.Close()
+	pp.filePath = ""
+	pp.diskUsagePreviewer.SetNode(root, node, selected)
+	pp.SetVisibleChildName("diskusagepreviewer")
+}
+
+// DiskUsageOptions returns the scan options chosen in the disk usage view.
+func (pp *PreviewPanel) DiskUsageOptions() disk_usage.Options {
+	return pp.diskUsagePreviewer.Options()
+}
+
 func (pp *PreviewPanel) ShowSpecificPreviewer() {
 	if pp.filePath == "" {
 		return
 	}
-	info, err := os.Stat(pp.filePath)
+	filePath := pp.filePath
+	if archive.IsArchivePath(filePath) {
+		// Files inside archives are previewed from an extracted copy.
+		localPath, err := archive.ExtractToCache(filePath)
+		if err != nil {
+			println("couldn't extract file:", err.Error())
+			return
+		}
+		filePath = localPath
+	}
+	info, err := os.Stat(filePath)
 	if err != nil {
 		return
 	}
@@ -101,25 +160,27 @@ func (pp *PreviewPanel) ShowSpecificPreviewer() {
 		return
 	}
 
-	pp.specialPathManager.AddRecentPath(pp.filePath)
+	if filePath == pp.filePath {
+		pp.specialPathManager.AddRecentPath(filePath, false)
+	}
 
 	if isImage(info.Name()) {
-		pp.imagePreviewer.SetImage(pp.filePath, info)
+		pp.imagePreviewer.SetImage(filePath, info)
 		pp.SetVisibleChildName("imagepreviewer")
 	} else if isText(info.Name()) {
-		pp.textPreviewer.SetText(pp.filePath, info)
+		pp.textPreviewer.SetText(filePath, info)
 		pp.SetVisibleChildName("textpreviewer")
 	} else if isCode(info.Name()) {
-		pp.codePreviewer.SetText(pp.filePath, info)
+		pp.codePreviewer.SetText(filePath, info)
 		pp.SetVisibleChildName("codepreviewer")
 	} else if isMedia(info.Name()) {
-		pp.mediaPreviewer.SetMedia(pp.filePath, info)
+		pp.mediaPreviewer.SetMedia(filePath, info)
 		pp.SetVisibleChildName("mediapreviewer")
 	} else if isDocument(info.Name()) {
-		pp.documentPreviewer.SetDocument(pp.filePath, info)
+		pp.documentPreviewer.SetDocument(filePath, info)
 		pp.SetVisibleChildName("documentpreviewer")
 	} else {
-		pp.filePreviewer.SetFile(pp.filePath, info)
+		pp.filePreviewer.SetFile(filePath, info)
 		pp.SetVisibleChildName("filepreviewer")
 	}
 }
@@ -0,0 +1,160 @@
+package properties
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/MrSametBurgazoglu/atilgan/disk_usage"
+)
+
+// PermissionBits are the mode bits the dialog edits, rwx for owner, group
+// and others plus setuid, setgid and sticky.
+const PermissionBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// Octal formats the permission bits of mode like chmod takes them.
+func Octal(mode os.FileMode) string {
+	special := 0
+	if mode&os.ModeSetuid != 0 {
+		special |= 4
+	}
+	if mode&os.ModeSetgid != 0 {
+		special |= 2
+	}
+	if mode&os.ModeSticky != 0 {
+		special |= 1
+	}
+	return fmt.Sprintf("%d%03o", special, mode&os.ModePerm)
+}
+
+// Symbolic formats the permission bits of mode like ls does, rwsr-xr-x.
+func Symbolic(mode os.FileMode) string {
+	text := []byte("rwxrwxrwx")
+	for i := range text {
+		if mode&(0400>>i) == 0 {
+			text[i] = '-'
+		}
+	}
+	special := func(i int, set bool, letter byte) {
+		if !set {
+			return
+		}
+		if text[i] == 'x' {
+			text[i] = letter
+		} else {
+			text[i] = letter - 'a' + 'A'
+		}
+	}
+	special(2, mode&os.ModeSetuid != 0, 's')
+	special(5, mode&os.ModeSetgid != 0, 's')
+	special(8, mode&os.ModeSticky != 0, 't')
+	return string(text)
+}
+
+// Change is an edit made in the dialog. Nil modes and negative ids are left
+// as they are.
+type Change struct {
+	Mode *os.FileMode
+	UID  int
+	GID  int
+	// Recursive applies the owner and group to everything below the
+	// folder too, and FileMode and DirMode to the files and folders there.
+	Recursive bool
+	FileMode  *os.FileMode
+	DirMode   *os.FileMode
+}
+
+// Apply makes change to path. progress gets the fraction of the items
+// that are done.
+func Apply(ctx context.Context, path string, change Change, progress func(float64)) []error {
+	var errs []error
+	if err := apply(path, change.Mode, change.UID, change.GID); err != nil {
+		errs = append(errs, err)
+	}
+	if !change.Recursive {
+		return errs
+	}
+
+	var paths []string
+	filepath.WalkDir(path, func(walked string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if walked != path {
+			paths = append(paths, walked)
+		}
+		return ctx.Err()
+	})
+	for i, walked := range paths {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		info, err := os.Lstat(walked)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mode := change.FileMode
+		if info.IsDir() {
+			mode = change.DirMode
+		}
+		if err := apply(walked, mode, change.UID, change.GID); err != nil {
+			errs = append(errs, err)
+		}
+		if progress != nil {
+			progress(float64(i+1) / float64(len(paths)))
+		}
+	}
+	return errs
+}
+
+func apply(path string, mode *os.FileMode, uid, gid int) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	// Owners that already match are skipped, chown needs privileges even
+	// when it changes nothing on some filesystems.
+	stat, _ := info.Sys().(*syscall.Stat_t)
+	if (uid >= 0 || gid >= 0) && (stat == nil || (uid >= 0 && uint32(uid) != stat.Uid) || (gid >= 0 && uint32(gid) != stat.Gid)) {
+		if err := os.Lchown(path, uid, gid); err != nil {
+			return err
+		}
+		// chown clears the setuid and setgid bits.
+		if info, err = os.Lstat(path); err != nil {
+			return err
+		}
+	}
+	// The mode of a symbolic link can't be changed, chmod would change its
+	// target instead.
+	if mode != nil && info.Mode()&os.ModeSymlink == 0 && info.Mode()&PermissionBits != *mode {
+		if err := os.Chmod(path, *mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contents measures a folder, returning the size of everything below it
+// and how many files and folders it holds.
+func Contents(ctx context.Context, path string) (size int64, files, folders int, err error) {
+	node, err := disk_usage.Scan(ctx, path, disk_usage.Options{}, nil)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var count func(node *disk_usage.Node)
+	count = func(node *disk_usage.Node) {
+		for _, child := range node.Children {
+			if child.IsDir {
+				folders++
+				count(child)
+			}
+		}
+	}
+	count(node)
+	return node.Size, node.Files, folders, nil
+}
@@ -0,0 +1,181 @@
+package properties
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/columns"
+	"golang.org/x/sys/unix"
+)
+
+// Info is everything the properties dialog shows about a file that can be
+// read without walking into it.
+type Info struct {
+	Path       string
+	Name       string
+	MimeType   string
+	Size       int64
+	Mode       os.FileMode
+	IsDir      bool
+	Accessed   time.Time
+	Modified   time.Time
+	Changed    time.Time
+	Born       time.Time // zero when the filesystem doesn't record it
+	Inode      uint64
+	Links      uint32
+	UID        int
+	GID        int
+	Owner      string
+	Group      string
+	LinkTarget string // empty unless the file is a symbolic link
+	Filesystem string
+	Device     string
+	MountPoint string
+}
+
+// Read gathers the properties of path without following a symbolic link
+// at path itself.
+func Read(path string) (*Info, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	var stat unix.Statx_t
+	mask := unix.STATX_BASIC_STATS | unix.STATX_BTIME
+	if err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, mask, &stat); err != nil {
+		return nil, err
+	}
+
+	props := &Info{
+		Path:     path,
+		Name:     filepath.Base(path),
+		MimeType: columns.DetectMimeType(path, info),
+		Size:     info.Size(),
+		Mode:     info.Mode(),
+		IsDir:    info.IsDir(),
+		Accessed: statxTime(stat.Atime),
+		Modified: statxTime(stat.Mtime),
+		Changed:  statxTime(stat.Ctime),
+		Inode:    stat.Ino,
+		Links:    stat.Nlink,
+		UID:      int(stat.Uid),
+		GID:      int(stat.Gid),
+		Owner:    UserName(int(stat.Uid)),
+		Group:    GroupName(int(stat.Gid)),
+	}
+	if stat.Mask&unix.STATX_BTIME != 0 {
+		props.Born = statxTime(stat.Btime)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		props.LinkTarget, _ = os.Readlink(path)
+	}
+	props.Filesystem, props.Device, props.MountPoint = findMount(path, stat.Dev_major, stat.Dev_minor)
+	return props, nil
+}
+
+func statxTime(t unix.StatxTimestamp) time.Time {
+	return time.Unix(t.Sec, int64(t.Nsec))
+}
+
+// UserName returns the login name of uid, or the number when it has none.
+func UserName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
+
+// GroupName returns the name of gid, or the number when it has none.
+func GroupName(gid int) string {
+	if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+		return g.Name
+	}
+	return strconv.Itoa(gid)
+}
+
+// LookupUser turns a login name or a number into a uid.
+func LookupUser(name string) (int, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// LookupGroup turns a group name or a number into a gid.
+func LookupGroup(name string) (int, error) {
+	if gid, err := strconv.Atoi(name); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// findMount looks up the mount of the device major:minor in mountinfo.
+// When a device is mounted more than once the mount point that contains
+// path wins.
+func findMount(path string, major, minor uint32) (filesystem, device, mountPoint string) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", "", ""
+	}
+	defer file.Close()
+
+	if resolved, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(resolved, filepath.Base(path))
+	}
+	id := fmt.Sprintf("%d:%d", major, minor)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw
+		before, after, ok := strings.Cut(scanner.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields, tail := strings.Fields(before), strings.Fields(after)
+		if len(fields) < 5 || len(tail) < 2 || fields[2] != id {
+			continue
+		}
+		point := unescapeMount(fields[4])
+		if mountPoint == "" || (isWithin(path, point) && (!isWithin(path, mountPoint) || len(point) > len(mountPoint))) {
+			filesystem, device, mountPoint = tail[0], unescapeMount(tail[1]), point
+		}
+	}
+	return filesystem, device, mountPoint
+}
+
+func isWithin(path, root string) bool {
+	return root == "/" || path == root || strings.HasPrefix(path, root+"/")
+}
+
+// unescapeMount decodes the octal escapes mountinfo uses for spaces and
+// other special characters.
+func unescapeMount(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+3 < len(text) {
+			if value, err := strconv.ParseUint(text[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
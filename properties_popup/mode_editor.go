@@ -0,0 +1,95 @@
+package properties_popup
+
+import (
+	"os"
+
+	"github.com/MrSametBurgazoglu/atilgan/properties"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// modeEditor edits permission bits as a grid of read, write and execute
+// checks for the owner, group and others with the special bits below.
+type modeEditor struct {
+	*gtk.Grid
+	checks  [9]*gtk.CheckButton
+	special [3]*gtk.CheckButton
+	octal   *gtk.Label
+}
+
+var specialBits = [3]os.FileMode{os.ModeSetuid, os.ModeSetgid, os.ModeSticky}
+
+func newModeEditor(mode os.FileMode, isDir bool) *modeEditor {
+	me := &modeEditor{Grid: gtk.NewGrid(), octal: gtk.NewLabel("")}
+	me.SetRowSpacing(6)
+	me.SetColumnSpacing(12)
+
+	access := []string{"Read", "Write", "Execute"}
+	if isDir {
+		access[2] = "Enter"
+	}
+	for column, name := range access {
+		me.Attach(gtk.NewLabel(name), column+1, 0, 1, 1)
+	}
+	for row, class := range []string{"Owner", "Group", "Others"} {
+		label := gtk.NewLabel(class)
+		label.SetXAlign(0)
+		me.Attach(label, 0, row+1, 1, 1)
+		for column := range access {
+			check := gtk.NewCheckButton()
+			check.SetHAlign(gtk.AlignCenter)
+			check.ConnectToggled(me.updateOctal)
+			me.checks[row*3+column] = check
+			me.Attach(check, column+1, row+1, 1, 1)
+		}
+	}
+
+	stickyName := "Sticky"
+	if isDir {
+		stickyName = "Sticky, only owners delete"
+	}
+	for i, name := range []string{"Set user ID", "Set group ID", stickyName} {
+		me.special[i] = gtk.NewCheckButtonWithLabel(name)
+		me.special[i].ConnectToggled(me.updateOctal)
+	}
+	specialBox := gtk.NewBox(gtk.OrientationHorizontal, 12)
+	for _, check := range me.special {
+		specialBox.Append(check)
+	}
+	me.Attach(specialBox, 0, 4, 4, 1)
+
+	me.octal.SetXAlign(0)
+	me.octal.AddCSSClass("dim-label")
+	me.Attach(me.octal, 0, 5, 4, 1)
+
+	me.SetMode(mode)
+	return me
+}
+
+func (me *modeEditor) SetMode(mode os.FileMode) {
+	for i, check := range me.checks {
+		check.SetActive(mode&(0400>>i) != 0)
+	}
+	for i, check := range me.special {
+		check.SetActive(mode&specialBits[i] != 0)
+	}
+	me.updateOctal()
+}
+
+func (me *modeEditor) Mode() os.FileMode {
+	var mode os.FileMode
+	for i, check := range me.checks {
+		if check.Active() {
+			mode |= 0400 >> i
+		}
+	}
+	for i, check := range me.special {
+		if check.Active() {
+			mode |= specialBits[i]
+		}
+	}
+	return mode
+}
+
+func (me *modeEditor) updateOctal() {
+	me.octal.SetText(properties.Octal(me.Mode()) + "  " + properties.Symbolic(me.Mode()))
+}
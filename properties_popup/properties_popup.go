@@ -0,0 +1,281 @@
+package properties_popup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/properties"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+// PropertiesWindow shows the details of a file and edits its permissions
+// and ownership.
+type PropertiesWindow struct {
+	*gtk.Window
+	Info *properties.Info
+	// Applied is called with the edits once they are checked.
+	Applied func(change properties.Change)
+
+	general        *gtk.Grid
+	generalRows    int
+	sizeLabel      *gtk.Label
+	modeEditor     *modeEditor
+	ownerEntry     *gtk.Entry
+	groupEntry     *gtk.Entry
+	recursiveCheck *gtk.CheckButton
+	filesCheck     *gtk.CheckButton
+	dirsCheck      *gtk.CheckButton
+	ownersCheck    *gtk.CheckButton
+	filesEditor    *modeEditor
+	dirsEditor     *modeEditor
+	statusLabel    *gtk.Label
+	cancel         context.CancelFunc
+}
+
+func NewPropertiesWindow(path string) (*PropertiesWindow, error) {
+	info, err := properties.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	pw := &PropertiesWindow{
+		Window:      gtk.NewWindow(),
+		Info:        info,
+		general:     gtk.NewGrid(),
+		sizeLabel:   gtk.NewLabel(fileops.GetFileSizeAsString(info.Size)),
+		modeEditor:  newModeEditor(info.Mode&properties.PermissionBits, info.IsDir),
+		ownerEntry:  gtk.NewEntry(),
+		groupEntry:  gtk.NewEntry(),
+		statusLabel: gtk.NewLabel(""),
+	}
+	pw.SetTitle(info.Name + " Properties")
+	pw.SetDefaultSize(520, -1)
+	pw.SetModal(true)
+	pw.ConnectCloseRequest(func() bool {
+		if pw.cancel != nil {
+			pw.cancel()
+		}
+		return false
+	})
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	pw.SetChild(box)
+
+	notebook := gtk.NewNotebook()
+	notebook.AppendPage(pw.generalPage(), gtk.NewLabel("General"))
+	notebook.AppendPage(pw.permissionsPage(), gtk.NewLabel("Permissions"))
+	box.Append(notebook)
+
+	pw.statusLabel.SetXAlign(0)
+	pw.statusLabel.SetWrap(true)
+	pw.statusLabel.AddCSSClass("error")
+	box.Append(pw.statusLabel)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	closeButton := gtk.NewButtonWithLabel("Close")
+	closeButton.ConnectClicked(pw.Close)
+	applyButton := gtk.NewButtonWithLabel("Apply")
+	applyButton.AddCSSClass("suggested-action")
+	applyButton.ConnectClicked(pw.apply)
+	buttonBox.Append(closeButton)
+	buttonBox.Append(applyButton)
+	box.Append(buttonBox)
+
+	if info.IsDir {
+		pw.measure()
+	}
+	return pw, nil
+}
+
+func (pw *PropertiesWindow) addRow(name string, value *gtk.Label) {
+	label := gtk.NewLabel(name)
+	label.SetXAlign(1)
+	label.AddCSSClass("dim-label")
+	value.SetXAlign(0)
+	value.SetSelectable(true)
+	value.SetEllipsize(pango.EllipsizeMiddle)
+	value.SetHExpand(true)
+	pw.general.Attach(label, 0, pw.generalRows, 1, 1)
+	pw.general.Attach(value, 1, pw.generalRows, 1, 1)
+	pw.generalRows++
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "Unknown"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func (pw *PropertiesWindow) generalPage() *gtk.Grid {
+	info := pw.Info
+	pw.general.SetRowSpacing(6)
+	pw.general.SetColumnSpacing(12)
+	pw.general.SetMarginTop(12)
+	pw.general.SetMarginBottom(12)
+	pw.general.SetMarginStart(12)
+	pw.general.SetMarginEnd(12)
+
+	pw.addRow("Name", gtk.NewLabel(info.Name))
+	pw.addRow("Type", gtk.NewLabel(info.MimeType))
+	pw.addRow("Size", pw.sizeLabel)
+	if info.LinkTarget != "" {
+		pw.addRow("Link target", gtk.NewLabel(info.LinkTarget))
+	}
+	pw.addRow("Location", gtk.NewLabel(filepath.Dir(info.Path)))
+	pw.addRow("Accessed", gtk.NewLabel(formatTime(info.Accessed)))
+	pw.addRow("Modified", gtk.NewLabel(formatTime(info.Modified)))
+	pw.addRow("Changed", gtk.NewLabel(formatTime(info.Changed)))
+	pw.addRow("Created", gtk.NewLabel(formatTime(info.Born)))
+	pw.addRow("Inode", gtk.NewLabel(strconv.FormatUint(info.Inode, 10)))
+	pw.addRow("Hard links", gtk.NewLabel(strconv.FormatUint(uint64(info.Links), 10)))
+	if info.Filesystem != "" {
+		pw.addRow("Filesystem", gtk.NewLabel(fmt.Sprintf("%s on %s", info.Filesystem, info.Device)))
+		pw.addRow("Mount point", gtk.NewLabel(info.MountPoint))
+	}
+	return pw.general
+}
+
+func (pw *PropertiesWindow) permissionsPage() *gtk.Box {
+	info := pw.Info
+	box := gtk.NewBox(gtk.OrientationVertical, 12)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+
+	if info.LinkTarget != "" {
+		note := gtk.NewLabel("The permissions of a symbolic link can't be changed, only its owner and group.")
+		note.SetWrap(true)
+		note.SetXAlign(0)
+		box.Append(note)
+		pw.modeEditor.SetSensitive(false)
+	}
+	box.Append(pw.modeEditor)
+
+	owners := gtk.NewGrid()
+	owners.SetRowSpacing(6)
+	owners.SetColumnSpacing(12)
+	pw.ownerEntry.SetText(info.Owner)
+	pw.ownerEntry.SetHExpand(true)
+	pw.groupEntry.SetText(info.Group)
+	pw.groupEntry.SetHExpand(true)
+	ownerLabel := gtk.NewLabel("Owner")
+	ownerLabel.SetXAlign(0)
+	groupLabel := gtk.NewLabel("Group")
+	groupLabel.SetXAlign(0)
+	owners.Attach(ownerLabel, 0, 0, 1, 1)
+	owners.Attach(pw.ownerEntry, 1, 0, 1, 1)
+	owners.Attach(groupLabel, 0, 1, 1, 1)
+	owners.Attach(pw.groupEntry, 1, 1, 1, 1)
+	box.Append(owners)
+
+	if !info.IsDir {
+		return box
+	}
+
+	pw.recursiveCheck = gtk.NewCheckButtonWithLabel("Apply to enclosed items")
+	box.Append(pw.recursiveCheck)
+
+	enclosed := gtk.NewBox(gtk.OrientationVertical, 6)
+	enclosed.SetMarginStart(24)
+	pw.filesCheck = gtk.NewCheckButtonWithLabel("Change files to")
+	pw.filesEditor = newModeEditor(0644, false)
+	pw.dirsCheck = gtk.NewCheckButtonWithLabel("Change folders to")
+	pw.dirsEditor = newModeEditor(info.Mode&properties.PermissionBits, true)
+	enclosed.Append(pw.filesCheck)
+	enclosed.Append(pw.filesEditor)
+	enclosed.Append(pw.dirsCheck)
+	enclosed.Append(pw.dirsEditor)
+	pw.ownersCheck = gtk.NewCheckButtonWithLabel("Give them the owner and group above")
+	pw.ownersCheck.SetTooltipText("Also when they weren't changed, so the enclosed items match the folder")
+	enclosed.Append(pw.ownersCheck)
+
+	revealer := gtk.NewRevealer()
+	revealer.SetChild(enclosed)
+	box.Append(revealer)
+
+	update := func() {
+		revealer.SetRevealChild(pw.recursiveCheck.Active())
+		pw.filesEditor.SetSensitive(pw.filesCheck.Active())
+		pw.dirsEditor.SetSensitive(pw.dirsCheck.Active())
+	}
+	pw.recursiveCheck.ConnectToggled(update)
+	pw.filesCheck.ConnectToggled(update)
+	pw.dirsCheck.ConnectToggled(update)
+	update()
+	return box
+}
+
+// measure fills in the size of a folder once everything below it is read.
+func (pw *PropertiesWindow) measure() {
+	ctx, cancel := context.WithCancel(context.Background())
+	pw.cancel = cancel
+	pw.sizeLabel.SetText("Calculating…")
+	go func() {
+		size, files, folders, err := properties.Contents(ctx, pw.Info.Path)
+		glib.IdleAdd(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				pw.sizeLabel.SetText(err.Error())
+				return
+			}
+			pw.sizeLabel.SetText(fmt.Sprintf("%s, %d files and %d folders", fileops.GetFileSizeAsString(size), files, folders))
+		})
+	}()
+}
+
+func (pw *PropertiesWindow) apply() {
+	info := pw.Info
+	recursive := pw.recursiveCheck != nil && pw.recursiveCheck.Active()
+	change := properties.Change{UID: -1, GID: -1, Recursive: recursive}
+
+	// An unchanged owner or group is only applied to the enclosed items
+	// when asked for, to make them match the folder.
+	matchOwners := recursive && pw.ownersCheck.Active()
+	if owner := pw.ownerEntry.Text(); owner != info.Owner || matchOwners {
+		uid, err := properties.LookupUser(owner)
+		if err != nil {
+			pw.statusLabel.SetText(fmt.Sprintf("There is no user called %s", owner))
+			return
+		}
+		change.UID = uid
+	}
+	if group := pw.groupEntry.Text(); group != info.Group || matchOwners {
+		gid, err := properties.LookupGroup(group)
+		if err != nil {
+			pw.statusLabel.SetText(fmt.Sprintf("There is no group called %s", group))
+			return
+		}
+		change.GID = gid
+	}
+	if mode := pw.modeEditor.Mode(); info.LinkTarget == "" && mode != info.Mode&properties.PermissionBits {
+		change.Mode = &mode
+	}
+	if recursive && pw.filesCheck.Active() {
+		mode := pw.filesEditor.Mode()
+		change.FileMode = &mode
+	}
+	if recursive && pw.dirsCheck.Active() {
+		mode := pw.dirsEditor.Mode()
+		change.DirMode = &mode
+	}
+
+	pw.statusLabel.SetText("")
+	if pw.Applied != nil {
+		pw.Applied(change)
+	}
+	pw.Close()
+}
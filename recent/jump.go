@@ -0,0 +1,232 @@
+package recent
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// matchKeywords tells whether keywords are found in path in their order,
+// ignoring case, the last one in the name of the folder itself like zoxide
+// does. "proj api" matches ~/work/projects/api-server.
+func matchKeywords(path string, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	path = strings.ToLower(path)
+	last := strings.ToLower(keywords[len(keywords)-1])
+	nameStart := strings.LastIndexByte(path, filepath.Separator) + 1
+	if strings.ContainsRune(last, filepath.Separator) {
+		nameStart = 0
+	}
+	i := strings.LastIndex(path[nameStart:], last)
+	if i < 0 {
+		return false
+	}
+	rest := path[:nameStart+i]
+	for _, keyword := range keywords[:len(keywords)-1] {
+		keyword = strings.ToLower(keyword)
+		j := strings.Index(rest, keyword)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(keyword):]
+	}
+	return true
+}
+
+// Jump returns the recent folders keywords match, the best ranked first.
+// The current folder and folders that are gone are left out.
+func (rm *RecentManager) Jump(keywords []string, current string, limit int) []Entry {
+	var matches []Entry
+	for _, e := range rm.Entries(true) {
+		if len(matches) == limit {
+			break
+		}
+		if e.Path == current || !matchKeywords(e.Path, keywords) {
+			continue
+		}
+		if info, err := os.Stat(e.Path); err != nil || !info.IsDir() {
+			continue
+		}
+		matches = append(matches, e)
+	}
+	return matches
+}
+
+// JumpTool is another program that ranks the folders visited in a shell.
+type JumpTool string
+
+const (
+	Zoxide   JumpTool = "zoxide"
+	Autojump JumpTool = "autojump"
+	Z        JumpTool = "z"
+)
+
+var JumpTools = []JumpTool{Zoxide, Autojump, Z}
+
+// jumpEntry is a folder in the database of a jump tool, last is zero when
+// the tool doesn't keep it.
+type jumpEntry struct {
+	path  string
+	score float64
+	last  time.Time
+}
+
+// readZoxide asks zoxide for its folders, as its database is in a binary
+// format of its own.
+func readZoxide() ([]jumpEntry, error) {
+	output, err := exec.Command("zoxide", "query", "--list", "--score").Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, errors.New("zoxide isn't installed")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []jumpEntry
+	for _, line := range strings.Split(string(output), "\n") {
+		score, path, ok := strings.Cut(strings.TrimSpace(line), " ")
+		value, err := strconv.ParseFloat(score, 64)
+		if !ok || err != nil {
+			continue
+		}
+		entries = append(entries, jumpEntry{path: strings.TrimSpace(path), score: value})
+	}
+	return entries, nil
+}
+
+// readLines calls parse with each line of the file at path.
+func readLines(path string, parse func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			parse(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readAutojump reads autojump.txt, a weight and a path on each line.
+func readAutojump() ([]jumpEntry, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return nil, err
+	}
+	var entries []jumpEntry
+	err = readLines(filepath.Join(dir, "autojump", "autojump.txt"), func(line string) {
+		weight, path, ok := strings.Cut(line, "\t")
+		value, err := strconv.ParseFloat(weight, 64)
+		if ok && err == nil {
+			entries = append(entries, jumpEntry{path: path, score: value})
+		}
+	})
+	return entries, err
+}
+
+// readZ reads ~/.z, or $_Z_DATA, a path, a rank and a Unix time on each
+// line.
+func readZ() ([]jumpEntry, error) {
+	path := os.Getenv("_Z_DATA")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".z")
+	}
+	var entries []jumpEntry
+	err := readLines(path, func(line string) {
+		// The path may hold | itself, the rank and time can't.
+		fields := strings.Split(line, "|")
+		if len(fields) < 3 {
+			return
+		}
+		rank, err := strconv.ParseFloat(fields[len(fields)-2], 64)
+		if err != nil {
+			return
+		}
+		seconds, _ := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+		entries = append(entries, jumpEntry{
+			path:  strings.Join(fields[:len(fields)-2], "|"),
+			score: rank,
+			last:  time.Unix(seconds, 0),
+		})
+	})
+	return entries, err
+}
+
+// Import adds the folders tool ranked to the recent folders, returning how
+// many there were. Their scores become visits, so importing again doesn't
+// count them twice.
+func (rm *RecentManager) Import(tool JumpTool) (int, error) {
+	var entries []jumpEntry
+	var err error
+	switch tool {
+	case Zoxide:
+		entries, err = readZoxide()
+	case Autojump:
+		entries, err = readAutojump()
+	case Z:
+		entries, err = readZ()
+	default:
+		return 0, errors.New("Unknown jump tool " + string(tool))
+	}
+	if err != nil {
+		return 0, err
+	}
+	// The folders are counted here, as the change is made again on top of
+	// what another instance saved.
+	exclusions := &recentFile{Exclusions: rm.Exclusions()}
+	entries = slices.DeleteFunc(entries, func(e jumpEntry) bool {
+		info, err := os.Stat(e.path)
+		return !filepath.IsAbs(e.path) || err != nil || !info.IsDir() || exclusions.excluded(e.path)
+	})
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	// Folders the tool doesn't date count as visited a while ago.
+	undated := now.Add(-7 * 24 * time.Hour)
+	rm.change(func(file *recentFile) {
+		for _, imported := range entries {
+			if file.excluded(imported.path) {
+				continue
+			}
+			last := imported.last
+			if last.IsZero() || last.After(now) {
+				last = undated
+			}
+			i := slices.IndexFunc(file.Entries, func(e Entry) bool { return e.Path == imported.path })
+			if i < 0 {
+				file.Entries = append(file.Entries, Entry{Path: imported.path, IsDir: true})
+				i = len(file.Entries) - 1
+			}
+			e := &file.Entries[i]
+			e.Visits = max(e.Visits, int(math.Round(imported.score)), 1)
+			if last.After(e.Last) {
+				e.Last = last
+			}
+		}
+		file.age(now)
+	})
+	return len(entries), nil
+}
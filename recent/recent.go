@@ -4,73 +4,398 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/json_store"
+)
+
+const (
+	recentFileVersion = 2
+	// maxEntries is how many files and folders are remembered.
+	maxEntries = 1000
+	// maxVisits is the sum of the visits above which all of them are scaled
+	// down, so that places not visited anymore fade away.
+	maxVisits = 10000
+	// revisitDelay is how long visiting the same place again doesn't count
+	// as another visit, as a file is recorded both when it is selected and
+	// when its preview is shown.
+	revisitDelay = 10 * time.Second
 )
 
+// DefaultExclusions are the patterns of a new history.
+var DefaultExclusions = []string{"/tmp", "/var/tmp"}
+
+// Entry is a visited file or folder.
+type Entry struct {
+	Path   string    `json:"path"`
+	IsDir  bool      `json:"is_dir,omitempty"`
+	Visits int       `json:"visits"`
+	Last   time.Time `json:"last"`
+	// Applications opened the file, the latest first, as the desktop's
+	// recently used files tell.
+	Applications []string `json:"-"`
+}
+
+// Score ranks the entry by frecency, how often and how lately it was
+// visited.
+func (e Entry) Score(now time.Time) float64 {
+	visits := float64(e.Visits)
+	switch age := now.Sub(e.Last); {
+	case age < time.Hour:
+		return visits * 4
+	case age < 24*time.Hour:
+		return visits * 2
+	case age < 7*24*time.Hour:
+		return visits / 2
+	default:
+		return visits / 4
+	}
+}
+
+type recentFile struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+	// Exclusions are glob patterns of places that aren't recorded. A
+	// pattern with a slash is matched against the path and the folders
+	// above it, one without against each of their names.
+	Exclusions []string `json:"exclusions"`
+}
+
+// parseRecentFile reads recent.json, telling whether it was a version 1
+// file. Those were a plain list of paths, newest first.
+func parseRecentFile(data []byte) (*recentFile, bool, error) {
+	file := &recentFile{}
+	if data == nil {
+		return &recentFile{Version: recentFileVersion, Exclusions: slices.Clone(DefaultExclusions)}, false, nil
+	}
+	if err := json.Unmarshal(data, file); err == nil && file.Version >= recentFileVersion {
+		return file, false, nil
+	}
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, false, err
+	}
+	file = &recentFile{Version: recentFileVersion, Exclusions: slices.Clone(DefaultExclusions)}
+	now := time.Now()
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		file.Entries = append(file.Entries, Entry{
+			Path:   path,
+			IsDir:  info.IsDir(),
+			Visits: 1,
+			Last:   now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+	return file, true, nil
+}
+
+func (file *recentFile) excluded(path string) bool {
+	for _, pattern := range file.Exclusions {
+		pattern = expandHome(pattern)
+		byName := !strings.ContainsRune(pattern, filepath.Separator)
+		for p := path; ; p = filepath.Dir(p) {
+			name := p
+			if byName {
+				name = filepath.Base(p)
+			}
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+			if p == filepath.Dir(p) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// age scales the visits down once there are too many and forgets the
+// entries ranked lowest once there are too many of them.
+func (file *recentFile) age(now time.Time) {
+	total := 0
+	for _, e := range file.Entries {
+		total += e.Visits
+	}
+	if total > maxVisits {
+		for i := range file.Entries {
+			file.Entries[i].Visits = file.Entries[i].Visits * 9 / 10
+		}
+		file.Entries = slices.DeleteFunc(file.Entries, func(e Entry) bool { return e.Visits == 0 })
+	}
+	if len(file.Entries) > maxEntries {
+		slices.SortStableFunc(file.Entries, func(a, b Entry) int {
+			return compareScores(b, a, now)
+		})
+		file.Entries = file.Entries[:maxEntries]
+	}
+}
+
+// mergeDesktop adds the files in recently-used.xbel to entries, taking the
+// later of both visits for those in both.
+func (file *recentFile) mergeDesktop(entries []Entry, desktop map[string]desktopEntry) []Entry {
+	indexes := make(map[string]int, len(entries))
+	for i, e := range entries {
+		indexes[e.Path] = i
+	}
+	for path, d := range desktop {
+		if i, ok := indexes[path]; ok {
+			entries[i].Applications = d.applications
+			if d.last.After(entries[i].Last) {
+				entries[i].Last = d.last
+			}
+			continue
+		}
+		if file.excluded(path) {
+			continue
+		}
+		entries = append(entries, Entry{
+			Path:         path,
+			Visits:       max(d.count, 1),
+			Last:         d.last,
+			Applications: d.applications,
+		})
+	}
+	return entries
+}
+
+func compareScores(a, b Entry, now time.Time) int {
+	if sa, sb := a.Score(now), b.Score(now); sa != sb {
+		if sa < sb {
+			return -1
+		}
+		return 1
+	}
+	return a.Last.Compare(b.Last)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
 type RecentManager struct {
-	Paths  []string
-	dbPath string
+	file  *recentFile
+	mu    sync.Mutex
+	store *json_store.Store
+	// pending holds the changes not saved yet. They are replayed on the
+	// recent.json other instances may have written in the meantime.
+	pending []func(file *recentFile)
+	// desktop holds the files desktop applications opened, nil when
+	// recently-used.xbel can't be used.
+	desktop *desktopRecent
+
+	// Reloaded is called, from another goroutine, after another instance
+	// changed recent.json or an application changed recently-used.xbel.
+	Reloaded func()
 }
 
 func NewRecentManager() (*RecentManager, error) {
-	configDir, err := os.UserConfigDir()
+	store, err := json_store.Open("recent.json")
 	if err != nil {
 		return nil, err
 	}
-	dbPath := filepath.Join(configDir, "atilgan", "recent.json")
-
 	rm := &RecentManager{
-		dbPath: dbPath,
+		store: store,
 	}
 
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-			return nil, err
-		}
-		if err := rm.save(); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := rm.load(); err != nil {
-			return nil, err
-		}
+	data, err := store.Read()
+	if err != nil {
+		return nil, err
+	}
+	file, legacy, err := parseRecentFile(data)
+	if err != nil {
+		return nil, err
+	}
+	rm.file = file
+	if data == nil || legacy {
+		rm.flush()
 	}
 
+	store.Changed = rm.reload
+	if err := store.Watch(); err != nil {
+		println("couldn't watch recent.json:", err.Error())
+	}
+	rm.desktop, err = newDesktopRecent(func() {
+		if rm.Reloaded != nil {
+			rm.Reloaded()
+		}
+	})
+	if err != nil {
+		println("couldn't use recently-used.xbel:", err.Error())
+	}
 	return rm, nil
 }
 
-func (rm *RecentManager) load() error {
-	data, err := os.ReadFile(rm.dbPath)
+// change applies op now and has it saved.
+func (rm *RecentManager) change(op func(file *recentFile)) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	op(rm.file)
+	rm.pending = append(rm.pending, op)
+	rm.store.Schedule(rm.flush)
+}
+
+// replay applies the pending changes to the history in data.
+func (rm *RecentManager) replay(data []byte) (*recentFile, error) {
+	file, _, err := parseRecentFile(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return json.Unmarshal(data, &rm.Paths)
+	for _, op := range rm.pending {
+		op(file)
+	}
+	return file, nil
 }
 
-func (rm *RecentManager) save() error {
-	data, err := json.MarshalIndent(rm.Paths, "", "  ")
+func (rm *RecentManager) flush() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	err := rm.store.Update(func(data []byte) ([]byte, error) {
+		file, err := rm.replay(data)
+		if err != nil {
+			println("recent.json can't be read, writing it again:", err.Error())
+			file = rm.file
+		}
+		rm.file = file
+		return json.MarshalIndent(rm.file, "", "  ")
+	})
 	if err != nil {
-		return err
+		println("couldn't save recent paths:", err.Error())
+		return
 	}
-	return os.WriteFile(rm.dbPath, data, 0644)
+	rm.pending = nil
 }
 
-func (rm *RecentManager) AddPath(path string) {
-	for i, p := range rm.Paths {
-		if p == path {
-			rm.Paths = append(rm.Paths[:i], rm.Paths[i+1:]...)
-			break
+// reload takes in the recent.json another instance wrote.
+func (rm *RecentManager) reload() {
+	data, err := rm.store.Read()
+	if err != nil {
+		return
+	}
+	rm.mu.Lock()
+	file, err := rm.replay(data)
+	if err == nil {
+		rm.file = file
+	}
+	rm.mu.Unlock()
+	if err == nil && rm.Reloaded != nil {
+		rm.Reloaded()
+	}
+}
+
+// Flush writes the changes waiting to be saved right away.
+func (rm *RecentManager) Flush() {
+	rm.store.Flush()
+}
+
+// Visit records a visit of path, unless it is excluded.
+func (rm *RecentManager) Visit(path string, isDir bool) {
+	now := time.Now()
+	rm.change(func(file *recentFile) {
+		if file.excluded(path) {
+			return
+		}
+		i := slices.IndexFunc(file.Entries, func(e Entry) bool { return e.Path == path })
+		if i < 0 {
+			file.Entries = append(file.Entries, Entry{Path: path})
+			i = len(file.Entries) - 1
 		}
+		e := &file.Entries[i]
+		if now.Sub(e.Last) > revisitDelay {
+			e.Visits++
+		}
+		e.Last = now
+		e.IsDir = isDir
+		file.age(now)
+	})
+}
+
+// Opened records that the file at path was opened with its default
+// application, also in recently-used.xbel for other applications to list.
+func (rm *RecentManager) Opened(path string) {
+	rm.Visit(path, false)
+	rm.mu.Lock()
+	excluded := rm.file.excluded(path)
+	rm.mu.Unlock()
+	if excluded || rm.desktop == nil {
+		return
 	}
+	if err := rm.desktop.add(path, time.Now()); err != nil {
+		println("couldn't add to recently-used.xbel:", err.Error())
+	}
+}
 
-	rm.Paths = append([]string{path}, rm.Paths...)
+// Remove forgets paths, also in recently-used.xbel.
+func (rm *RecentManager) Remove(paths []string) {
+	rm.change(func(file *recentFile) {
+		file.Entries = slices.DeleteFunc(file.Entries, func(e Entry) bool { return slices.Contains(paths, e.Path) })
+	})
+	rm.removeDesktop(func(path string) bool { return slices.Contains(paths, path) })
+}
+
+// Clear forgets every file and folder, along with the local files in
+// recently-used.xbel.
+func (rm *RecentManager) Clear() {
+	rm.change(func(file *recentFile) {
+		file.Entries = nil
+	})
+	rm.removeDesktop(func(string) bool { return true })
+}
 
-	if len(rm.Paths) > 100 {
-		rm.Paths = rm.Paths[:100]
+func (rm *RecentManager) removeDesktop(remove func(path string) bool) {
+	if rm.desktop == nil {
+		return
 	}
+	if err := rm.desktop.remove(remove); err != nil {
+		println("couldn't remove from recently-used.xbel:", err.Error())
+	}
+}
 
-	rm.save()
+func (rm *RecentManager) Exclusions() []string {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return slices.Clone(rm.file.Exclusions)
 }
 
-func (rm *RecentManager) GetPaths() []string {
-	return rm.Paths
+// SetExclusions replaces the exclusion patterns and forgets the places
+// they exclude.
+func (rm *RecentManager) SetExclusions(patterns []string) {
+	patterns = slices.Clone(patterns)
+	rm.change(func(file *recentFile) {
+		file.Exclusions = patterns
+		file.Entries = slices.DeleteFunc(file.Entries, func(e Entry) bool { return file.excluded(e.Path) })
+	})
+}
+
+// Entries returns the folders, or the files, with the best ranked first.
+// The files include the ones desktop applications opened.
+func (rm *RecentManager) Entries(dirs bool) []Entry {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	var entries []Entry
+	for _, e := range rm.file.Entries {
+		if e.IsDir == dirs {
+			entries = append(entries, e)
+		}
+	}
+	if !dirs && rm.desktop != nil {
+		entries = rm.file.mergeDesktop(entries, rm.desktop.get())
+	}
+	now := time.Now()
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return compareScores(b, a, now)
+	})
+	return entries
 }
@@ -3,45 +3,105 @@ package recent
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/MrSametBurgazoglu/atilgan/types"
 )
 
+// FoldersPath lists the recent folders, recent:// lists the recent files.
+const FoldersPath = "recent://folders"
+
+// maxShown is how many of the best ranked entries are listed.
+const maxShown = 200
+
 type RecentPath struct {
 	path          string
+	folders       bool
 	recentManager *RecentManager
 }
 
-func NewRecentPath(recentManager *RecentManager) *RecentPath {
+func NewRecentPath(path string, recentManager *RecentManager) *RecentPath {
+	folders := path == FoldersPath || path == FoldersPath+"/"
+	if !folders {
+		path = "recent://"
+	}
 	return &RecentPath{
-		path:          "recent://",
+		path:          path,
+		folders:       folders,
 		recentManager: recentManager,
 	}
 }
 
+// Folders tells whether the view lists folders rather than files.
+func (r *RecentPath) Folders() bool {
+	return r.folders
+}
+
+var periods = []string{"Today", "Yesterday", "This Week", "Earlier"}
+
+// period returns the group of a visit at last, counted in calendar days.
+func period(last, now time.Time) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case !last.Before(today):
+		return periods[0]
+	case !last.Before(today.AddDate(0, 0, -1)):
+		return periods[1]
+	case !last.Before(today.AddDate(0, 0, -6)):
+		return periods[2]
+	default:
+		return periods[3]
+	}
+}
+
+// GetItems returns the entries grouped by when they were last visited, the
+// best ranked first within each group.
 func (r *RecentPath) GetItems() []*types.ListItem {
+	now := time.Now()
 	var items []*types.ListItem
-	paths := r.recentManager.GetPaths()
-	for _, path := range paths {
-		info, err := os.Stat(path)
+	entries := r.recentManager.Entries(r.folders)
+	for _, entry := range entries[:min(len(entries), maxShown)] {
+		info, err := os.Stat(entry.Path)
 		if err != nil {
 			continue
 		}
 		listItem := &types.ListItem{
-			Name:  filepath.Base(path),
-			IsDir: info.IsDir(),
-			Path:  path,
+			Name:        filepath.Base(entry.Path),
+			IsDir:       info.IsDir(),
+			Path:        entry.Path,
+			Group:       period(entry.Last, now),
+			ModTime:     info.ModTime(),
+			CreatedTime: info.ModTime(),
+			SpecialInfo: describe(entry),
 		}
 		if listItem.IsDir {
-			listItem.ItemCount = getDirItemCount(path)
+			listItem.ItemCount = getDirItemCount(entry.Path)
 		} else {
 			listItem.Size = info.Size()
 		}
 		items = append(items, listItem)
 	}
+	slices.SortStableFunc(items, func(a, b *types.ListItem) int {
+		return slices.Index(periods, a.Group) - slices.Index(periods, b.Group)
+	})
 	return items
 }
 
+// maxApplications is how many of the applications that opened a file are
+// named.
+const maxApplications = 3
+
+// describe tells where the file of entry is and what opened it.
+func describe(entry Entry) string {
+	info := filepath.Dir(entry.Path)
+	if len(entry.Applications) > 0 {
+		info += " — opened with " + strings.Join(entry.Applications[:min(len(entry.Applications), maxApplications)], ", ")
+	}
+	return info
+}
+
 func (r *RecentPath) GetPath() string {
 	return r.path
 }
@@ -51,7 +111,10 @@ func (r *RecentPath) GetParentPath() string {
 }
 
 func (r *RecentPath) GetName() string {
-	return "Recent"
+	if r.folders {
+		return "Recent Folders"
+	}
+	return "Recent Files"
 }
 
 func getDirItemCount(dirPath string) int {
@@ -0,0 +1,387 @@
+package recent
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/columns"
+	"github.com/MrSametBurgazoglu/atilgan/json_store"
+)
+
+const (
+	// desktopName and desktopExec record the files opened from atilgan,
+	// which hands them to xdg-open.
+	desktopName = "atilgan"
+	desktopExec = "&apos;xdg-open %u&apos;"
+
+	xbelTime   = "2006-01-02T15:04:05.000000Z"
+	xbelHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<xbel version="1.0"
+      xmlns:bookmark="http://www.freedesktop.org/standards/desktop-bookmarks"
+      xmlns:mime="http://www.freedesktop.org/standards/shared-mime-info"
+>
+</xbel>
+`
+)
+
+// desktopEntry is a file in the desktop's recently used files.
+type desktopEntry struct {
+	path string
+	last time.Time
+	// count is how many times applications opened the file.
+	count int
+	// applications opened the file, the latest first.
+	applications []string
+}
+
+type xbelBookmark struct {
+	Href         string            `xml:"href,attr"`
+	Modified     string            `xml:"modified,attr"`
+	Visited      string            `xml:"visited,attr"`
+	Applications []xbelApplication `xml:"info>metadata>applications>application"`
+}
+
+type xbelApplication struct {
+	Name      string `xml:"name,attr"`
+	Modified  string `xml:"modified,attr"`
+	Timestamp string `xml:"timestamp,attr"`
+	Count     int    `xml:"count,attr"`
+}
+
+// parseXBELTime reads the ISO 8601 times of recently-used.xbel, or the Unix
+// timestamps older versions of GLib wrote.
+func parseXBELTime(value string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0)
+	}
+	return time.Time{}
+}
+
+// uriPath returns the local path of a file:// URI, empty for other URIs.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return ""
+	}
+	return u.Path
+}
+
+// fileURI escapes path the way GLib does for its file:// URIs.
+func fileURI(path string) string {
+	var b strings.Builder
+	b.WriteString("file://")
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~!$&'()*+,;=:@/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// readXBEL reads the local files in recently-used.xbel one bookmark at a
+// time, so that a large file isn't held in memory at once.
+func readXBEL(r io.Reader) (map[string]desktopEntry, error) {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	entries := make(map[string]desktopEntry)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != "" || start.Name.Local != "bookmark" {
+			continue
+		}
+		var bookmark xbelBookmark
+		if err := decoder.DecodeElement(&bookmark, &start); err != nil {
+			return nil, err
+		}
+		path := uriPath(bookmark.Href)
+		if path == "" {
+			continue
+		}
+		entry := desktopEntry{path: path, last: parseXBELTime(bookmark.Modified)}
+		if visited := parseXBELTime(bookmark.Visited); visited.After(entry.last) {
+			entry.last = visited
+		}
+		slices.SortStableFunc(bookmark.Applications, func(a, b xbelApplication) int {
+			return applicationTime(b).Compare(applicationTime(a))
+		})
+		for _, application := range bookmark.Applications {
+			entry.count += max(application.Count, 1)
+			if application.Name != "" && !slices.Contains(entry.applications, application.Name) {
+				entry.applications = append(entry.applications, application.Name)
+			}
+			if t := applicationTime(application); t.After(entry.last) {
+				entry.last = t
+			}
+		}
+		entries[path] = entry
+	}
+}
+
+func applicationTime(application xbelApplication) time.Time {
+	if application.Modified != "" {
+		return parseXBELTime(application.Modified)
+	}
+	return parseXBELTime(application.Timestamp)
+}
+
+var (
+	bookmarkPattern    = regexp.MustCompile(`(?s)[ \t]*<bookmark\s[^>]*?(?:/>|>.*?</bookmark>)[ \t]*\n?`)
+	hrefPattern        = regexp.MustCompile(`\bhref="([^"]*)"`)
+	applicationPattern = regexp.MustCompile(`<bookmark:application\s[^>]*?\bname="` + desktopName + `"[^>]*?/>`)
+	countPattern       = regexp.MustCompile(`\bcount="(\d*)"`)
+)
+
+// bookmarkPath returns the local path a bookmark element is about.
+func bookmarkPath(element string) string {
+	start, _, _ := strings.Cut(element, ">")
+	match := hrefPattern.FindStringSubmatch(start)
+	if match == nil {
+		return ""
+	}
+	return uriPath(html.UnescapeString(match[1]))
+}
+
+// setAttribute gives the name attribute of the element starting tag value.
+func setAttribute(tag, name, value string) string {
+	pattern := regexp.MustCompile(`\s` + name + `="[^"]*"`)
+	if pattern.MatchString(tag) {
+		return pattern.ReplaceAllLiteralString(tag, " "+name+`="`+value+`"`)
+	}
+	end := strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/")
+	return end + " " + name + `="` + value + `"` + tag[len(end):]
+}
+
+func applicationElement(stamp string) string {
+	return `<bookmark:application name="` + desktopName + `" exec="` + desktopExec + `" modified="` + stamp + `" count="1"/>`
+}
+
+// touchBookmark records another opening of the file of a bookmark element.
+func touchBookmark(element, stamp string) string {
+	end := strings.IndexByte(element, '>') + 1
+	start := setAttribute(setAttribute(element[:end], "modified", stamp), "visited", stamp)
+	rest := element[end:]
+	if loc := applicationPattern.FindStringIndex(rest); loc != nil {
+		application := setAttribute(rest[loc[0]:loc[1]], "modified", stamp)
+		count := 0
+		if match := countPattern.FindStringSubmatch(application); match != nil {
+			count, _ = strconv.Atoi(match[1])
+		}
+		application = setAttribute(application, "count", strconv.Itoa(count+1))
+		return start + rest[:loc[0]] + application + rest[loc[1]:]
+	}
+	if i := strings.Index(rest, "</bookmark:applications>"); i >= 0 {
+		return start + rest[:i] + "  " + applicationElement(stamp) + "\n        " + rest[i:]
+	}
+	if i := strings.Index(rest, "</metadata>"); i >= 0 {
+		return start + rest[:i] + "  <bookmark:applications>\n          " + applicationElement(stamp) +
+			"\n        </bookmark:applications>\n      " + rest[i:]
+	}
+	return start + rest
+}
+
+func newBookmark(path, mimeType, stamp string) string {
+	var href strings.Builder
+	xml.EscapeText(&href, []byte(fileURI(path)))
+	return `  <bookmark href="` + href.String() + `" added="` + stamp + `" modified="` + stamp + `" visited="` + stamp + `">
+    <info>
+      <metadata owner="http://freedesktop.org">
+        <mime:mime-type type="` + mimeType + `"/>
+        <bookmark:applications>
+          ` + applicationElement(stamp) + `
+        </bookmark:applications>
+      </metadata>
+    </info>
+  </bookmark>
+`
+}
+
+// addBookmark records in recently-used.xbel data, nil when there is none
+// yet, that atilgan opened path. The rest of the file is left as it is.
+func addBookmark(data []byte, path, mimeType string, now time.Time) ([]byte, error) {
+	stamp := now.UTC().Format(xbelTime)
+	text := xbelHeader
+	if data != nil {
+		text = string(data)
+	}
+	for _, loc := range bookmarkPattern.FindAllStringIndex(text, -1) {
+		element := text[loc[0]:loc[1]]
+		if bookmarkPath(element) == path {
+			return []byte(text[:loc[0]] + touchBookmark(element, stamp) + text[loc[1]:]), nil
+		}
+	}
+	end := strings.LastIndex(text, "</xbel>")
+	if end < 0 {
+		return nil, errors.New("no </xbel>")
+	}
+	return []byte(text[:end] + newBookmark(path, mimeType, stamp) + text[end:]), nil
+}
+
+// removeBookmarks drops the bookmarks of the files remove tells from
+// recently-used.xbel data.
+func removeBookmarks(data []byte, remove func(path string) bool) []byte {
+	return bookmarkPattern.ReplaceAllFunc(data, func(element []byte) []byte {
+		if path := bookmarkPath(string(element)); path != "" && remove(path) {
+			return nil
+		}
+		return element
+	})
+}
+
+// desktopRecent is the list of recently used files desktop applications
+// share, in $XDG_DATA_HOME/recently-used.xbel.
+type desktopRecent struct {
+	store *json_store.Store
+
+	mu sync.Mutex
+	// entries is replaced rather than changed, so it can be handed out.
+	entries map[string]desktopEntry
+
+	// loaded is called, from another goroutine, once the file was read.
+	loaded func()
+}
+
+// dataHome returns $XDG_DATA_HOME, ~/.local/share by default.
+func dataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+func newDesktopRecent(loaded func()) (*desktopRecent, error) {
+	dataDir, err := dataHome()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	store, err := json_store.OpenFile(filepath.Join(dataDir, "recently-used.xbel"))
+	if err != nil {
+		return nil, err
+	}
+	d := &desktopRecent{store: store, loaded: loaded}
+	store.Changed = d.load
+	if err := store.Watch(); err != nil {
+		println("couldn't watch recently-used.xbel:", err.Error())
+	}
+	go d.load()
+	return d, nil
+}
+
+func (d *desktopRecent) load() {
+	entries := make(map[string]desktopEntry)
+	file, err := os.Open(d.store.Path())
+	if err == nil {
+		entries, err = readXBEL(file)
+		file.Close()
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		println("couldn't read recently-used.xbel:", err.Error())
+		return
+	}
+	d.mu.Lock()
+	d.entries = entries
+	d.mu.Unlock()
+	if d.loaded != nil {
+		d.loaded()
+	}
+}
+
+func (d *desktopRecent) get() map[string]desktopEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.entries
+}
+
+// add records that path was opened with its default application.
+func (d *desktopRecent) add(path string, now time.Time) error {
+	mimeType := "application/octet-stream"
+	if info, err := os.Stat(path); err == nil {
+		if detected := columns.DetectMimeType(path, info); detected != "" {
+			mimeType = detected
+		}
+	}
+	err := d.store.Update(func(data []byte) ([]byte, error) {
+		return addBookmark(data, path, mimeType, now)
+	})
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entries := maps.Clone(d.entries)
+	if entries == nil {
+		entries = make(map[string]desktopEntry)
+	}
+	entry := entries[path]
+	entry.path = path
+	entry.last = now
+	entry.count++
+	entry.applications = append([]string{desktopName}, slices.DeleteFunc(slices.Clone(entry.applications), func(name string) bool {
+		return name == desktopName
+	})...)
+	entries[path] = entry
+	d.entries = entries
+	return nil
+}
+
+// remove forgets the files remove tells.
+func (d *desktopRecent) remove(remove func(path string) bool) error {
+	d.mu.Lock()
+	found := false
+	for path := range d.entries {
+		found = found || remove(path)
+	}
+	d.mu.Unlock()
+	if !found {
+		return nil
+	}
+	err := d.store.Update(func(data []byte) ([]byte, error) {
+		if data == nil {
+			return []byte(xbelHeader), nil
+		}
+		return removeBookmarks(data, remove), nil
+	})
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entries := maps.Clone(d.entries)
+	maps.DeleteFunc(entries, func(path string, _ desktopEntry) bool { return remove(path) })
+	d.entries = entries
+	return nil
+}
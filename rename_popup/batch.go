@@ -0,0 +1,135 @@
+package rename_popup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+)
+
+type move struct {
+	from string
+	to   string
+}
+
+// Batch is a set of renames applied together. Every item is first moved to
+// a temporary name so swaps and cycles such as a→b, b→a work, and a failure
+// moves every item back. Batch implements undo.Operation.
+type Batch struct {
+	moves []move
+}
+
+// ApplyBulkRename applies the changed renames as one batch. It refuses to
+// start when any rename has an error.
+func ApplyBulkRename(renames []BulkRename) (*Batch, error) {
+	batch := &Batch{}
+	for _, rename := range renames {
+		if rename.Err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(rename.OldPath), rename.Err)
+		}
+		if rename.Changed() {
+			batch.moves = append(batch.moves, move{from: rename.OldPath, to: rename.NewPath})
+		}
+	}
+	if len(batch.moves) == 0 {
+		return nil, errors.New("Nothing to rename")
+	}
+	if err := applyMoves(batch.moves); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// NewRenameBatch records a rename that has already been done so it can be
+// undone.
+func NewRenameBatch(oldPath, newPath string) *Batch {
+	return &Batch{moves: []move{{from: oldPath, to: newPath}}}
+}
+
+func (b *Batch) Name() string {
+	if len(b.moves) == 1 {
+		return fmt.Sprintf("Rename %s", filepath.Base(b.moves[0].from))
+	}
+	return fmt.Sprintf("Rename %d items", len(b.moves))
+}
+
+func (b *Batch) Undo() error {
+	reversed := make([]move, len(b.moves))
+	for i, m := range b.moves {
+		reversed[i] = move{from: m.to, to: m.from}
+	}
+	return applyMoves(reversed)
+}
+
+func (b *Batch) Redo() error {
+	return applyMoves(b.moves)
+}
+
+// NewPaths returns the paths of the renamed items.
+func (b *Batch) NewPaths() []string {
+	paths := make([]string, len(b.moves))
+	for i, m := range b.moves {
+		paths[i] = m.to
+	}
+	return paths
+}
+
+func applyMoves(moves []move) error {
+	sources := make(map[string]bool, len(moves))
+	for _, m := range moves {
+		sources[m.from] = true
+	}
+	for _, m := range moves {
+		if _, err := os.Lstat(m.from); err != nil {
+			return err
+		}
+		if sources[m.to] {
+			continue
+		}
+		if _, err := os.Lstat(m.to); !os.IsNotExist(err) && !sameFile(m.from, m.to) {
+			return fmt.Errorf("\"%s\" already exists", filepath.Base(m.to))
+		}
+	}
+
+	temps := make([]string, 0, len(moves))
+	rollback := func(done int) {
+		for i := done - 1; i >= 0; i-- {
+			if err := os.Rename(moves[i].to, temps[i]); err != nil {
+				println("couldn't roll back rename:", err.Error())
+			}
+		}
+		for i := len(temps) - 1; i >= 0; i-- {
+			if err := os.Rename(temps[i], moves[i].from); err != nil {
+				println("couldn't roll back rename:", err.Error())
+			}
+		}
+	}
+
+	for i, m := range moves {
+		temp := filepath.Join(filepath.Dir(m.from), fmt.Sprintf(".atilgan-rename-%d-%d", os.Getpid(), i))
+		if err := os.Rename(m.from, temp); err != nil {
+			rollback(0)
+			return err
+		}
+		temps = append(temps, temp)
+	}
+	for i, m := range moves {
+		if _, err := os.Lstat(m.to); !os.IsNotExist(err) {
+			rollback(i)
+			return fmt.Errorf("\"%s\" already exists", filepath.Base(m.to))
+		}
+		if err := os.Rename(temps[i], m.to); err != nil {
+			rollback(i)
+			return err
+		}
+	}
+	oldPaths := make([]string, len(moves))
+	newPaths := make([]string, len(moves))
+	for i, m := range moves {
+		oldPaths[i], newPaths[i] = m.from, m.to
+	}
+	fileops.Moved(oldPaths, newPaths)
+	return nil
+}
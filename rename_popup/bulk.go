@@ -0,0 +1,291 @@
+package rename_popup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+type CaseMode int
+
+const (
+	CaseKeep CaseMode = iota
+	CaseLower
+	CaseUpper
+	CaseTitle
+	CaseSentence
+)
+
+var CaseModes = []CaseMode{CaseKeep, CaseLower, CaseUpper, CaseTitle, CaseSentence}
+
+func (c CaseMode) String() string {
+	switch c {
+	case CaseLower:
+		return "lowercase"
+	case CaseUpper:
+		return "UPPERCASE"
+	case CaseTitle:
+		return "Title Case"
+	case CaseSentence:
+		return "Sentence case"
+	default:
+		return "Keep case"
+	}
+}
+
+// DefaultTemplate keeps the edited name and the original extension.
+const DefaultTemplate = "{name}{ext}"
+
+// BulkOptions describes how every name in a batch is changed. The steps are
+// applied to the name without its extension in field order, then the
+// template builds the final name from the tokens {name}, {ext}, {n},
+// {date} and {exif}.
+type BulkOptions struct {
+	Find             string
+	Replace          string
+	UseRegex         bool
+	MatchCase        bool
+	IncludeExtension bool
+
+	Insert        string
+	InsertAt      int
+	InsertFromEnd bool
+
+	RemoveAt      int
+	RemoveCount   int
+	RemoveFromEnd bool
+
+	Case CaseMode
+
+	Template      string
+	NumberStart   int
+	NumberPadding int
+	DateFormat    string
+}
+
+func DefaultBulkOptions() BulkOptions {
+	return BulkOptions{
+		Template:    DefaultTemplate,
+		NumberStart: 1,
+		DateFormat:  "YYYY-MM-DD",
+	}
+}
+
+// BulkRename is the planned rename of one item. Err is set when the new
+// name is invalid or conflicts with another file.
+type BulkRename struct {
+	OldPath string
+	NewPath string
+	Err     error
+}
+
+func (br BulkRename) Changed() bool {
+	return br.OldPath != br.NewPath
+}
+
+// PreviewBulkRename returns the planned renames for items in order. The
+// exifDate func returns the date a photo was taken, or the zero time.
+func PreviewBulkRename(items []*types.ListItem, opts BulkOptions, exifDate func(path string) time.Time) ([]BulkRename, error) {
+	find, err := opts.findPattern()
+	if err != nil {
+		return nil, err
+	}
+
+	renames := make([]BulkRename, len(items))
+	for i, item := range items {
+		name := opts.newName(item, i, find, exifDate)
+		renames[i] = BulkRename{
+			OldPath: item.Path,
+			NewPath: filepath.Join(filepath.Dir(item.Path), name),
+			Err:     CheckName(name),
+		}
+	}
+	checkConflicts(renames)
+	return renames, nil
+}
+
+func (opts BulkOptions) findPattern() (*regexp.Regexp, error) {
+	if opts.Find == "" {
+		return nil, nil
+	}
+	pattern := opts.Find
+	if !opts.UseRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !opts.MatchCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func (opts BulkOptions) newName(item *types.ListItem, index int, find *regexp.Regexp, exifDate func(path string) time.Time) string {
+	name := []rune(item.Name)
+	stemLength := len(name)
+	if !opts.IncludeExtension {
+		stemLength = StemLength(item.Name, item.IsDir)
+	}
+	stem := string(name[:stemLength])
+	ext := string(name[stemLength:])
+
+	if find != nil {
+		if opts.UseRegex {
+			stem = find.ReplaceAllString(stem, opts.Replace)
+		} else {
+			stem = find.ReplaceAllLiteralString(stem, opts.Replace)
+		}
+	}
+	if opts.RemoveCount > 0 {
+		stem = removeRunes(stem, opts.RemoveAt, opts.RemoveCount, opts.RemoveFromEnd)
+	}
+	if opts.Insert != "" {
+		stem = insertRunes(stem, opts.Insert, opts.InsertAt, opts.InsertFromEnd)
+	}
+	stem = changeCase(stem, opts.Case)
+
+	template := opts.Template
+	if template == "" {
+		template = DefaultTemplate
+	}
+	tokens := []string{
+		"{name}", stem,
+		"{ext}", ext,
+		"{n}", fmt.Sprintf("%0*d", opts.NumberPadding, opts.NumberStart+index),
+	}
+	if strings.Contains(template, "{date}") {
+		tokens = append(tokens, "{date}", FormatDate(item.ModTime, opts.DateFormat))
+	}
+	if strings.Contains(template, "{exif}") {
+		taken := time.Time{}
+		if exifDate != nil {
+			taken = exifDate(item.Path)
+		}
+		if taken.IsZero() {
+			taken = item.ModTime
+		}
+		tokens = append(tokens, "{exif}", FormatDate(taken, opts.DateFormat))
+	}
+	return strings.NewReplacer(tokens...).Replace(template)
+}
+
+func clampPosition(length, at int, fromEnd bool) int {
+	at = max(0, min(at, length))
+	if fromEnd {
+		return length - at
+	}
+	return at
+}
+
+func insertRunes(text, insert string, at int, fromEnd bool) string {
+	runes := []rune(text)
+	at = clampPosition(len(runes), at, fromEnd)
+	return string(runes[:at]) + insert + string(runes[at:])
+}
+
+func removeRunes(text string, at, count int, fromEnd bool) string {
+	runes := []rune(text)
+	start := clampPosition(len(runes), at, false)
+	if fromEnd {
+		start = max(0, len(runes)-at-count)
+	}
+	end := min(len(runes), start+count)
+	return string(runes[:start]) + string(runes[end:])
+}
+
+func changeCase(text string, mode CaseMode) string {
+	switch mode {
+	case CaseLower:
+		return strings.ToLower(text)
+	case CaseUpper:
+		return strings.ToUpper(text)
+	case CaseTitle, CaseSentence:
+		runes := []rune(strings.ToLower(text))
+		startOfWord := true
+		for i, r := range runes {
+			if startOfWord && unicode.IsLetter(r) {
+				runes[i] = unicode.ToUpper(r)
+				if mode == CaseSentence {
+					break
+				}
+			}
+			startOfWord = !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		}
+		return string(runes)
+	default:
+		return text
+	}
+}
+
+// datePlaceholders are the parts of a date format that stand for a part of
+// the date, with the Go layout of each.
+var datePlaceholders = []struct{ placeholder, layout string }{
+	{"YYYY", "2006"},
+	{"MM", "01"},
+	{"DD", "02"},
+	{"hh", "15"},
+	{"mm", "04"},
+	{"ss", "05"},
+}
+
+// FormatDate formats t with the YYYY, MM, DD, hh, mm and ss placeholders.
+// The rest of format is copied as it is.
+func FormatDate(t time.Time, format string) string {
+	if format == "" {
+		format = "YYYY-MM-DD"
+	}
+	var builder strings.Builder
+	for rest := format; rest != ""; {
+		matched := false
+		for _, date := range datePlaceholders {
+			if strings.HasPrefix(rest, date.placeholder) {
+				builder.WriteString(t.Format(date.layout))
+				rest = rest[len(date.placeholder):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			_, size := utf8.DecodeRuneInString(rest)
+			builder.WriteString(rest[:size])
+			rest = rest[size:]
+		}
+	}
+	return builder.String()
+}
+
+// checkConflicts marks renames whose new path is used twice in the batch or
+// by a file that stays where it is.
+func checkConflicts(renames []BulkRename) {
+	moving := make(map[string]bool)
+	targets := make(map[string]int)
+	for _, rename := range renames {
+		if rename.Changed() {
+			moving[rename.OldPath] = true
+		}
+		targets[rename.NewPath]++
+	}
+
+	for i := range renames {
+		rename := &renames[i]
+		if rename.Err != nil || !rename.Changed() {
+			continue
+		}
+		if targets[rename.NewPath] > 1 {
+			rename.Err = errors.New("Name is used twice")
+			continue
+		}
+		if moving[rename.NewPath] {
+			continue
+		}
+		if _, err := os.Lstat(rename.NewPath); !os.IsNotExist(err) && !sameFile(rename.OldPath, rename.NewPath) {
+			rename.Err = fmt.Errorf("\"%s\" already exists", filepath.Base(rename.NewPath))
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package rename_popup
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+type BulkRenameWindow struct {
+	*gtk.Window
+	Items   []*types.ListItem
+	Options BulkOptions
+	Applied func(batch *Batch)
+
+	findEntry       *gtk.Entry
+	replaceEntry    *gtk.Entry
+	regexCheck      *gtk.CheckButton
+	matchCaseCheck  *gtk.CheckButton
+	extensionCheck  *gtk.CheckButton
+	insertEntry     *gtk.Entry
+	insertAtSpin    *gtk.SpinButton
+	insertFromEnd   *gtk.CheckButton
+	removeAtSpin    *gtk.SpinButton
+	removeCountSpin *gtk.SpinButton
+	removeFromEnd   *gtk.CheckButton
+	caseDropDown    *gtk.DropDown
+	templateEntry   *gtk.Entry
+	numberStartSpin *gtk.SpinButton
+	numberPadSpin   *gtk.SpinButton
+	dateFormatEntry *gtk.Entry
+	previewScroll   *gtk.ScrolledWindow
+	statusLabel     *gtk.Label
+	renameButton    *gtk.Button
+	renames         []BulkRename
+	exifDates       map[string]time.Time
+}
+
+func NewBulkRenameWindow(items []*types.ListItem) *BulkRenameWindow {
+	bw := &BulkRenameWindow{
+		Window:          gtk.NewWindow(),
+		Items:           items,
+		Options:         DefaultBulkOptions(),
+		findEntry:       gtk.NewEntry(),
+		replaceEntry:    gtk.NewEntry(),
+		regexCheck:      gtk.NewCheckButtonWithLabel("Regular expression"),
+		matchCaseCheck:  gtk.NewCheckButtonWithLabel("Match case"),
+		extensionCheck:  gtk.NewCheckButtonWithLabel("Include extension"),
+		insertEntry:     gtk.NewEntry(),
+		insertAtSpin:    gtk.NewSpinButtonWithRange(0, 255, 1),
+		insertFromEnd:   gtk.NewCheckButtonWithLabel("From end"),
+		removeAtSpin:    gtk.NewSpinButtonWithRange(0, 255, 1),
+		removeCountSpin: gtk.NewSpinButtonWithRange(0, 255, 1),
+		removeFromEnd:   gtk.NewCheckButtonWithLabel("From end"),
+		templateEntry:   gtk.NewEntry(),
+		numberStartSpin: gtk.NewSpinButtonWithRange(0, 1000000, 1),
+		numberPadSpin:   gtk.NewSpinButtonWithRange(0, 10, 1),
+		dateFormatEntry: gtk.NewEntry(),
+		previewScroll:   gtk.NewScrolledWindow(),
+		statusLabel:     gtk.NewLabel(""),
+		renameButton:    gtk.NewButtonWithLabel("Rename"),
+		exifDates:       make(map[string]time.Time),
+	}
+
+	caseNames := make([]string, len(CaseModes))
+	for i, mode := range CaseModes {
+		caseNames[i] = mode.String()
+	}
+	bw.caseDropDown = gtk.NewDropDownFromStrings(caseNames)
+
+	bw.SetTitle(fmt.Sprintf("Rename %d Items", len(items)))
+	bw.SetDefaultSize(760, 560)
+	bw.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	bw.SetChild(box)
+
+	form := gtk.NewGrid()
+	form.SetRowSpacing(6)
+	form.SetColumnSpacing(6)
+	box.Append(form)
+
+	bw.findEntry.SetHExpand(true)
+	bw.replaceEntry.SetHExpand(true)
+	bw.replaceEntry.SetPlaceholderText("Use $1 for regex groups")
+	bw.insertEntry.SetHExpand(true)
+	bw.templateEntry.SetText(bw.Options.Template)
+	bw.templateEntry.SetTooltipText("Tokens: {name} {ext} {n} {date} {exif}")
+	bw.numberStartSpin.SetValue(float64(bw.Options.NumberStart))
+	bw.dateFormatEntry.SetText(bw.Options.DateFormat)
+	bw.dateFormatEntry.SetTooltipText("YYYY MM DD hh mm ss")
+
+	row := 0
+	addRow := func(label string, widgets ...gtk.Widgetter) {
+		title := gtk.NewLabel(label)
+		title.SetXAlign(0)
+		form.Attach(title, 0, row, 1, 1)
+		for i, widget := range widgets {
+			form.Attach(widget, i+1, row, 1, 1)
+		}
+		row++
+	}
+	addRow("Find", bw.findEntry, bw.regexCheck, bw.matchCaseCheck)
+	addRow("Replace", bw.replaceEntry, bw.extensionCheck)
+	addRow("Insert", bw.insertEntry, bw.insertAtSpin, bw.insertFromEnd)
+	addRow("Remove", bw.removeCountSpin, bw.removeAtSpin, bw.removeFromEnd)
+	addRow("Case", bw.caseDropDown)
+	addRow("Template", bw.templateEntry, bw.numberStartSpin, bw.numberPadSpin)
+	addRow("Date format", bw.dateFormatEntry)
+
+	bw.insertAtSpin.SetTooltipText("Position")
+	bw.removeCountSpin.SetTooltipText("Characters to remove")
+	bw.removeAtSpin.SetTooltipText("Position")
+	bw.numberStartSpin.SetTooltipText("{n} start")
+	bw.numberPadSpin.SetTooltipText("{n} padding")
+
+	bw.previewScroll.SetVExpand(true)
+	box.Append(bw.previewScroll)
+
+	bw.statusLabel.SetXAlign(0)
+	bw.statusLabel.SetHExpand(true)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(bw.Destroy)
+	bw.renameButton.AddCSSClass("suggested-action")
+	bw.renameButton.ConnectClicked(bw.apply)
+	buttonBox.Append(bw.statusLabel)
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(bw.renameButton)
+	box.Append(buttonBox)
+
+	for _, entry := range []*gtk.Entry{bw.findEntry, bw.replaceEntry, bw.insertEntry, bw.templateEntry, bw.dateFormatEntry} {
+		entry.ConnectChanged(bw.update)
+		entry.ConnectActivate(bw.apply)
+	}
+	for _, check := range []*gtk.CheckButton{bw.regexCheck, bw.matchCaseCheck, bw.extensionCheck, bw.insertFromEnd, bw.removeFromEnd} {
+		check.ConnectToggled(bw.update)
+	}
+	for _, spin := range []*gtk.SpinButton{bw.insertAtSpin, bw.removeAtSpin, bw.removeCountSpin, bw.numberStartSpin, bw.numberPadSpin} {
+		spin.ConnectValueChanged(bw.update)
+	}
+	bw.caseDropDown.NotifyProperty("selected", bw.update)
+
+	bw.update()
+	return bw
+}
+
+func (bw *BulkRenameWindow) readOptions() {
+	bw.Options = BulkOptions{
+		Find:             bw.findEntry.Text(),
+		Replace:          bw.replaceEntry.Text(),
+		UseRegex:         bw.regexCheck.Active(),
+		MatchCase:        bw.matchCaseCheck.Active(),
+		IncludeExtension: bw.extensionCheck.Active(),
+		Insert:           bw.insertEntry.Text(),
+		InsertAt:         bw.insertAtSpin.ValueAsInt(),
+		InsertFromEnd:    bw.insertFromEnd.Active(),
+		RemoveAt:         bw.removeAtSpin.ValueAsInt(),
+		RemoveCount:      bw.removeCountSpin.ValueAsInt(),
+		RemoveFromEnd:    bw.removeFromEnd.Active(),
+		Case:             CaseModes[bw.caseDropDown.Selected()],
+		Template:         bw.templateEntry.Text(),
+		NumberStart:      bw.numberStartSpin.ValueAsInt(),
+		NumberPadding:    bw.numberPadSpin.ValueAsInt(),
+		DateFormat:       bw.dateFormatEntry.Text(),
+	}
+}
+
+func (bw *BulkRenameWindow) exifDate(path string) time.Time {
+	if date, ok := bw.exifDates[path]; ok {
+		return date
+	}
+	date, err := fileops.GetExifDate(path)
+	if err != nil {
+		date = time.Time{}
+	}
+	bw.exifDates[path] = date
+	return date
+}
+
+func (bw *BulkRenameWindow) update() {
+	bw.readOptions()
+	renames, err := PreviewBulkRename(bw.Items, bw.Options, bw.exifDate)
+	if err != nil {
+		bw.renames = nil
+		bw.findEntry.AddCSSClass("error")
+		bw.statusLabel.SetText(err.Error())
+		bw.renameButton.SetSensitive(false)
+		return
+	}
+	bw.findEntry.RemoveCSSClass("error")
+	bw.renames = renames
+
+	grid := gtk.NewGrid()
+	grid.SetRowSpacing(4)
+	grid.SetColumnSpacing(12)
+	for i, title := range []string{"Name", "", "New Name", ""} {
+		label := gtk.NewLabel(title)
+		label.SetXAlign(0)
+		label.AddCSSClass("dim-label")
+		grid.Attach(label, i, 0, 1, 1)
+	}
+
+	changed, failed := 0, 0
+	for i, rename := range renames {
+		oldLabel := gtk.NewLabel(filepath.Base(rename.OldPath))
+		newLabel := gtk.NewLabel(filepath.Base(rename.NewPath))
+		statusLabel := gtk.NewLabel("")
+		for _, label := range []*gtk.Label{oldLabel, newLabel, statusLabel} {
+			label.SetXAlign(0)
+			label.SetEllipsize(pango.EllipsizeMiddle)
+			label.SetHExpand(true)
+		}
+		statusLabel.SetHExpand(false)
+
+		if rename.Err != nil {
+			failed++
+			newLabel.AddCSSClass("error")
+			statusLabel.AddCSSClass("error")
+			statusLabel.SetText(rename.Err.Error())
+		} else if rename.Changed() {
+			changed++
+		} else {
+			newLabel.AddCSSClass("dim-label")
+		}
+
+		grid.Attach(oldLabel, 0, i+1, 1, 1)
+		grid.Attach(gtk.NewLabel("→"), 1, i+1, 1, 1)
+		grid.Attach(newLabel, 2, i+1, 1, 1)
+		grid.Attach(statusLabel, 3, i+1, 1, 1)
+	}
+	bw.previewScroll.SetChild(grid)
+
+	switch {
+	case failed > 0:
+		bw.statusLabel.SetText(fmt.Sprintf("%d names have problems", failed))
+	case changed == 0:
+		bw.statusLabel.SetText("No names change")
+	default:
+		bw.statusLabel.SetText(fmt.Sprintf("%d of %d items will be renamed", changed, len(renames)))
+	}
+	bw.renameButton.SetSensitive(failed == 0 && changed > 0)
+}
+
+func (bw *BulkRenameWindow) apply() {
+	if !bw.renameButton.Sensitive() {
+		return
+	}
+	batch, err := ApplyBulkRename(bw.renames)
+	if err != nil {
+		bw.update()
+		bw.statusLabel.SetText(err.Error())
+		return
+	}
+	if bw.Applied != nil {
+		bw.Applied(batch)
+	}
+	bw.Destroy()
+}
@@ -1,75 +1,101 @@
 package rename_popup
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
 )
 
-type RenameWindow struct {
-	*gtk.Window
-	Entry        *gtk.Entry
-	BasePath     string
-	SelectedPath string
-}
+const illegalChars = "/\\:*?\"<>|"
 
-func NewRenameWindow(basePath string, selectedPath string) *RenameWindow {
-	rw := &RenameWindow{
-		Window:       gtk.NewWindow(),
-		Entry:        gtk.NewEntry(),
-		BasePath:     basePath,
-		SelectedPath: selectedPath,
+// ValidateName checks whether the item at oldPath can be renamed to name
+// inside its own directory. The returned error is meant to be shown to the
+// user as is.
+func ValidateName(oldPath string, name string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	if name == filepath.Base(oldPath) {
+		return nil
 	}
 
-	rw.SetTitle("Rename")
-	rw.SetDefaultSize(400, 50)
-	rw.SetModal(true)
-
-	box := gtk.NewBox(gtk.OrientationVertical, 5)
-	rw.SetChild(box)
-
-	rw.Entry.SetText(filepath.Base(selectedPath))
-	box.Append(rw.Entry)
-
-	rw.Entry.Connect("activate", func() {
-		if rw.isEntryValid() {
-			newPath := filepath.Join(rw.BasePath, rw.GetNewName())
-			err := os.Rename(rw.SelectedPath, newPath)
-			if err != nil {
-				log.Printf("Error renaming %s to %s: %v", rw.SelectedPath, newPath, err)
-			} else {
-				rw.Destroy()
-			}
-		} else {
-			rw.Entry.SetIconFromIconName(gtk.EntryIconSecondary, "window-close-symbolic")
+	newPath := filepath.Join(filepath.Dir(oldPath), name)
+	if _, err := os.Lstat(newPath); !os.IsNotExist(err) {
+		// A case only rename on a case insensitive file system resolves to
+		// the item itself.
+		if sameFile(oldPath, newPath) {
+			return nil
 		}
-	})
-
-	return rw
+		return fmt.Errorf("\"%s\" already exists", name)
+	}
+	return nil
 }
 
-func (rw *RenameWindow) GetNewName() string {
-	return rw.Entry.Text()
+// CheckName checks name for characters and lengths that cannot be used in
+// a file name.
+func CheckName(name string) error {
+	if len(name) == 0 {
+		return errors.New("Name cannot be empty")
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("\"%s\" is not a valid name", name)
+	}
+	if i := strings.IndexAny(name, illegalChars); i >= 0 {
+		return fmt.Errorf("Name cannot contain \"%c\"", name[i])
+	}
+	if len(name) > 255 {
+		return errors.New("Name is too long")
+	}
+	return nil
 }
 
-func (rw *RenameWindow) isEntryValid() bool {
-	searchText := rw.Entry.Text()
-
-	hasIllegalChars := false
-	if strings.ContainsAny(searchText, "/\\:*?\"<>|") || len(searchText) == 0 {
-		hasIllegalChars = true
+// Rename validates name and renames the item at oldPath, returning the new
+// path.
+func Rename(oldPath string, name string) (string, error) {
+	if err := ValidateName(oldPath, name); err != nil {
+		return "", err
 	}
+	newPath := filepath.Join(filepath.Dir(oldPath), name)
+	if newPath == oldPath {
+		return oldPath, nil
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return "", err
+	}
+	fileops.Moved([]string{oldPath}, []string{newPath})
+	return newPath, nil
+}
 
-	newPath := filepath.Join(rw.BasePath, searchText)
-	_, err := os.Stat(newPath)
-	alreadyExists := !os.IsNotExist(err)
-
-	if searchText == filepath.Base(rw.SelectedPath) {
-		alreadyExists = false
+// StemLength returns the length in characters of the part of name that is
+// selected when renaming starts, which is the name without its extension.
+// Compound extensions such as ".tar.gz" are kept together and hidden files
+// without another dot are selected completely.
+func StemLength(name string, isDir bool) int {
+	stem := name
+	if !isDir {
+		if i := strings.LastIndex(name, "."); i > 0 {
+			stem = name[:i]
+			if j := strings.LastIndex(stem, "."); j > 0 && strings.EqualFold(stem[j:], ".tar") {
+				stem = stem[:j]
+			}
+		}
 	}
+	return utf8.RuneCountInString(stem)
+}
 
-	return !hasIllegalChars && !alreadyExists
+func sameFile(a, b string) bool {
+	aInfo, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Lstat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
 }
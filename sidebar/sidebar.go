@@ -1,11 +1,16 @@
 package sidebar
 
 import (
+	"math"
 	"os"
 	"os/user"
 	"runtime"
+	"strings"
 
+	"github.com/MrSametBurgazoglu/atilgan/tag"
 	"github.com/adrg/xdg"
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
@@ -13,6 +18,13 @@ type Sidebar struct {
 	*gtk.Box
 	buttons     map[string]*gtk.Button
 	currentPath string
+	tagDots     *gtk.DrawingArea
+	tagColors   []string
+	pinned      []*gtk.Button
+	pathChanged func(string)
+
+	// UnpinQuery is called to take a pinned tag query off the sidebar.
+	UnpinQuery func(query string)
 }
 
 func NewSidebar(pathChanged func(string)) *Sidebar {
@@ -22,8 +34,9 @@ func NewSidebar(pathChanged func(string)) *Sidebar {
 	box.AddCSSClass("sidebar")
 
 	sidebar := &Sidebar{
-		Box:     box,
-		buttons: make(map[string]*gtk.Button),
+		Box:         box,
+		buttons:     make(map[string]*gtk.Button),
+		pathChanged: pathChanged,
 	}
 
 	homeDir, err := getHomeDir()
@@ -48,7 +61,8 @@ func NewSidebar(pathChanged func(string)) *Sidebar {
 	recentButton.SetTooltipText("recent")
 	sidebar.buttons["recent://"] = recentButton
 
-	tagsButton := gtk.NewButtonFromIconName("tag-symbolic")
+	tagsButton := gtk.NewButton()
+	tagsButton.SetChild(sidebar.newTagsIcon())
 	tagsButton.AddCSSClass("sidebar-button")
 	tagsButton.SetTooltipText("tags")
 	sidebar.buttons["tags://"] = tagsButton
@@ -139,6 +153,82 @@ func NewSidebar(pathChanged func(string)) *Sidebar {
 	return sidebar
 }
 
+// newTagsIcon is the tag icon with a dot for each color the tags use
+// below it.
+func (s *Sidebar) newTagsIcon() *gtk.Overlay {
+	overlay := gtk.NewOverlay()
+	overlay.SetChild(gtk.NewImageFromIconName("tag-symbolic"))
+	s.tagDots = gtk.NewDrawingArea()
+	s.tagDots.SetCanTarget(false)
+	s.tagDots.SetDrawFunc(func(_ *gtk.DrawingArea, cr *cairo.Context, w, h int) {
+		const radius, spacing = 1.5, 4.0
+		x := (float64(w) - spacing*float64(len(s.tagColors)-1)) / 2
+		for i, color := range s.tagColors {
+			if r, g, b, ok := tag.RGB(color); ok {
+				cr.SetSourceRGB(r, g, b)
+				cr.Arc(x+float64(i)*spacing, float64(h)-radius, radius, 0, 2*math.Pi)
+				cr.Fill()
+			}
+		}
+	})
+	overlay.AddOverlay(s.tagDots)
+	return overlay
+}
+
+// SetTagColors sets the colors shown on the tags button.
+func (s *Sidebar) SetTagColors(colors []string) {
+	s.tagColors = colors
+	s.tagDots.QueueDraw()
+}
+
+// SetPinnedQueries shows a button for each of the pinned tag queries after
+// the tags button.
+func (s *Sidebar) SetPinnedQueries(queries []string) {
+	for _, button := range s.pinned {
+		s.Remove(button)
+	}
+	s.pinned = nil
+	for path := range s.buttons {
+		if strings.HasPrefix(path, "tags://") && path != "tags://" {
+			delete(s.buttons, path)
+		}
+	}
+
+	for _, query := range queries {
+		path := "tags://" + query
+		button := gtk.NewButtonFromIconName("folder-saved-search-symbolic")
+		button.AddCSSClass("sidebar-button")
+		button.SetTooltipText(query)
+		button.ConnectClicked(func() {
+			s.pathChanged(path)
+		})
+
+		click := gtk.NewGestureClick()
+		click.SetButton(gdk.BUTTON_SECONDARY)
+		click.ConnectPressed(func(n int, x, y float64) {
+			pop := gtk.NewPopover()
+			unpin := gtk.NewButtonWithLabel("Unpin")
+			unpin.AddCSSClass("flat")
+			unpin.ConnectClicked(func() {
+				pop.Popdown()
+				if s.UnpinQuery != nil {
+					s.UnpinQuery(query)
+				}
+			})
+			pop.SetChild(unpin)
+			pop.SetParent(button)
+			pop.ConnectClosed(pop.Unparent)
+			pop.Popup()
+		})
+		button.AddController(click)
+
+		s.Append(button)
+		s.pinned = append(s.pinned, button)
+		s.buttons[path] = button
+	}
+	s.SetPath(s.currentPath)
+}
+
 func (s *Sidebar) SetPath(path string) {
 	s.currentPath = path
 	for btnPath, button := range s.buttons {
@@ -0,0 +1,100 @@
+package sort_popup
+
+import (
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+type SortPopover struct {
+	*gtk.Popover
+	Options            sorter.SortOptions
+	keyButtons         map[sorter.SortKey]*gtk.CheckButton
+	descendingButton   *gtk.CheckButton
+	foldersFirstButton *gtk.CheckButton
+	updating           bool
+
+	OptionsChanged func(sorter.SortOptions)
+}
+
+func NewSortPopover(optionsChanged func(sorter.SortOptions)) *SortPopover {
+	sp := &SortPopover{
+		Popover:        gtk.NewPopover(),
+		Options:        sorter.DefaultSortOptions(),
+		keyButtons:     make(map[sorter.SortKey]*gtk.CheckButton),
+		OptionsChanged: optionsChanged,
+	}
+
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	sp.SetChild(box)
+
+	var group *gtk.CheckButton
+	for _, key := range sorter.SortKeys {
+		button := gtk.NewCheckButtonWithLabel(key.String())
+		if group == nil {
+			group = button
+		} else {
+			button.SetGroup(group)
+		}
+		sortKey := key
+		button.ConnectToggled(func() {
+			if !button.Active() {
+				return
+			}
+			sp.Options.Key = sortKey
+			if !sp.updating {
+				sp.Options.Descending = sortKey.DefaultDescending()
+				sp.SetOptions(sp.Options)
+			}
+			sp.notify()
+		})
+		sp.keyButtons[key] = button
+		box.Append(button)
+	}
+
+	box.Append(gtk.NewSeparator(gtk.OrientationHorizontal))
+
+	sp.descendingButton = gtk.NewCheckButtonWithLabel("Descending")
+	sp.descendingButton.ConnectToggled(func() {
+		sp.Options.Descending = sp.descendingButton.Active()
+		sp.notify()
+	})
+	box.Append(sp.descendingButton)
+
+	sp.foldersFirstButton = gtk.NewCheckButtonWithLabel("Folders first")
+	sp.foldersFirstButton.ConnectToggled(func() {
+		sp.Options.FoldersFirst = sp.foldersFirstButton.Active()
+		sp.notify()
+	})
+	box.Append(sp.foldersFirstButton)
+
+	sp.SetOptions(sp.Options)
+	return sp
+}
+
+// SetOptions updates the buttons to match opts without calling
+// OptionsChanged.
+func (sp *SortPopover) SetOptions(opts sorter.SortOptions) {
+	sp.updating = true
+	defer func() { sp.updating = false }()
+	sp.Options = opts
+	if button, ok := sp.keyButtons[opts.Key]; ok {
+		button.SetActive(true)
+	}
+	sp.descendingButton.SetActive(opts.Descending)
+	sp.foldersFirstButton.SetActive(opts.FoldersFirst)
+}
+
+func (sp *SortPopover) notify() {
+	if sp.updating || sp.OptionsChanged == nil {
+		return
+	}
+	sp.OptionsChanged(sp.Options)
+}
+
+// IconName returns the sort button icon for the current direction.
+func (sp *SortPopover) IconName() string {
+	if sp.Options.Descending {
+		return "view-sort-descending-symbolic"
+	}
+	return "view-sort-ascending-symbolic"
+}
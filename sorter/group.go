@@ -0,0 +1,154 @@
+package sorter
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+type typeGroup struct {
+	name       string
+	extensions []string
+}
+
+var typeGroups = []typeGroup{
+	{"Documents", []string{".doc", ".docx", ".odt", ".pdf", ".txt", ".md", ".epub", ".mobi", ".xlsx", ".ods", ".pptx", ".odp", ".csv"}},
+	{"Images", []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".svg", ".bmp", ".tiff", ".heic"}},
+	{"Audio", []string{".mp3", ".ogg", ".wav", ".flac", ".m4a", ".opus"}},
+	{"Video", []string{".mp4", ".mkv", ".mov", ".avi", ".webm"}},
+	{"Archives", []string{".zip", ".gz", ".tar", ".xz", ".zst", ".bz2", ".7z", ".rar", ".tgz"}},
+	{"Code", []string{".go", ".py", ".js", ".ts", ".json", ".yaml", ".yml", ".c", ".cpp", ".h", ".hpp", ".rs", ".rb", ".php", ".java", ".kt", ".swift", ".sh", ".html", ".css"}},
+}
+
+// GroupFor returns the list header an item belongs to under key.
+func GroupFor(item *types.ListItem, key SortKey) string {
+	switch key {
+	case SortByTime:
+		return GetGroupForTime(item.ModTime)
+	case SortByCreated:
+		return GetGroupForTime(item.CreatedTime)
+	case SortBySize:
+		return getGroupForSize(item)
+	case SortByType:
+		return getGroupForType(item)
+	case SortByItemCount:
+		return getGroupForItemCount(item)
+	case SortByDiskUsage:
+		return getGroupForDiskUsage(item)
+	default:
+		return getGroupForName(item.Name)
+	}
+}
+
+func GetGroupForTime(modTime time.Time) string {
+	if modTime.IsZero() {
+		return "Unknown"
+	}
+	now := time.Now()
+	duration := now.Sub(modTime)
+
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if modTime.After(todayStart) {
+		return "Today"
+	}
+
+	if duration.Hours() <= 24 {
+		return "Last 24 hours"
+	}
+
+	if duration.Hours() <= 24*7 {
+		return "Last Week"
+	}
+
+	if duration.Hours() <= 24*30 {
+		return "Last Month"
+	}
+
+	return "Later"
+}
+
+func getGroupForName(name string) string {
+	for _, r := range name {
+		return string(unicode.ToUpper(r))
+	}
+	return ""
+}
+
+func getGroupForSize(item *types.ListItem) string {
+	if item.IsDir {
+		return "Folders"
+	}
+	return getGroupForBytes(item.Size)
+}
+
+func getGroupForDiskUsage(item *types.ListItem) string {
+	if item.IsDir && item.TotalSize == 0 {
+		return "Not Scanned"
+	}
+	return getGroupForBytes(DiskUsage(item))
+}
+
+func getGroupForBytes(size int64) string {
+	switch {
+	case size == 0:
+		return "Empty"
+	case size < 16*1024:
+		return "Tiny (< 16 KB)"
+	case size < 1024*1024:
+		return "Small (< 1 MB)"
+	case size < 128*1024*1024:
+		return "Medium (< 128 MB)"
+	case size < 1024*1024*1024:
+		return "Large (< 1 GB)"
+	default:
+		return "Huge (> 1 GB)"
+	}
+}
+
+func getGroupForItemCount(item *types.ListItem) string {
+	if !item.IsDir {
+		return "Files"
+	}
+	switch count := item.ItemCount; {
+	case count == 0:
+		return "Empty"
+	case count <= 10:
+		return "1 - 10 items"
+	case count <= 100:
+		return "11 - 100 items"
+	default:
+		return "More than 100 items"
+	}
+}
+
+func getGroupForType(item *types.ListItem) string {
+	rank := typeRank(item)
+	switch {
+	case rank == 0:
+		return "Folders"
+	case rank <= len(typeGroups):
+		return typeGroups[rank-1].name
+	default:
+		return "Other"
+	}
+}
+
+// typeRank orders folders first, then the type groups in declaration order
+// and everything else last.
+func typeRank(item *types.ListItem) int {
+	if item.IsDir {
+		return 0
+	}
+	ext := strings.ToLower(filepath.Ext(item.Name))
+	for i, group := range typeGroups {
+		for _, e := range group.extensions {
+			if e == ext {
+				return i + 1
+			}
+		}
+	}
+	return len(typeGroups) + 1
+}
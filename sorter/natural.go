@@ -0,0 +1,41 @@
+package sorter
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+type collator struct {
+	*collate.Collator
+}
+
+// newCollator returns a collator for the user's locale that compares runs
+// of digits by their numeric value, so "file2" sorts before "file10".
+func newCollator() *collator {
+	return &collator{
+		Collator: collate.New(userLanguage(), collate.IgnoreCase, collate.Loose, collate.Numeric),
+	}
+}
+
+func (c *collator) compare(a, b string) int {
+	return c.CompareString(a, b)
+}
+
+func userLanguage() language.Tag {
+	for _, env := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" || value == "C" || value == "POSIX" {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		tag, err := language.Parse(strings.ReplaceAll(value, "_", "-"))
+		if err == nil {
+			return tag
+		}
+	}
+	return language.English
+}
@@ -0,0 +1,207 @@
+package sorter
+
+import (
+	"cmp"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/types"
+)
+
+type SortKey int
+
+const (
+	SortByName SortKey = iota
+	SortByTime
+	SortBySize
+	SortByType
+	SortByCreated
+	SortByItemCount
+	SortByDiskUsage
+	// SortByColumn sorts by the text of a details view column, see
+	// SortWithValues.
+	SortByColumn
+)
+
+var SortKeys = []SortKey{SortByName, SortByTime, SortByCreated, SortBySize, SortByDiskUsage, SortByType, SortByItemCount}
+
+func (k SortKey) String() string {
+	switch k {
+	case SortByTime:
+		return "Modified"
+	case SortBySize:
+		return "Size"
+	case SortByType:
+		return "Type"
+	case SortByCreated:
+		return "Created"
+	case SortByItemCount:
+		return "Item Count"
+	case SortByDiskUsage:
+		return "Disk Usage"
+	case SortByColumn:
+		return "Column"
+	default:
+		return "Name"
+	}
+}
+
+type SortOptions struct {
+	Key          SortKey `json:"key"`
+	Descending   bool    `json:"descending"`
+	FoldersFirst bool    `json:"folders_first"`
+	Column       string  `json:"column,omitempty"`
+}
+
+func DefaultSortOptions() SortOptions {
+	return SortOptions{Key: SortByName}
+}
+
+// DefaultDescending tells which direction k starts in when it is picked:
+// times newest first, as they always sorted, everything else ascending.
+func (k SortKey) DefaultDescending() bool {
+	return k == SortByTime || k == SortByCreated
+}
+
+// UnmarshalJSON takes options saved without a direction as sorting in the
+// default direction of their key.
+func (o *SortOptions) UnmarshalJSON(data []byte) error {
+	type plain SortOptions
+	var saved struct {
+		plain
+		Descending *bool `json:"descending"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	*o = SortOptions(saved.plain)
+	if saved.Descending != nil {
+		o.Descending = *saved.Descending
+	} else {
+		o.Descending = o.Key.DefaultDescending()
+	}
+	return nil
+}
+
+func (o SortOptions) String() string {
+	direction := "ascending"
+	if o.Descending {
+		direction = "descending"
+	}
+	if o.Key == SortByColumn {
+		return strings.ReplaceAll(o.Column, "_", " ") + ", " + direction
+	}
+	return o.Key.String() + ", " + direction
+}
+
+// Sort orders items by opts and fills in the Group of every item so that
+// the list headers match the active key. Items that compare equal keep a
+// deterministic order by name and then by path.
+func Sort(items []*types.ListItem, opts SortOptions) {
+	SortWithValues(items, opts, nil)
+}
+
+// SortWithValues is like Sort but takes the values compared and grouped by
+// for SortByColumn from value. Without value SortByColumn sorts by name.
+func SortWithValues(items []*types.ListItem, opts SortOptions, value func(*types.ListItem) string) {
+	if opts.Key == SortByColumn && value == nil {
+		opts.Key = SortByName
+	}
+	collator := newCollator()
+	slices.SortStableFunc(items, func(a, b *types.ListItem) int {
+		if opts.FoldersFirst && a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		var c int
+		if opts.Key == SortByColumn {
+			c = collator.compare(value(a), value(b))
+		} else {
+			c = compareKey(a, b, opts.Key, collator)
+		}
+		if opts.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		if c = collator.compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	for _, item := range items {
+		if opts.FoldersFirst && item.IsDir {
+			item.Group = "Folders"
+			continue
+		}
+		if opts.Key == SortByColumn {
+			item.Group = value(item)
+			if item.Group == "" {
+				item.Group = "Unknown"
+			}
+			continue
+		}
+		item.Group = GroupFor(item, opts.Key)
+	}
+}
+
+func compareKey(a, b *types.ListItem, key SortKey, collator *collator) int {
+	switch key {
+	case SortByTime:
+		return a.ModTime.Compare(b.ModTime)
+	case SortByCreated:
+		return a.CreatedTime.Compare(b.CreatedTime)
+	case SortBySize:
+		return cmp.Compare(sizeKey(a), sizeKey(b))
+	case SortByType:
+		if c := cmp.Compare(typeRank(a), typeRank(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(extension(a), extension(b))
+	case SortByItemCount:
+		return cmp.Compare(itemCountKey(a), itemCountKey(b))
+	case SortByDiskUsage:
+		return cmp.Compare(DiskUsage(a), DiskUsage(b))
+	default:
+		return collator.compare(a.Name, b.Name)
+	}
+}
+
+// sizeKey places directories, which don't carry a size, before every file.
+func sizeKey(item *types.ListItem) int64 {
+	if item.IsDir {
+		return -1
+	}
+	return item.Size
+}
+
+// DiskUsage returns the size of a file or the scanned recursive size of a
+// folder.
+func DiskUsage(item *types.ListItem) int64 {
+	if item.IsDir {
+		return item.TotalSize
+	}
+	return item.Size
+}
+
+// itemCountKey places files, which don't carry an item count, before every
+// directory.
+func itemCountKey(item *types.ListItem) int {
+	if !item.IsDir {
+		return -1
+	}
+	return item.ItemCount
+}
+
+func extension(item *types.ListItem) string {
+	if item.IsDir {
+		return ""
+	}
+	return strings.ToLower(filepath.Ext(item.Name))
+}
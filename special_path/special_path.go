@@ -3,6 +3,11 @@ package special_path
 import (
 	"strings"
 
+	"github.com/MrSametBurgazoglu/atilgan/archive"
+	"github.com/MrSametBurgazoglu/atilgan/dir_compare"
+	"github.com/MrSametBurgazoglu/atilgan/disk_usage"
+	"github.com/MrSametBurgazoglu/atilgan/duplicates"
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
 	"github.com/MrSametBurgazoglu/atilgan/recent"
 	"github.com/MrSametBurgazoglu/atilgan/tag"
 	"github.com/MrSametBurgazoglu/atilgan/trash"
@@ -11,7 +16,11 @@ import (
 type SpecialPathManager struct {
 	Paths         map[string]IPath
 	tagManager    *tag.TagManager
+	autoTagger    *tag.AutoTagger
 	recentManager *recent.RecentManager
+	duplicates    *duplicates.Manager
+	diskUsage     *disk_usage.Cache
+	comparisons   *dir_compare.Manager
 }
 
 func NewSpecialPathManager() (*SpecialPathManager, error) {
@@ -20,26 +29,37 @@ func NewSpecialPathManager() (*SpecialPathManager, error) {
 		println(err.Error())
 		return nil, err
 	}
+	fileops.OnMove(tagManager.Move)
+	autoTagger, err := tag.NewAutoTagger(tagManager)
+	if err != nil {
+		return nil, err
+	}
 	recentManager, err := recent.NewRecentManager()
 	if err != nil {
 		return nil, err
 	}
 	return &SpecialPathManager{
 		Paths: map[string]IPath{
-			"trash":  trash.NewTrash(),
-			"tags":   tag.NewTagsPath(tagManager),
-			"recent": recent.NewRecentPath(recentManager),
+			"trash": trash.NewTrash(),
+			"tags":  tag.NewTagsPath(tagManager),
 		},
 		tagManager:    tagManager,
+		autoTagger:    autoTagger,
 		recentManager: recentManager,
+		duplicates:    duplicates.NewManager(),
+		diskUsage:     disk_usage.NewCache(),
+		comparisons:   dir_compare.NewManager(),
 	}, nil
 }
 
 func (spm *SpecialPathManager) GetPath(path string) IPath {
 	if strings.HasPrefix(path, "tags://") {
-		parts := strings.Split(strings.TrimPrefix(path, "tags://"), "/")
-		if len(parts) == 1 && parts[0] != "" {
-			return tag.NewTagPath(parts[0], spm.tagManager)
+		name := strings.Trim(strings.TrimPrefix(path, "tags://"), "/")
+		if tag.IsQuery(name) && !spm.tagManager.IsTag(name) {
+			return tag.NewQueryPath(name, spm.tagManager)
+		}
+		if name != "" {
+			return tag.NewTagPath(name, spm.tagManager)
 		}
 		return spm.Paths["tags"]
 	}
@@ -47,15 +67,53 @@ func (spm *SpecialPathManager) GetPath(path string) IPath {
 		return spm.Paths["trash"]
 	}
 	if strings.HasPrefix(path, "recent://") {
-		return spm.Paths["recent"]
+		return recent.NewRecentPath(path, spm.recentManager)
+	}
+	if strings.HasPrefix(path, duplicates.Scheme) {
+		return duplicates.NewDuplicatesPath(path, spm.duplicates)
+	}
+	if strings.HasPrefix(path, dir_compare.Scheme) {
+		return dir_compare.NewComparePath(path, spm.comparisons)
+	}
+	if archive.IsArchivePath(path) {
+		if archivePath := archive.NewArchivePath(path); archivePath != nil {
+			return archivePath
+		}
 	}
 	return nil
 }
 
-func (spm *SpecialPathManager) AddRecentPath(path string) {
-	spm.recentManager.AddPath(path)
+// AddRecentPath records a visit of the folder, or the file, at path.
+func (spm *SpecialPathManager) AddRecentPath(path string, isDir bool) {
+	spm.recentManager.Visit(path, isDir)
+}
+
+func (spm *SpecialPathManager) GetRecentManager() *recent.RecentManager {
+	return spm.recentManager
+}
+
+// Flush writes the tags and recent paths waiting to be saved right away.
+func (spm *SpecialPathManager) Flush() {
+	spm.tagManager.Flush()
+	spm.recentManager.Flush()
 }
 
 func (spm *SpecialPathManager) GetTagManager() *tag.TagManager {
 	return spm.tagManager
 }
+
+func (spm *SpecialPathManager) GetAutoTagger() *tag.AutoTagger {
+	return spm.autoTagger
+}
+
+func (spm *SpecialPathManager) GetDuplicates() *duplicates.Manager {
+	return spm.duplicates
+}
+
+func (spm *SpecialPathManager) GetDiskUsage() *disk_usage.Cache {
+	return spm.diskUsage
+}
+
+func (spm *SpecialPathManager) GetComparisons() *dir_compare.Manager {
+	return spm.comparisons
+}
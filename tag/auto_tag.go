@@ -0,0 +1,245 @@
+package tag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/MrSametBurgazoglu/atilgan/dir_watch"
+	"github.com/MrSametBurgazoglu/atilgan/json_store"
+)
+
+// AutoTagger applies the rules kept in rules.json to the files found below
+// chosen folders, and to the new files that appear in the folders named by
+// the rules or in the open folder.
+type AutoTagger struct {
+	tagManager *TagManager
+	store      *json_store.Store
+	watcher    *dir_watch.Watcher
+
+	mu       sync.Mutex
+	rules    []Rule
+	matchers []*matcher
+	folder   string
+
+	// Tagged is called, from another goroutine, after a new file got tags
+	// from the rules or brought tags along.
+	Tagged func(path string)
+}
+
+func NewAutoTagger(tagManager *TagManager) (*AutoTagger, error) {
+	store, err := json_store.Open("rules.json")
+	if err != nil {
+		return nil, err
+	}
+	at := &AutoTagger{
+		tagManager: tagManager,
+		store:      store,
+	}
+	data, err := store.Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := at.load(data); err != nil {
+		return nil, err
+	}
+
+	at.watcher, err = dir_watch.NewWatcher()
+	if err != nil {
+		println("couldn't watch folders for new files:", err.Error())
+	} else {
+		at.watcher.Added = at.fileAdded
+		at.watch()
+	}
+	store.Changed = at.reload
+	if err := store.Watch(); err != nil {
+		println("couldn't watch rules.json:", err.Error())
+	}
+	return at, nil
+}
+
+// load takes the rules in data. Rules that are no longer valid are kept
+// but not applied.
+func (at *AutoTagger) load(data []byte) error {
+	var rules []Rule
+	if data != nil {
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return err
+		}
+	}
+	var matchers []*matcher
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		m, err := newMatcher(rule)
+		if err != nil {
+			println("skipping tag rule", rule.Name+":", err.Error())
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+	at.mu.Lock()
+	at.rules = rules
+	at.matchers = matchers
+	at.mu.Unlock()
+	return nil
+}
+
+// watch watches the folders of the rules and the open folder.
+func (at *AutoTagger) watch() {
+	if at.watcher == nil {
+		return
+	}
+	at.mu.Lock()
+	var dirs []string
+	if len(at.matchers) > 0 && at.folder != "" {
+		dirs = append(dirs, at.folder)
+	}
+	for _, m := range at.matchers {
+		if dir := m.rule.WatchedFolder(); dir != "" && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	at.mu.Unlock()
+	at.watcher.Set(dirs)
+}
+
+// reload takes in the rules another instance saved.
+func (at *AutoTagger) reload() {
+	data, err := at.store.Read()
+	if err != nil {
+		println("couldn't reload tag rules:", err.Error())
+		return
+	}
+	if err := at.load(data); err != nil {
+		println("couldn't reload tag rules:", err.Error())
+		return
+	}
+	at.watch()
+}
+
+func (at *AutoTagger) Rules() []Rule {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	return slices.Clone(at.rules)
+}
+
+// SetRules replaces the rules, which have to be valid unless disabled.
+func (at *AutoTagger) SetRules(rules []Rule) error {
+	for _, rule := range rules {
+		if !rule.Disabled {
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = at.store.Update(func([]byte) ([]byte, error) {
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := at.load(data); err != nil {
+		return err
+	}
+	at.watch()
+	return nil
+}
+
+// SetFolder makes the open folder the one watched for new files besides
+// the folders of the rules.
+func (at *AutoTagger) SetFolder(dir string) {
+	at.mu.Lock()
+	changed := at.folder != dir
+	at.folder = dir
+	at.mu.Unlock()
+	if changed {
+		at.watch()
+	}
+}
+
+// tag gives path the tags of the rules matching it and tells whether it
+// got new ones.
+func (at *AutoTagger) tag(matchers []*matcher, path string) bool {
+	var tags []string
+	for _, m := range matchers {
+		ok, _, err := m.match(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Temporary files are often gone by the time they are noticed.
+			return false
+		}
+		if err != nil {
+			println("couldn't match tag rule", m.rule.Name, "on", path+":", err.Error())
+			continue
+		}
+		if ok {
+			tags = append(tags, m.rule.Tags...)
+		}
+	}
+	return len(tags) > 0 && at.tagManager.addTags(path, tags)
+}
+
+func (at *AutoTagger) fileAdded(path string) {
+	at.mu.Lock()
+	matchers := at.matchers
+	at.mu.Unlock()
+	// Files moved in may carry tags set elsewhere.
+	indexed := at.tagManager.index(path)
+	if (at.tag(matchers, path) || indexed) && at.Tagged != nil {
+		at.Tagged(path)
+	}
+}
+
+// Apply runs the rules over the files below roots and returns how many
+// got new tags.
+func (at *AutoTagger) Apply(ctx context.Context, roots []string, progress func(float64)) (int, error) {
+	at.mu.Lock()
+	matchers := at.matchers
+	at.mu.Unlock()
+
+	var paths []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable folders are skipped instead of ending the run.
+				if d != nil && d.IsDir() && path != root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	tagged := 0
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return tagged, err
+		}
+		if at.tag(matchers, path) {
+			tagged++
+		}
+		if progress != nil {
+			progress(float64(i+1) / float64(len(paths)))
+		}
+	}
+	return tagged, nil
+}
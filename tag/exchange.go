@@ -0,0 +1,307 @@
+package tag
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Format is a way to move tags in and out of the app.
+type Format string
+
+const (
+	// FormatJSON is a file in our own format, which keeps the colors too.
+	FormatJSON Format = "json"
+	// FormatCSV is a file with a path and a comma separated list of tags
+	// on each line.
+	FormatCSV Format = "csv"
+	// FormatXMP is the dc:subject keywords of the XMP sidecars of images.
+	FormatXMP Format = "xmp"
+	// FormatXattr is the user.xdg.tags attribute of the files, which can
+	// only be imported, from files tagged by other tools or copied from
+	// another machine.
+	FormatXattr Format = "xattr"
+)
+
+// exchangeFormat marks the JSON files written by Export.
+const exchangeFormat = "atilgan-tags"
+
+// Exchange describes an import or an export.
+type Exchange struct {
+	Format Format
+	// File is the JSON or CSV file.
+	File string
+	// Root limits the export to the files below it, and is what relative
+	// paths are resolved against on import. XMP and xattr work on the tree
+	// below it.
+	Root string
+	// Relative writes the paths relative to Root.
+	Relative bool
+}
+
+type exchangeFile struct {
+	Format  string              `json:"format"`
+	Version int                 `json:"version"`
+	Files   map[string][]string `json:"files"`
+	Colors  map[string]string   `json:"colors,omitempty"`
+}
+
+// Check reports what keeps the exchange from being run.
+func (ex Exchange) Check() error {
+	if ex.Format == FormatJSON || ex.Format == FormatCSV {
+		if !filepath.IsAbs(ex.File) {
+			return errors.New("Choose a file")
+		}
+	}
+	if ex.Format == FormatXMP || ex.Format == FormatXattr || ex.Relative {
+		if !filepath.IsAbs(ex.Root) {
+			return errors.New("Choose a folder")
+		}
+	}
+	if ex.Root != "" {
+		if info, err := os.Stat(ex.Root); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s is not a folder", ex.Root)
+		}
+	}
+	return nil
+}
+
+// below returns the tagged paths below root, all of them for an empty root.
+func (tm *TagManager) below(root string) map[string][]string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	paths := make(map[string][]string)
+	for path, tags := range tm.Tags {
+		if root == "" || path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			paths[path] = slices.Clone(tags)
+		}
+	}
+	return paths
+}
+
+// Export writes the tags out and returns how many files it wrote them for.
+func (tm *TagManager) Export(ctx context.Context, ex Exchange, progress func(float64)) (int, error) {
+	if err := ex.Check(); err != nil {
+		return 0, err
+	}
+	root := ex.Root
+	if root != "" {
+		root = filepath.Clean(root)
+	}
+	paths := tm.below(root)
+	if ex.Format == FormatXMP {
+		return exportXMP(ctx, paths, progress)
+	}
+
+	files := make(map[string][]string, len(paths))
+	for path, tags := range paths {
+		if ex.Relative {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return 0, err
+			}
+			path = filepath.ToSlash(rel)
+		}
+		files[path] = tags
+	}
+
+	var data []byte
+	var err error
+	switch ex.Format {
+	case FormatJSON:
+		file := exchangeFile{Format: exchangeFormat, Version: 1, Files: files, Colors: make(map[string]string)}
+		for _, tags := range files {
+			for _, t := range tags {
+				if color := tm.TagColor(t); color != "" {
+					file.Colors[t] = color
+				}
+			}
+		}
+		data, err = json.MarshalIndent(file, "", "  ")
+	case FormatCSV:
+		data, err = marshalCSV(files)
+	default:
+		return 0, fmt.Errorf("Tags can't be exported as %s", ex.Format)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if progress != nil {
+		progress(1)
+	}
+	return len(files), os.WriteFile(ex.File, data, 0644)
+}
+
+func marshalCSV(files map[string][]string) ([]byte, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"path", "tags"})
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		w.Write([]string{path, strings.Join(files[path], ",")})
+	}
+	w.Flush()
+	return []byte(b.String()), w.Error()
+}
+
+// Import adds the tags read from ex to the files and returns how many files
+// it tagged or found tagged. Tags the files already carry are kept.
+func (tm *TagManager) Import(ctx context.Context, ex Exchange, progress func(float64)) (int, error) {
+	if err := ex.Check(); err != nil {
+		return 0, err
+	}
+	var files map[string][]string
+	var err error
+	switch ex.Format {
+	case FormatJSON, FormatCSV:
+		files, err = tm.readExchangeFile(ex)
+	case FormatXMP:
+		files, err = readXMPTree(ctx, ex.Root)
+	case FormatXattr:
+		files, err = readXattrTree(ctx, ex.Root)
+	default:
+		err = fmt.Errorf("Tags can't be imported from %s", ex.Format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	done := 0
+	for path, tags := range files {
+		if err := ctx.Err(); err != nil {
+			return imported, err
+		}
+		// Files copied with their attributes carry their tags already, they
+		// only need to be indexed.
+		changed := tm.index(path)
+		if ex.Format != FormatXattr {
+			tags = slices.DeleteFunc(tags, func(t string) bool { return CheckName(t) != nil })
+			if _, err := os.Lstat(path); err == nil && len(tags) > 0 && tm.addTags(path, tags) {
+				changed = true
+			}
+		}
+		if changed {
+			imported++
+		}
+		done++
+		if progress != nil {
+			progress(float64(done) / float64(len(files)))
+		}
+	}
+	return imported, nil
+}
+
+// readExchangeFile reads a JSON or CSV file, resolving its relative paths
+// against the root, or the folder of the file when there is none.
+func (tm *TagManager) readExchangeFile(ex Exchange) (map[string][]string, error) {
+	data, err := os.ReadFile(ex.File)
+	if err != nil {
+		return nil, err
+	}
+	var files map[string][]string
+	if ex.Format == FormatJSON {
+		var file exchangeFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+		if file.Format != exchangeFormat {
+			return nil, fmt.Errorf("%s is not a tags export", filepath.Base(ex.File))
+		}
+		files = file.Files
+		tm.importColors(file.Colors)
+	} else if files, err = unmarshalCSV(data); err != nil {
+		return nil, err
+	}
+
+	root := ex.Root
+	if root == "" {
+		root = filepath.Dir(ex.File)
+	}
+	resolved := make(map[string][]string, len(files))
+	for path, tags := range files {
+		path = filepath.FromSlash(path)
+		if !filepath.IsAbs(path) {
+			// Paths leaving the root are not followed.
+			if !filepath.IsLocal(path) {
+				continue
+			}
+			path = filepath.Join(root, path)
+		}
+		resolved[filepath.Clean(path)] = tags
+	}
+	return resolved, nil
+}
+
+// importColors gives the tags without a color the one they had in the
+// export.
+func (tm *TagManager) importColors(colors map[string]string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	changed := false
+	for t, color := range colors {
+		if _, ok := tm.Colors[t]; ok {
+			continue
+		}
+		if _, _, _, ok := RGB(color); ok {
+			tm.Colors[t] = color
+			changed = true
+		}
+	}
+	if changed {
+		tm.saveSettings()
+	}
+}
+
+func unmarshalCSV(data []byte) (map[string][]string, error) {
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.FieldsPerRecord = -1
+	files := make(map[string][]string)
+	for line := 0; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 0 && len(record) > 0 && record[0] == "path" {
+			continue
+		}
+		if len(record) < 2 || record[0] == "" {
+			continue
+		}
+		files[record[0]] = append(files[record[0]], SplitTags(strings.Join(record[1:], ","))...)
+	}
+}
+
+// readXattrTree returns the tags in the attributes of everything below
+// root.
+func readXattrTree(ctx context.Context, root string) (map[string][]string, error) {
+	files := make(map[string][]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable folders are skipped instead of ending the import.
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if tags, err := readXattr(path); err == nil && len(tags) > 0 {
+			files[path] = tags
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,84 @@
+package tag
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Tags form a hierarchy through "/", like clients/acme/invoices, which is
+// browsed like folders in tags://.
+
+// IsWithin reports whether tag is parent or one of the tags below it.
+func IsWithin(tag, parent string) bool {
+	return tag == parent || strings.HasPrefix(tag, parent+"/")
+}
+
+// IsTag reports whether name is a tag in use or a level above one, which
+// tags:// shows as it is even when it looks like a query.
+func (tm *TagManager) IsTag(name string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for tag := range tm.byTag {
+		if IsWithin(tag, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parent returns the tag above tag, empty for a top level one.
+func Parent(tag string) string {
+	if i := strings.LastIndex(tag, "/"); i >= 0 {
+		return tag[:i]
+	}
+	return ""
+}
+
+// Base returns the last level of tag.
+func Base(tag string) string {
+	return tag[strings.LastIndex(tag, "/")+1:]
+}
+
+// ChildTags returns the tags one level below parent, or the top level tags
+// for an empty parent. Levels no file is tagged with directly are included.
+func (tm *TagManager) ChildTags(parent string) []string {
+	prefix := ""
+	if parent != "" {
+		prefix = parent + "/"
+	}
+	var children []string
+	for _, tag := range tm.GetAllTags() {
+		rest, ok := strings.CutPrefix(tag, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		child := prefix + strings.Split(rest, "/")[0]
+		if !slices.Contains(children, child) {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// GetPathsBelowTag returns the paths tagged with tag or a tag below it.
+func (tm *TagManager) GetPathsBelowTag(tag string) []string {
+	return tm.GetPathsForQuery(&Query{tag: tag, below: true})
+}
+
+// IncludeSubtags reports whether a tag view lists the files of the tags
+// below it too.
+func (tm *TagManager) IncludeSubtags() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.includeSubtags
+}
+
+func (tm *TagManager) SetIncludeSubtags(include bool) {
+	tm.mu.Lock()
+	tm.includeSubtags = include
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+}
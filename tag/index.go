@@ -0,0 +1,45 @@
+package tag
+
+// setPath gives path tags in the index, removing it for none, and has the
+// change saved. The file at path is taken as the one tagged.
+func (tm *TagManager) setPath(path string, tags []string) {
+	for _, tag := range tm.Tags[path] {
+		delete(tm.byTag[tag], path)
+		if len(tm.byTag[tag]) == 0 {
+			delete(tm.byTag, tag)
+		}
+	}
+	delete(tm.ids, path)
+	if len(tags) == 0 {
+		delete(tm.Tags, path)
+	} else {
+		tm.Tags[path] = tags
+		tm.indexPath(path, tags)
+		if id, ok := statID(path); ok {
+			tm.ids[path] = id
+		}
+	}
+	tm.dirty[path] = true
+	tm.store.Schedule(tm.flush)
+}
+
+func (tm *TagManager) indexPath(path string, tags []string) {
+	for _, tag := range tags {
+		if tm.byTag[tag] == nil {
+			tm.byTag[tag] = make(map[string]struct{})
+		}
+		tm.byTag[tag][path] = struct{}{}
+	}
+}
+
+// saveSettings has a change to the colors, pinned queries or options
+// saved.
+func (tm *TagManager) saveSettings() {
+	tm.settingsDirty = true
+	tm.store.Schedule(tm.flush)
+}
+
+// Flush writes the changes waiting to be saved right away.
+func (tm *TagManager) Flush() {
+	tm.store.Flush()
+}
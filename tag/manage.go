@@ -0,0 +1,247 @@
+package tag
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Color struct {
+	Name string
+	Hex  string
+}
+
+// Palette lists the colors a tag can be given.
+var Palette = []Color{
+	{"Red", "#e01b24"},
+	{"Orange", "#ff7800"},
+	{"Yellow", "#f6d32d"},
+	{"Green", "#33d17a"},
+	{"Blue", "#3584e4"},
+	{"Purple", "#9141ac"},
+	{"Brown", "#986a44"},
+	{"Gray", "#9a9996"},
+}
+
+// RGB returns the components of a #rrggbb color between 0 and 1.
+func RGB(hex string) (r, g, b float64, ok bool) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(hex) != 7 {
+		return 0, 0, 0, false
+	}
+	return float64(value>>16&0xff) / 255, float64(value>>8&0xff) / 255, float64(value&0xff) / 255, true
+}
+
+// CheckName returns why name can't be used as a tag, if it can't.
+func CheckName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("A tag needs a name")
+	}
+	if strings.Contains(name, ",") {
+		return errors.New("A tag can't contain a comma")
+	}
+	if slices.Contains(strings.Split(name, "/"), "") {
+		return errors.New("A tag can't have an empty level")
+	}
+	return nil
+}
+
+// renameTags passes each of tags through rename, dropping the ones it
+// returns empty for and leaving no tag twice.
+func renameTags(tags []string, rename func(tag string) string) []string {
+	var result []string
+	for _, tag := range tags {
+		if tag = rename(tag); tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// retag passes the tags of every path through rename.
+func (tm *TagManager) retag(rename func(tag string) string) {
+	var paths []string
+	for path, tags := range tm.Tags {
+		if slices.ContainsFunc(tags, func(tag string) bool { return rename(tag) != tag }) {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		tm.setTags(path, renameTags(tm.getTags(path), rename))
+	}
+}
+
+// RenameTag renames oldTag on every file carrying it, along with the tags
+// below it. Renaming to a tag that is already used merges the two.
+func (tm *TagManager) RenameTag(oldTag, newTag string) error {
+	newTag = strings.TrimSpace(newTag)
+	if err := CheckName(newTag); err != nil {
+		return err
+	}
+	if IsWithin(newTag, oldTag) {
+		return errors.New("A tag can't be moved below itself")
+	}
+	rename := func(tag string) string {
+		if IsWithin(tag, oldTag) {
+			return newTag + strings.TrimPrefix(tag, oldTag)
+		}
+		return tag
+	}
+	tm.mu.Lock()
+	tm.retag(rename)
+	renamed := make(map[string]string)
+	for tag, color := range tm.Colors {
+		if newName := rename(tag); newName != tag {
+			renamed[newName] = color
+			delete(tm.Colors, tag)
+		}
+	}
+	for tag, color := range renamed {
+		if _, has := tm.Colors[tag]; !has {
+			tm.Colors[tag] = color
+		}
+	}
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+	return nil
+}
+
+// MergeTags replaces each of tags with into on every file. into keeps its
+// own color, or takes the first one of tags that has one.
+func (tm *TagManager) MergeTags(tags []string, into string) error {
+	into = strings.TrimSpace(into)
+	if err := CheckName(into); err != nil {
+		return err
+	}
+	tm.mu.Lock()
+	tm.retag(func(tag string) string {
+		if slices.Contains(tags, tag) {
+			return into
+		}
+		return tag
+	})
+	for _, tag := range tags {
+		if color, ok := tm.Colors[tag]; ok && tag != into {
+			if _, has := tm.Colors[into]; !has {
+				tm.Colors[into] = color
+			}
+			delete(tm.Colors, tag)
+		}
+	}
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+	return nil
+}
+
+// DeleteTag removes tag and the tags below it from every file.
+func (tm *TagManager) DeleteTag(tag string) {
+	tm.mu.Lock()
+	tm.retag(func(t string) string {
+		if IsWithin(t, tag) {
+			return ""
+		}
+		return t
+	})
+	for t := range tm.Colors {
+		if IsWithin(t, tag) {
+			delete(tm.Colors, t)
+		}
+	}
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+}
+
+// SetTagColor gives tag one of the Palette colors, or none for "".
+func (tm *TagManager) SetTagColor(tag, hex string) {
+	tm.mu.Lock()
+	if hex == "" {
+		delete(tm.Colors, tag)
+	} else {
+		tm.Colors[tag] = hex
+	}
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+}
+
+func (tm *TagManager) TagColor(tag string) string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.Colors[tag]
+}
+
+// PathColors returns the colors to mark path with, the color of the tag
+// itself for an entry of tags://. It is called for every row drawn, so it
+// reads the index rather than the file, Rescan keeps it current.
+func (tm *TagManager) PathColors(path string) []string {
+	if tag, ok := strings.CutPrefix(path, "tags://"); ok {
+		if color := tm.TagColor(tag); color != "" {
+			return []string{color}
+		}
+		return nil
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	var colors []string
+	for _, tag := range tm.Tags[path] {
+		if color, ok := tm.Colors[tag]; ok {
+			colors = append(colors, color)
+		}
+	}
+	return colors
+}
+
+// UsedColors returns the colors of the tags in use, once each, in palette
+// order.
+func (tm *TagManager) UsedColors() []string {
+	used := make(map[string]bool)
+	for _, tag := range tm.GetAllTags() {
+		if color := tm.TagColor(tag); color != "" {
+			used[color] = true
+		}
+	}
+	var colors []string
+	for _, color := range Palette {
+		if used[color.Hex] {
+			colors = append(colors, color.Hex)
+		}
+	}
+	return colors
+}
+
+// PinQuery adds query to the sidebar.
+func (tm *TagManager) PinQuery(query string) {
+	tm.mu.Lock()
+	if slices.Contains(tm.Pinned, query) {
+		tm.mu.Unlock()
+		return
+	}
+	tm.Pinned = append(tm.Pinned, query)
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+}
+
+func (tm *TagManager) UnpinQuery(query string) {
+	tm.mu.Lock()
+	tm.Pinned = slices.DeleteFunc(tm.Pinned, func(pinned string) bool { return pinned == query })
+	tm.saveSettings()
+	tm.mu.Unlock()
+	tm.changed()
+}
+
+func (tm *TagManager) PinnedQueries() []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return slices.Clone(tm.Pinned)
+}
+
+func (tm *TagManager) changed() {
+	if tm.Changed != nil {
+		tm.Changed()
+	}
+}
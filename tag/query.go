@@ -0,0 +1,188 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Query is a boolean expression over tags, as used in tags:// paths:
+//
+//	work+urgent      files tagged both work and urgent
+//	photos|scans     files tagged photos, scans or both
+//	project-!archived, !archived
+//	                 files tagged project but not archived, or not archived
+//
+// + binds tighter than |, and parentheses group. A - is part of a tag name
+// unless a ! follows it. A \ keeps the character after it in the tag name,
+// as in c\+\+.
+type Query struct {
+	op       byte
+	tag      string
+	below    bool
+	operands []*Query
+}
+
+const (
+	opTag = 0
+	opAnd = '+'
+	opOr  = '|'
+	opNot = '!'
+)
+
+// IsQuery reports whether text combines tags rather than naming one. Tags
+// may hold these characters too, see TagManager.IsTag.
+func IsQuery(text string) bool {
+	return strings.ContainsAny(text, "+|!()")
+}
+
+func ParseQuery(text string) (*Query, error) {
+	p := &queryParser{text: text}
+	q, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, fmt.Errorf("Unexpected \"%c\" in the query", p.text[p.pos])
+	}
+	return q, nil
+}
+
+// Match reports whether a file with tags is selected by the query.
+func (q *Query) Match(tags []string) bool {
+	switch q.op {
+	case opAnd:
+		for _, operand := range q.operands {
+			if !operand.Match(tags) {
+				return false
+			}
+		}
+		return true
+	case opOr:
+		for _, operand := range q.operands {
+			if operand.Match(tags) {
+				return true
+			}
+		}
+		return false
+	case opNot:
+		return !q.operands[0].Match(tags)
+	case opTag:
+		if q.below {
+			return slices.ContainsFunc(tags, func(tag string) bool { return IsWithin(tag, q.tag) })
+		}
+		return slices.Contains(tags, q.tag)
+	default:
+		return false
+	}
+}
+
+type queryParser struct {
+	text string
+	pos  int
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *queryParser) or() (*Query, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{op: opOr, operands: []*Query{first}}
+	for p.skipSpace(); p.pos < len(p.text) && p.text[p.pos] == '|'; p.skipSpace() {
+		p.pos++
+		operand, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		q.operands = append(q.operands, operand)
+	}
+	if len(q.operands) == 1 {
+		return first, nil
+	}
+	return q, nil
+}
+
+func (p *queryParser) and() (*Query, error) {
+	first, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{op: opAnd, operands: []*Query{first}}
+	for p.skipSpace(); p.pos < len(p.text); p.skipSpace() {
+		negate := false
+		switch {
+		case p.text[p.pos] == '+':
+			p.pos++
+		case strings.HasPrefix(p.text[p.pos:], "-!"):
+			p.pos += 2
+			negate = true
+		default:
+			if len(q.operands) == 1 {
+				return first, nil
+			}
+			return q, nil
+		}
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			operand = &Query{op: opNot, operands: []*Query{operand}}
+		}
+		q.operands = append(q.operands, operand)
+	}
+	if len(q.operands) == 1 {
+		return first, nil
+	}
+	return q, nil
+}
+
+func (p *queryParser) not() (*Query, error) {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return nil, errors.New("The query ends too early")
+	}
+	switch p.text[p.pos] {
+	case '!':
+		p.pos++
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &Query{op: opNot, operands: []*Query{operand}}, nil
+	case '(':
+		p.pos++
+		q, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.text) || p.text[p.pos] != ')' {
+			return nil, errors.New("A \"(\" in the query isn't closed")
+		}
+		p.pos++
+		return q, nil
+	}
+	start := p.pos
+	var builder strings.Builder
+	for p.pos < len(p.text) && !strings.ContainsRune("+|!()", rune(p.text[p.pos])) && !strings.HasPrefix(p.text[p.pos:], "-!") {
+		if p.text[p.pos] == '\\' && p.pos+1 < len(p.text) {
+			p.pos++
+		}
+		builder.WriteByte(p.text[p.pos])
+		p.pos++
+	}
+	name := strings.TrimSpace(builder.String())
+	if name == "" {
+		return nil, fmt.Errorf("A tag is missing at \"%s\"", p.text[start:])
+	}
+	return &Query{tag: name}, nil
+}
@@ -0,0 +1,45 @@
+package tag
+
+import "github.com/MrSametBurgazoglu/atilgan/types"
+
+// QueryPath lists the files matching a tags:// query like work+urgent.
+type QueryPath struct {
+	text       string
+	query      *Query
+	err        error
+	tagManager *TagManager
+}
+
+func NewQueryPath(text string, tagManager *TagManager) *QueryPath {
+	query, err := ParseQuery(text)
+	return &QueryPath{
+		text:       text,
+		query:      query,
+		err:        err,
+		tagManager: tagManager,
+	}
+}
+
+func (q *QueryPath) GetItems() []*types.ListItem {
+	if q.err != nil {
+		return nil
+	}
+	return listItems(q.tagManager, q.tagManager.GetPathsForQuery(q.query))
+}
+
+func (q *QueryPath) GetPath() string {
+	return "tags://" + q.text
+}
+
+func (q *QueryPath) GetParentPath() string {
+	return "tags://"
+}
+
+func (q *QueryPath) GetName() string {
+	return q.text
+}
+
+// Err returns why the query couldn't be read, if it couldn't.
+func (q *QueryPath) Err() error {
+	return q.err
+}
@@ -0,0 +1,202 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MrSametBurgazoglu/atilgan/columns"
+)
+
+// contentLimit is how much of a file a content pattern is matched against.
+const contentLimit = 4 << 20
+
+// Rule tags the files that meet all of its conditions. Conditions left
+// empty or zero are ignored.
+type Rule struct {
+	Name     string `json:"name"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Glob is matched against the whole path, or only the name when it has
+	// no slash. A leading ~ stands for the home folder.
+	Glob string `json:"glob,omitempty"`
+	// Extensions are given without the dot.
+	Extensions []string `json:"extensions,omitempty"`
+	// MimeType is a type such as application/pdf or a family such as
+	// image/*.
+	MimeType   string `json:"mime_type,omitempty"`
+	MinSize    int64  `json:"min_size,omitempty"`
+	MaxSize    int64  `json:"max_size,omitempty"`
+	MinAgeDays int    `json:"min_age_days,omitempty"`
+	MaxAgeDays int    `json:"max_age_days,omitempty"`
+	// Content is a regular expression searched in the text of the file.
+	Content string   `json:"content,omitempty"`
+	Tags    []string `json:"tags"`
+}
+
+// Validate reports what keeps the rule from being saved.
+func (r Rule) Validate() error {
+	_, err := newMatcher(r)
+	return err
+}
+
+// Check tells whether the rule matches the file at path and, when it
+// doesn't, which condition failed.
+func (r Rule) Check(path string) (bool, string, error) {
+	m, err := newMatcher(r)
+	if err != nil {
+		return false, "", err
+	}
+	return m.match(path)
+}
+
+// WatchedFolder returns the folder new files have to appear in to match
+// the glob, empty when the glob doesn't name one.
+func (r Rule) WatchedFolder() string {
+	pattern := expandHome(r.Glob)
+	if !filepath.IsAbs(pattern) {
+		return ""
+	}
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		pattern = pattern[:i]
+	}
+	return filepath.Dir(pattern)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// matcher is a rule ready to be matched against many files.
+type matcher struct {
+	rule    Rule
+	glob    string
+	content *regexp.Regexp
+}
+
+func newMatcher(r Rule) (*matcher, error) {
+	m := &matcher{rule: r, glob: expandHome(strings.TrimSpace(r.Glob))}
+	if strings.TrimSpace(r.Name) == "" {
+		return nil, errors.New("A rule needs a name")
+	}
+	if len(r.Tags) == 0 {
+		return nil, errors.New("A rule needs tags to apply")
+	}
+	for _, t := range r.Tags {
+		if err := CheckName(t); err != nil {
+			return nil, err
+		}
+	}
+	if m.glob == "" && len(r.Extensions) == 0 && r.MimeType == "" && r.MinSize == 0 && r.MaxSize == 0 &&
+		r.MinAgeDays == 0 && r.MaxAgeDays == 0 && r.Content == "" {
+		return nil, errors.New("A rule needs at least one condition")
+	}
+	if _, err := filepath.Match(m.glob, ""); err != nil {
+		return nil, fmt.Errorf("The glob %s is not valid", r.Glob)
+	}
+	if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+		return nil, errors.New("The smallest size is above the largest")
+	}
+	if r.MaxAgeDays > 0 && r.MinAgeDays > r.MaxAgeDays {
+		return nil, errors.New("The lowest age is above the highest")
+	}
+	if r.Content != "" {
+		content, err := regexp.Compile(r.Content)
+		if err != nil {
+			return nil, fmt.Errorf("The content pattern is not valid: %w", err)
+		}
+		m.content = content
+	}
+	return m, nil
+}
+
+// match checks the conditions from the cheapest to the content.
+func (m *matcher) match(path string) (bool, string, error) {
+	r := m.rule
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, "", err
+	}
+	if !info.Mode().IsRegular() {
+		return false, "Only files are tagged by rules", nil
+	}
+	if m.glob != "" {
+		name := path
+		if !strings.ContainsRune(m.glob, filepath.Separator) {
+			name = filepath.Base(path)
+		}
+		if ok, _ := filepath.Match(m.glob, name); !ok {
+			return false, fmt.Sprintf("The path doesn't match %s", r.Glob), nil
+		}
+	}
+	if len(r.Extensions) > 0 {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+		if !slices.ContainsFunc(r.Extensions, func(e string) bool { return strings.ToLower(strings.TrimPrefix(e, ".")) == ext }) {
+			return false, fmt.Sprintf("The extension isn't one of %s", strings.Join(r.Extensions, ", ")), nil
+		}
+	}
+	if r.MinSize > 0 && info.Size() < r.MinSize {
+		return false, "The file is smaller than the smallest size", nil
+	}
+	if r.MaxSize > 0 && info.Size() > r.MaxSize {
+		return false, "The file is larger than the largest size", nil
+	}
+	age := time.Since(info.ModTime())
+	if r.MinAgeDays > 0 && age < time.Duration(r.MinAgeDays)*24*time.Hour {
+		return false, fmt.Sprintf("The file was modified less than %d days ago", r.MinAgeDays), nil
+	}
+	if r.MaxAgeDays > 0 && age > time.Duration(r.MaxAgeDays)*24*time.Hour {
+		return false, fmt.Sprintf("The file was modified more than %d days ago", r.MaxAgeDays), nil
+	}
+	mimeType := ""
+	if r.MimeType != "" || m.content != nil {
+		mimeType = columns.DetectMimeType(path, info)
+	}
+	if r.MimeType != "" {
+		family, isFamily := strings.CutSuffix(r.MimeType, "/*")
+		if isFamily && !strings.HasPrefix(mimeType, family+"/") || !isFamily && mimeType != r.MimeType {
+			return false, fmt.Sprintf("The type is %s, not %s", mimeType, r.MimeType), nil
+		}
+	}
+	if m.content != nil {
+		text, err := readText(path, mimeType)
+		if err != nil {
+			return false, "", err
+		}
+		if !m.content.Match(text) {
+			return false, fmt.Sprintf("The content doesn't match %s", r.Content), nil
+		}
+	}
+	return true, "", nil
+}
+
+// readText returns the start of the text of a file. The text of PDFs is
+// extracted with pdftotext, other files are read as they are.
+func readText(path, mimeType string) ([]byte, error) {
+	if mimeType == "application/pdf" {
+		text, err := exec.Command("pdftotext", "-q", "-l", "20", path, "-").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run pdftotext: %w", err)
+		}
+		return text, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, contentLimit))
+}
@@ -0,0 +1,178 @@
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+)
+
+// tagsFileVersion 2 keeps tags in extended attributes with tags.json as
+// their index. Version 1 files were a plain map of paths to tags.
+const tagsFileVersion = 2
+
+type tagsFile struct {
+	Version        int                 `json:"version"`
+	Paths          map[string][]string `json:"paths"`
+	IDs            map[string]fileID   `json:"ids,omitempty"`
+	Colors         map[string]string   `json:"colors,omitempty"`
+	Pinned         []string            `json:"pinned,omitempty"`
+	IncludeSubtags bool                `json:"include_subtags,omitempty"`
+}
+
+// parseTagsFile reads tags.json, telling whether it was a version 1 file.
+func parseTagsFile(data []byte) (*tagsFile, bool, error) {
+	if data == nil {
+		return &tagsFile{Version: tagsFileVersion, Paths: make(map[string][]string)}, false, nil
+	}
+	file := &tagsFile{}
+	if err := json.Unmarshal(data, file); err == nil && file.Version >= tagsFileVersion {
+		if file.Paths == nil {
+			file.Paths = make(map[string][]string)
+		}
+		return file, false, nil
+	}
+	file = &tagsFile{Version: tagsFileVersion}
+	if err := json.Unmarshal(data, &file.Paths); err != nil {
+		return nil, false, err
+	}
+	if file.Paths == nil {
+		file.Paths = make(map[string][]string)
+	}
+	return file, true, nil
+}
+
+func (tm *TagManager) load() error {
+	data, err := tm.store.Read()
+	if err != nil {
+		return err
+	}
+	file, legacy, err := parseTagsFile(data)
+	if err != nil {
+		return err
+	}
+	tm.adopt(file)
+	if data == nil || legacy {
+		if legacy {
+			tm.migrate()
+		}
+		tm.settingsDirty = true
+		tm.flushLocked()
+	}
+	return nil
+}
+
+// migrate copies the tags of a version 1 file to the attributes of the
+// files, merged with tags other tools already set there. Paths that no
+// longer exist are dropped.
+func (tm *TagManager) migrate() {
+	for path, tags := range tm.Tags {
+		if _, err := os.Lstat(path); err != nil {
+			tm.setPath(path, nil)
+			continue
+		}
+		existing, err := readXattr(path)
+		if err != nil && !errors.Is(err, errNoAttr) {
+			tm.dirty[path] = true
+			continue
+		}
+		for _, tag := range existing {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		tm.setPath(path, tags)
+		if err := writeXattr(path, tags); err != nil {
+			println("couldn't move tags of", path, "to extended attributes:", err.Error())
+		}
+	}
+}
+
+// adopt makes file the state of the manager.
+func (tm *TagManager) adopt(file *tagsFile) {
+	tm.Tags = file.Paths
+	tm.ids = file.IDs
+	if tm.ids == nil {
+		tm.ids = make(map[string]fileID)
+	}
+	tm.byTag = make(map[string]map[string]struct{})
+	for path, tags := range tm.Tags {
+		tm.indexPath(path, tags)
+	}
+	tm.Colors = file.Colors
+	if tm.Colors == nil {
+		tm.Colors = make(map[string]string)
+	}
+	tm.Pinned = file.Pinned
+	tm.includeSubtags = file.IncludeSubtags
+}
+
+// merge applies the changes of this instance that aren't saved yet to
+// data, the tags.json another instance may have written since, and takes
+// the result as the current state.
+func (tm *TagManager) merge(data []byte) *tagsFile {
+	file, _, err := parseTagsFile(data)
+	if err != nil {
+		println("tags.json can't be read, writing it again:", err.Error())
+		file = &tagsFile{Version: tagsFileVersion, Paths: tm.Tags}
+	}
+	if file.IDs == nil {
+		file.IDs = make(map[string]fileID)
+	}
+	for path := range tm.dirty {
+		if tags, ok := tm.Tags[path]; ok {
+			file.Paths[path] = tags
+		} else {
+			delete(file.Paths, path)
+		}
+		if id, ok := tm.ids[path]; ok {
+			file.IDs[path] = id
+		} else {
+			delete(file.IDs, path)
+		}
+	}
+	if tm.settingsDirty || err != nil {
+		file.Colors = tm.Colors
+		file.Pinned = tm.Pinned
+		file.IncludeSubtags = tm.includeSubtags
+	}
+	tm.adopt(file)
+	return file
+}
+
+func (tm *TagManager) flush() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.flushLocked()
+}
+
+func (tm *TagManager) flushLocked() {
+	err := tm.store.Update(func(data []byte) ([]byte, error) {
+		return json.MarshalIndent(tm.merge(data), "", "  ")
+	})
+	if err != nil {
+		// The changes stay dirty and go out with the next write.
+		println("couldn't save tags:", err.Error())
+		return
+	}
+	clear(tm.dirty)
+	tm.settingsDirty = false
+}
+
+// reload takes in the tags.json another instance wrote, keeping the changes
+// of this one that aren't saved yet.
+func (tm *TagManager) reload() {
+	data, err := tm.store.Read()
+	if err != nil {
+		println("couldn't reload tags:", err.Error())
+		return
+	}
+	tm.mu.Lock()
+	if _, _, err := parseTagsFile(data); err == nil {
+		tm.merge(data)
+	}
+	tm.mu.Unlock()
+	if tm.Reloaded != nil {
+		tm.Reloaded()
+	}
+}
@@ -1,117 +1,272 @@
 package tag
 
 import (
-	"encoding/json"
-	"os"
+	"errors"
+	"maps"
 	"path/filepath"
 	"slices"
 	"sort"
+	"strings"
+	"sync"
+
+	"github.com/MrSametBurgazoglu/atilgan/json_store"
 )
 
+// TagManager keeps tags in the user.xdg.tags attribute of files so that
+// they follow the file when it is moved, by this app or another one. Tags
+// lists every tagged path the app knows of. For files on filesystems
+// without extended attributes it is the only place their tags are kept.
 type TagManager struct {
-	Tags   map[string][]string
-	dbPath string
+	Tags map[string][]string
+	// Colors maps tags to their Palette color.
+	Colors map[string]string
+	// Pinned lists the queries shown in the sidebar.
+	Pinned         []string
+	includeSubtags bool
+	mu             sync.Mutex
+
+	// byTag indexes Tags by tag.
+	byTag map[string]map[string]struct{}
+	// ids holds which file each path was when it was tagged.
+	ids   map[string]fileID
+	store *json_store.Store
+	// dirty holds the paths changed since tags.json was last written, and
+	// settingsDirty tells whether the colors or pinned queries were.
+	dirty         map[string]bool
+	settingsDirty bool
+
+	// Changed is called after tags are added, removed or edited.
+	Changed func()
+	// Reloaded is called, from another goroutine, after another instance
+	// changed tags.json.
+	Reloaded func()
 }
 
 func NewTagManager() (*TagManager, error) {
-	configDir, err := os.UserConfigDir()
+	store, err := json_store.Open("tags.json")
 	if err != nil {
 		return nil, err
 	}
-	dbPath := filepath.Join(configDir, "atilgan", "tags.json")
-
 	tm := &TagManager{
-		dbPath: dbPath,
 		Tags:   make(map[string][]string),
+		Colors: make(map[string]string),
+		byTag:  make(map[string]map[string]struct{}),
+		ids:    make(map[string]fileID),
+		store:  store,
+		dirty:  make(map[string]bool),
 	}
-
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-			return nil, err
-		}
-		if err := tm.save(); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := tm.load(); err != nil {
-			return nil, err
-		}
+	if err := tm.load(); err != nil {
+		return nil, err
+	}
+	store.Changed = tm.reload
+	if err := store.Watch(); err != nil {
+		println("couldn't watch tags.json:", err.Error())
 	}
 	return tm, nil
 }
 
-func (tm *TagManager) load() error {
-	data, err := os.ReadFile(tm.dbPath)
-	if err != nil {
-		return err
+// setIndex records tags as the tags of path.
+func (tm *TagManager) setIndex(path string, tags []string) {
+	if slices.Equal(tm.Tags[path], tags) {
+		return
 	}
-	return json.Unmarshal(data, &tm.Tags)
+	tm.setPath(path, tags)
 }
 
-func (tm *TagManager) save() error {
-	data, err := json.MarshalIndent(tm.Tags, "", "  ")
-	if err != nil {
-		return err
+// setTags stores tags on path, in tags.json alone when the file can't hold
+// the attribute.
+func (tm *TagManager) setTags(path string, tags []string) {
+	if err := writeXattr(path, tags); err != nil {
+		println("keeping tags of", path, "in tags.json:", err.Error())
 	}
-	return os.WriteFile(tm.dbPath, data, 0644)
+	tm.setIndex(path, tags)
 }
 
 func (tm *TagManager) AddTag(path string, tag string) {
-	tags, ok := tm.Tags[path]
-	if !ok {
-		tags = []string{}
+	tag = strings.TrimSpace(tag)
+	if CheckName(tag) != nil {
+		return
 	}
+	tm.mu.Lock()
+	tags := tm.getTags(path)
 	if slices.Contains(tags, tag) {
+		tm.mu.Unlock()
 		return
 	}
-	tm.Tags[path] = append(tags, tag)
-	tm.save()
+	tm.setTags(path, append(slices.Clone(tags), tag))
+	tm.mu.Unlock()
+	tm.changed()
 }
 
-func (tm *TagManager) RemoveTag(path string, tag string) {
-	tags, ok := tm.Tags[path]
-	if !ok {
-		return
+// addTags gives path the tags it doesn't carry yet, without calling
+// Changed, and tells whether it got any.
+func (tm *TagManager) addTags(path string, tags []string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	current := tm.getTags(path)
+	added := slices.Clone(current)
+	for _, tag := range tags {
+		if !slices.Contains(added, tag) {
+			added = append(added, tag)
+		}
+	}
+	if len(added) == len(current) {
+		return false
+	}
+	tm.setTags(path, added)
+	return true
+}
+
+// Rescan brings the index in line with the attributes of paths, which is
+// how files tagged by other tools or moved outside the app are found, and
+// tells whether that changed it.
+func (tm *TagManager) Rescan(paths []string) bool {
+	changed := false
+	for _, path := range paths {
+		if tm.index(path) {
+			changed = true
+		}
 	}
-	newTags := []string{}
-	for _, t := range tags {
-		if t != tag {
-			newTags = append(newTags, t)
+	return changed
+}
+
+// index brings the index in line with the attribute of path and tells
+// whether that changed its tags.
+func (tm *TagManager) index(path string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	known := tm.Tags[path]
+	tags, err := readXattr(path)
+	switch {
+	case err == nil:
+		id, ok := statID(path)
+		if !slices.Equal(known, tags) || (len(tags) > 0 && ok && tm.ids[path] != id) {
+			tm.setPath(path, tags)
+		}
+		return !slices.Equal(known, tags)
+	case errors.Is(err, errNoAttr) && len(known) > 0:
+		recorded, wasRecorded := tm.ids[path]
+		id, ok := statID(path)
+		if !wasRecorded || !ok {
+			return false
+		}
+		if recorded != id {
+			// Another file took the place of the tagged one.
+			tm.setPath(path, nil)
+			return true
 		}
+		// The attribute was dropped from the tagged file itself.
+		if err := writeXattr(path, known); err != nil {
+			println("couldn't restore the tags of", path+":", err.Error())
+		}
+		return false
+	default:
+		return false
 	}
-	tm.Tags[path] = newTags
-	tm.save()
+}
+
+func (tm *TagManager) RemoveTag(path string, tag string) {
+	tm.mu.Lock()
+	tags := tm.getTags(path)
+	if !slices.Contains(tags, tag) {
+		tm.mu.Unlock()
+		return
+	}
+	tm.setTags(path, slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return t == tag }))
+	tm.mu.Unlock()
+	tm.changed()
 }
 
 func (tm *TagManager) GetTags(path string) []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return slices.Clone(tm.getTags(path))
+}
+
+// getTags returns the tags in the attribute of path, or the ones in the
+// index when it has none. It changes nothing, the index is brought in line
+// by Rescan.
+func (tm *TagManager) getTags(path string) []string {
+	if tags, err := readXattr(path); err == nil {
+		return tags
+	}
 	return tm.Tags[path]
 }
 
+// Move carries the tags known for oldPaths, and for everything below them,
+// over to newPaths. The attributes move with the files themselves.
+func (tm *TagManager) Move(oldPaths, newPaths []string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	moved := make(map[string][]string)
+	for path, tags := range tm.Tags {
+		for i, oldPath := range oldPaths {
+			if path != oldPath && !strings.HasPrefix(path, oldPath+string(filepath.Separator)) {
+				continue
+			}
+			tm.setPath(path, nil)
+			moved[newPaths[i]+strings.TrimPrefix(path, oldPath)] = tags
+			break
+		}
+	}
+	for path, tags := range moved {
+		tm.setPath(path, tags)
+	}
+}
+
+// GetPathsForTag returns the paths tagged with tag.
 func (tm *TagManager) GetPathsForTag(tag string) []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return slices.Sorted(maps.Keys(tm.byTag[tag]))
+}
+
+// CountForTag returns how many paths are tagged with tag.
+func (tm *TagManager) CountForTag(tag string) int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return len(tm.byTag[tag])
+}
+
+// GetPathsForQuery returns the tagged paths that match query.
+func (tm *TagManager) GetPathsForQuery(query *Query) []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if query.op == opTag && !query.below {
+		return slices.Sorted(maps.Keys(tm.byTag[query.tag]))
+	}
+	if query.op == opTag {
+		paths := make(map[string]struct{})
+		for tag, tagPaths := range tm.byTag {
+			if IsWithin(tag, query.tag) {
+				for path := range tagPaths {
+					paths[path] = struct{}{}
+				}
+			}
+		}
+		return slices.Sorted(maps.Keys(paths))
+	}
 	var paths []string
 	for path, tags := range tm.Tags {
-		for _, t := range tags {
-			if t == tag {
-				paths = append(paths, path)
-				break
-			}
+		if query.Match(tags) {
+			paths = append(paths, path)
 		}
 	}
 	sort.Strings(paths)
 	return paths
 }
 
-func (tm *TagManager) GetAllTags() []string {
-	tagSet := make(map[string]struct{})
-	for _, tags := range tm.Tags {
-		for _, tag := range tags {
-			tagSet[tag] = struct{}{}
-		}
-	}
-	var allTags []string
-	for tag := range tagSet {
-		allTags = append(allTags, tag)
+// Forget drops paths that are gone from the index.
+func (tm *TagManager) Forget(paths []string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	for _, path := range paths {
+		tm.setPath(path, nil)
 	}
-	sort.Strings(allTags)
-	return allTags
+}
+
+func (tm *TagManager) GetAllTags() []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return slices.Sorted(maps.Keys(tm.byTag))
 }
@@ -19,12 +19,27 @@ func NewTagPath(tag string, tagManager *TagManager) *TagPath {
 	}
 }
 
+// GetItems lists the tags below the tag and the files tagged with it, or
+// with any tag below it when IncludeSubtags is set.
 func (t *TagPath) GetItems() []*types.ListItem {
+	items := tagItems(t.tagManager, t.tagManager.ChildTags(t.tag))
+	if t.tagManager.IncludeSubtags() {
+		return append(items, listItems(t.tagManager, t.tagManager.GetPathsBelowTag(t.tag))...)
+	}
+	return append(items, listItems(t.tagManager, t.tagManager.GetPathsForTag(t.tag))...)
+}
+
+// listItems lists the tagged paths, dropping the ones that are gone from
+// the index.
+func listItems(tagManager *TagManager, paths []string) []*types.ListItem {
 	var items []*types.ListItem
-	paths := t.tagManager.GetPathsForTag(t.tag)
+	var gone []string
 	for _, path := range paths {
 		info, err := os.Stat(path)
 		if err != nil {
+			if _, err := os.Lstat(path); os.IsNotExist(err) {
+				gone = append(gone, path)
+			}
 			continue
 		}
 		listItem := &types.ListItem{
@@ -39,6 +54,9 @@ func (t *TagPath) GetItems() []*types.ListItem {
 		}
 		items = append(items, listItem)
 	}
+	if len(gone) > 0 {
+		tagManager.Forget(gone)
+	}
 	return items
 }
 
@@ -47,10 +65,14 @@ func (t *TagPath) GetPath() string {
 }
 
 func (t *TagPath) GetParentPath() string {
-	return "tags://"
+	return "tags://" + Parent(t.tag)
 }
 
 func (t *TagPath) GetName() string {
+	return Base(t.tag)
+}
+
+func (t *TagPath) Tag() string {
 	return t.tag
 }
 
@@ -13,14 +13,19 @@ func NewTagsPath(tagManager *TagManager) *TagsPath {
 }
 
 func (t *TagsPath) GetItems() []*types.ListItem {
+	return tagItems(t.tagManager, t.tagManager.ChildTags(""))
+}
+
+// tagItems lists tags like folders, counting the tags below them and the
+// files tagged with them.
+func tagItems(tagManager *TagManager, tags []string) []*types.ListItem {
 	var items []*types.ListItem
-	tags := t.tagManager.GetAllTags()
 	for _, tag := range tags {
 		items = append(items, &types.ListItem{
-			Name:      tag,
+			Name:      Base(tag),
 			IsDir:     true,
 			Path:      "tags://" + tag,
-			ItemCount: len(t.tagManager.GetPathsForTag(tag)),
+			ItemCount: len(tagManager.ChildTags(tag)) + tagManager.CountForTag(tag),
 		})
 	}
 	return items
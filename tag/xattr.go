@@ -0,0 +1,76 @@
+package tag
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// xattrName is the attribute other desktop tools, like Dolphin and Baloo,
+// keep tags in. The value is a comma separated list.
+const xattrName = "user.xdg.tags"
+
+// errNoAttr means the file can hold tags but has none.
+var errNoAttr = errors.New("no tags attribute")
+
+// readXattr returns the tags stored on path. It fails with errNoAttr when
+// the attribute isn't set and with another error when the filesystem
+// doesn't support it.
+func readXattr(path string) ([]string, error) {
+	buffer := make([]byte, 256)
+	for {
+		n, err := unix.Lgetxattr(path, xattrName, buffer)
+		if errors.Is(err, unix.ERANGE) {
+			buffer = make([]byte, len(buffer)*4)
+			continue
+		}
+		if errors.Is(err, unix.ENODATA) {
+			return nil, errNoAttr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return SplitTags(string(buffer[:n])), nil
+	}
+}
+
+// writeXattr stores tags on path, removing the attribute when there are
+// none left.
+func writeXattr(path string, tags []string) error {
+	if len(tags) == 0 {
+		err := unix.Lremovexattr(path, xattrName)
+		if errors.Is(err, unix.ENODATA) {
+			return nil
+		}
+		return err
+	}
+	return unix.Lsetxattr(path, xattrName, []byte(strings.Join(tags, ",")), 0)
+}
+
+// fileID tells a file apart from another one put at its path later, like
+// by an editor that saves by replacing the file.
+type fileID struct {
+	Dev uint64 `json:"dev"`
+	Ino uint64 `json:"ino"`
+}
+
+func statID(path string) (fileID, bool) {
+	var stat unix.Stat_t
+	if err := unix.Lstat(path, &stat); err != nil {
+		return fileID{}, false
+	}
+	return fileID{Dev: uint64(stat.Dev), Ino: stat.Ino}, true
+}
+
+// SplitTags turns a comma separated list, from the attribute or typed by
+// the user, into tags.
+func SplitTags(text string) []string {
+	var tags []string
+	for _, tag := range strings.Split(text, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,262 @@
+package tag
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	nsDC        = "http://purl.org/dc/elements/1.1/"
+	nsLightroom = "http://ns.adobe.com/lightroom/1.0/"
+	nsRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// rawExtensions are the camera raw formats mime doesn't know of.
+var rawExtensions = []string{".arw", ".cr2", ".cr3", ".dng", ".nef", ".orf", ".raf", ".rw2"}
+
+func isImage(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return strings.HasPrefix(mime.TypeByExtension(ext), "image/") || slices.Contains(rawExtensions, ext)
+}
+
+// sidecarPath returns the XMP sidecar of an image: photo.jpg.xmp as written
+// by darktable and digiKam, or photo.xmp as written by Lightroom when only
+// that one exists.
+func sidecarPath(path string) string {
+	sidecar := path + ".xmp"
+	if _, err := os.Stat(sidecar); err == nil {
+		return sidecar
+	}
+	if stem := strings.TrimSuffix(path, filepath.Ext(path)) + ".xmp"; stem != sidecar {
+		if _, err := os.Stat(stem); err == nil {
+			return stem
+		}
+	}
+	return sidecar
+}
+
+// readKeywords returns the dc:subject keywords in XMP data, along with the
+// lr:hierarchicalSubject ones turned into our a/b/c form.
+func readKeywords(data []byte) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var keywords []string
+	var list string
+	inItem := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return keywords, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == nsDC && t.Name.Local == "subject":
+				list = "subject"
+			case t.Name.Space == nsLightroom && t.Name.Local == "hierarchicalSubject":
+				list = "hierarchicalSubject"
+			case list != "" && t.Name.Space == nsRDF && t.Name.Local == "li":
+				inItem = true
+			}
+		case xml.EndElement:
+			if t.Name.Space == nsRDF && t.Name.Local == "li" {
+				inItem = false
+			} else if t.Name.Local == "subject" || t.Name.Local == "hierarchicalSubject" {
+				list = ""
+			}
+		case xml.CharData:
+			keyword := strings.TrimSpace(string(t))
+			if !inItem || keyword == "" {
+				continue
+			}
+			if list == "hierarchicalSubject" {
+				keyword = strings.ReplaceAll(keyword, "|", "/")
+			}
+			if !slices.Contains(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+	}
+}
+
+func subjectElement(keywords []string) string {
+	var b strings.Builder
+	b.WriteString("<dc:subject>\n    <rdf:Bag>\n")
+	for _, keyword := range keywords {
+		b.WriteString("     <rdf:li>")
+		xml.EscapeText(&b, []byte(keyword))
+		b.WriteString("</rdf:li>\n")
+	}
+	b.WriteString("    </rdf:Bag>\n   </dc:subject>")
+	return b.String()
+}
+
+var (
+	subjectPattern     = regexp.MustCompile(`(?s)<dc:subject\b[^>]*?(/>|>.*?</dc:subject>)`)
+	descriptionPattern = regexp.MustCompile(`<rdf:Description\b[^>]*?/?>`)
+)
+
+// setKeywords puts keywords in the dc:subject of XMP data, which is a new
+// sidecar for nil. The rest of an existing sidecar is left as it is.
+func setKeywords(data []byte, keywords []string) ([]byte, error) {
+	subject := subjectElement(keywords)
+	if data == nil {
+		return []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="` + nsRDF + `">
+  <rdf:Description rdf:about=""
+    xmlns:dc="` + nsDC + `">
+   ` + subject + `
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+`), nil
+	}
+	text := string(data)
+	if loc := subjectPattern.FindStringIndex(text); loc != nil {
+		return []byte(text[:loc[0]] + subject + text[loc[1]:]), nil
+	}
+	loc := descriptionPattern.FindStringIndex(text)
+	if loc == nil {
+		return nil, errors.New("no rdf:Description")
+	}
+	start := text[loc[0]:loc[1]]
+	closing := ""
+	if strings.HasSuffix(start, "/>") {
+		start = strings.TrimSuffix(start, "/>")
+		closing = "</rdf:Description>"
+	} else {
+		start = strings.TrimSuffix(start, ">")
+	}
+	if !strings.Contains(start, "xmlns:dc=") {
+		start += `
+    xmlns:dc="` + nsDC + `"`
+	}
+	return []byte(text[:loc[0]] + start + ">\n   " + subject + closing + text[loc[1]:]), nil
+}
+
+// exportXMP adds the tags of the images in paths to the keywords of their
+// sidecars, writing new sidecars where there are none.
+func exportXMP(ctx context.Context, paths map[string][]string, progress func(float64)) (int, error) {
+	var images []string
+	for path := range paths {
+		if isImage(path) {
+			images = append(images, path)
+		}
+	}
+	slices.Sort(images)
+	written := 0
+	for i, path := range images {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		sidecar := sidecarPath(path)
+		data, err := os.ReadFile(sidecar)
+		if err != nil && !os.IsNotExist(err) {
+			return written, err
+		}
+		var keywords []string
+		if data != nil {
+			if keywords, err = readKeywords(data); err != nil {
+				return written, fmt.Errorf("couldn't read %s: %w", sidecar, err)
+			}
+		}
+		merged := slices.Clone(keywords)
+		for _, t := range paths[path] {
+			if !slices.Contains(merged, t) {
+				merged = append(merged, t)
+			}
+		}
+		if data == nil || len(merged) != len(keywords) {
+			if data, err = setKeywords(data, merged); err != nil {
+				return written, fmt.Errorf("couldn't update %s: %w", sidecar, err)
+			}
+			if err := os.WriteFile(sidecar, data, 0644); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if progress != nil {
+			progress(float64(i+1) / float64(len(images)))
+		}
+	}
+	return written, nil
+}
+
+// readXMPTree returns the keywords of the sidecars below root by the image
+// they describe.
+func readXMPTree(ctx context.Context, root string) (map[string][]string, error) {
+	files := make(map[string][]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable folders are skipped instead of ending the import.
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".xmp") {
+			return nil
+		}
+		image := sidecarImage(path)
+		if image == "" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		keywords, err := readKeywords(data)
+		if err != nil {
+			println("couldn't read", path, err.Error())
+			return nil
+		}
+		if len(keywords) > 0 {
+			files[image] = append(files[image], keywords...)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// sidecarImage returns the image the sidecar at path describes, empty when
+// there is none.
+func sidecarImage(path string) string {
+	image := path[:len(path)-len(".xmp")]
+	if _, err := os.Stat(image); err == nil && filepath.Ext(image) != "" {
+		return image
+	}
+	matches, _ := filepath.Glob(escapeGlob(image) + ".*")
+	for _, match := range matches {
+		if isImage(match) {
+			return match
+		}
+	}
+	return ""
+}
+
+func escapeGlob(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package tag_popup
+
+import (
+	"math"
+
+	"github.com/MrSametBurgazoglu/atilgan/tag"
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+const dotSize = 10
+
+// NewColorDot draws a dot in the #rrggbb color hex, or an outline when the
+// color is empty.
+func NewColorDot(hex string) *gtk.DrawingArea {
+	dot := gtk.NewDrawingArea()
+	dot.SetContentWidth(dotSize)
+	dot.SetContentHeight(dotSize)
+	dot.SetVAlign(gtk.AlignCenter)
+	dot.SetDrawFunc(func(_ *gtk.DrawingArea, cr *cairo.Context, w, h int) {
+		cr.Arc(float64(w)/2, float64(h)/2, dotSize/2-1, 0, 2*math.Pi)
+		if r, g, b, ok := tag.RGB(hex); ok {
+			cr.SetSourceRGB(r, g, b)
+			cr.Fill()
+			return
+		}
+		cr.SetSourceRGBA(1, 1, 1, 0.5)
+		cr.SetLineWidth(1)
+		cr.Stroke()
+	})
+	return dot
+}
+
+// NewColorButton opens a menu of the palette colors and gives the chosen
+// one to the tags selected returns.
+func NewColorButton(tagManager *tag.TagManager, selected func() []string) *gtk.MenuButton {
+	button := gtk.NewMenuButton()
+	button.SetIconName("color-select-symbolic")
+	button.SetTooltipText("Tag Color")
+
+	popover := gtk.NewPopover()
+	box := gtk.NewBox(gtk.OrientationVertical, 2)
+	popover.SetChild(box)
+	button.SetPopover(popover)
+
+	addColor := func(name, hex string) {
+		row := gtk.NewBox(gtk.OrientationHorizontal, 8)
+		row.Append(NewColorDot(hex))
+		row.Append(gtk.NewLabel(name))
+		colorButton := gtk.NewButton()
+		colorButton.AddCSSClass("flat")
+		colorButton.SetChild(row)
+		colorButton.ConnectClicked(func() {
+			popover.Popdown()
+			for _, t := range selected() {
+				tagManager.SetTagColor(t, hex)
+			}
+		})
+		box.Append(colorButton)
+	}
+	for _, color := range tag.Palette {
+		addColor(color.Name, color.Hex)
+	}
+	addColor("No Color", "")
+	return button
+}
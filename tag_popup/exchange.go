@@ -0,0 +1,257 @@
+package tag_popup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/tag"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+type formatChoice struct {
+	name   string
+	format tag.Format
+}
+
+var exportFormats = []formatChoice{
+	{"Tags File (JSON)", tag.FormatJSON},
+	{"Spreadsheet (CSV)", tag.FormatCSV},
+	{"XMP Sidecars of Images", tag.FormatXMP},
+}
+
+var importFormats = append(exportFormats[:len(exportFormats):len(exportFormats)],
+	formatChoice{"Extended Attributes", tag.FormatXattr})
+
+// ExchangeWindow asks how to import or export tags. The work itself is
+// left to Applied.
+type ExchangeWindow struct {
+	*gtk.Window
+	Applied func(ex tag.Exchange)
+
+	export         bool
+	formats        []formatChoice
+	formatDropDown *gtk.DropDown
+	fileLabel      *gtk.Label
+	fileBox        *gtk.Box
+	fileEntry      *gtk.Entry
+	rootEntry      *gtk.Entry
+	relativeCheck  *gtk.CheckButton
+	hintLabel      *gtk.Label
+	statusLabel    *gtk.Label
+	applyButton    *gtk.Button
+}
+
+func NewExportWindow() *ExchangeWindow {
+	return newExchangeWindow(true)
+}
+
+func NewImportWindow() *ExchangeWindow {
+	return newExchangeWindow(false)
+}
+
+func newExchangeWindow(export bool) *ExchangeWindow {
+	ew := &ExchangeWindow{
+		Window:        gtk.NewWindow(),
+		export:        export,
+		formats:       importFormats,
+		fileLabel:     gtk.NewLabel("File"),
+		fileBox:       gtk.NewBox(gtk.OrientationHorizontal, 6),
+		fileEntry:     gtk.NewEntry(),
+		rootEntry:     gtk.NewEntry(),
+		relativeCheck: gtk.NewCheckButtonWithLabel("Paths relative to the folder"),
+		hintLabel:     gtk.NewLabel(""),
+		statusLabel:   gtk.NewLabel(""),
+		applyButton:   gtk.NewButtonWithLabel("Import"),
+	}
+	if export {
+		ew.formats = exportFormats
+		ew.applyButton.SetLabel("Export")
+		ew.SetTitle("Export Tags")
+	} else {
+		ew.SetTitle("Import Tags")
+	}
+	names := make([]string, len(ew.formats))
+	for i, choice := range ew.formats {
+		names[i] = choice.name
+	}
+	ew.formatDropDown = gtk.NewDropDownFromStrings(names)
+	ew.SetDefaultSize(460, -1)
+	ew.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 12)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	ew.SetChild(box)
+
+	form := gtk.NewGrid()
+	form.SetRowSpacing(6)
+	form.SetColumnSpacing(6)
+	box.Append(form)
+
+	ew.fileEntry.SetHExpand(true)
+	ew.fileBox.Append(ew.fileEntry)
+	ew.fileBox.Append(ew.browseFileButton())
+	ew.rootEntry.SetHExpand(true)
+	rootBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	rootBox.Append(ew.rootEntry)
+	rootBox.Append(ew.browseFolderButton())
+	rootLabel := gtk.NewLabel("Folder")
+	rootLabel.SetXAlign(0)
+	formatLabel := gtk.NewLabel("Format")
+	formatLabel.SetXAlign(0)
+	ew.fileLabel.SetXAlign(0)
+
+	form.Attach(formatLabel, 0, 0, 1, 1)
+	form.Attach(ew.formatDropDown, 1, 0, 1, 1)
+	form.Attach(ew.fileLabel, 0, 1, 1, 1)
+	form.Attach(ew.fileBox, 1, 1, 1, 1)
+	form.Attach(rootLabel, 0, 2, 1, 1)
+	form.Attach(rootBox, 1, 2, 1, 1)
+	if export {
+		ew.relativeCheck.SetActive(true)
+		form.Attach(ew.relativeCheck, 1, 3, 1, 1)
+	}
+
+	ew.hintLabel.SetXAlign(0)
+	ew.hintLabel.SetWrap(true)
+	ew.hintLabel.AddCSSClass("dim-label")
+	box.Append(ew.hintLabel)
+
+	ew.statusLabel.SetXAlign(0)
+	ew.statusLabel.SetWrap(true)
+	ew.statusLabel.AddCSSClass("error")
+	box.Append(ew.statusLabel)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(ew.Close)
+	buttonBox.Append(cancelButton)
+	ew.applyButton.AddCSSClass("suggested-action")
+	ew.applyButton.ConnectClicked(ew.apply)
+	buttonBox.Append(ew.applyButton)
+	box.Append(buttonBox)
+
+	ew.formatDropDown.NotifyProperty("selected", ew.update)
+	ew.fileEntry.ConnectChanged(ew.update)
+	ew.rootEntry.ConnectChanged(ew.update)
+	ew.relativeCheck.ConnectToggled(ew.update)
+	ew.update()
+	return ew
+}
+
+func (ew *ExchangeWindow) format() tag.Format {
+	return ew.formats[ew.formatDropDown.Selected()].format
+}
+
+func (ew *ExchangeWindow) exchange() tag.Exchange {
+	ex := tag.Exchange{
+		Format: ew.format(),
+		Root:   filepath.Clean(ew.rootEntry.Text()),
+	}
+	if ew.rootEntry.Text() == "" {
+		ex.Root = ""
+	}
+	if ex.Format == tag.FormatJSON || ex.Format == tag.FormatCSV {
+		ex.File = filepath.Clean(ew.fileEntry.Text())
+		ex.Relative = ew.export && ew.relativeCheck.Active()
+	}
+	return ex
+}
+
+// update shows the fields the format needs and explains it.
+func (ew *ExchangeWindow) update() {
+	format := ew.format()
+	usesFile := format == tag.FormatJSON || format == tag.FormatCSV
+	ew.fileLabel.SetVisible(usesFile)
+	ew.fileBox.SetVisible(usesFile)
+	ew.relativeCheck.SetVisible(usesFile)
+
+	switch {
+	case ew.export && format == tag.FormatXMP:
+		ew.hintLabel.SetText("The tags of the images below the folder are added to the keywords of their sidecars, which are created where missing.")
+	case ew.export && ew.rootEntry.Text() == "":
+		ew.hintLabel.SetText("All tagged files are exported.")
+	case ew.export:
+		ew.hintLabel.SetText("The tagged files below the folder are exported.")
+	case format == tag.FormatXMP:
+		ew.hintLabel.SetText("The keywords in the sidecars below the folder become tags of their images.")
+	case format == tag.FormatXattr:
+		ew.hintLabel.SetText("Tags other tools or another machine left on the files below the folder are added to the index.")
+	default:
+		ew.hintLabel.SetText("Relative paths are found below the folder, or next to the file when no folder is given.")
+	}
+	ew.statusLabel.SetText("")
+	ew.applyButton.SetSensitive(ew.exchange().Check() == nil)
+}
+
+func (ew *ExchangeWindow) browseFileButton() *gtk.Button {
+	button := gtk.NewButtonFromIconName("document-open-symbolic")
+	button.SetTooltipText("Choose File")
+	button.ConnectClicked(func() {
+		dialog := gtk.NewFileDialog()
+		if root := ew.rootEntry.Text(); filepath.IsAbs(root) {
+			dialog.SetInitialFolder(gio.NewFileForPath(root))
+		}
+		if !ew.export {
+			dialog.SetTitle("Import Tags")
+			dialog.Open(context.Background(), ew.Window, func(res gio.AsyncResulter) {
+				file, err := dialog.OpenFinish(res)
+				if err == nil && file != nil {
+					ew.fileEntry.SetText(file.Path())
+				}
+			})
+			return
+		}
+		dialog.SetTitle("Export Tags")
+		dialog.SetInitialName("tags." + string(ew.format()))
+		dialog.Save(context.Background(), ew.Window, func(res gio.AsyncResulter) {
+			file, err := dialog.SaveFinish(res)
+			if err == nil && file != nil {
+				ew.fileEntry.SetText(file.Path())
+			}
+		})
+	})
+	return button
+}
+
+func (ew *ExchangeWindow) browseFolderButton() *gtk.Button {
+	button := gtk.NewButtonFromIconName("folder-open-symbolic")
+	button.SetTooltipText("Choose Folder")
+	button.ConnectClicked(func() {
+		dialog := gtk.NewFileDialog()
+		dialog.SetTitle("Choose Folder")
+		if root := ew.rootEntry.Text(); filepath.IsAbs(root) {
+			dialog.SetInitialFolder(gio.NewFileForPath(root))
+		}
+		dialog.SelectFolder(context.Background(), ew.Window, func(res gio.AsyncResulter) {
+			folder, err := dialog.SelectFolderFinish(res)
+			if err == nil && folder != nil {
+				ew.rootEntry.SetText(folder.Path())
+			}
+		})
+	})
+	return button
+}
+
+func (ew *ExchangeWindow) apply() {
+	ex := ew.exchange()
+	if err := ex.Check(); err != nil {
+		ew.statusLabel.SetText(err.Error())
+		return
+	}
+	if !ew.export && ex.File != "" {
+		if _, err := os.Stat(ex.File); err != nil {
+			ew.statusLabel.SetText(err.Error())
+			return
+		}
+	}
+	if ew.Applied != nil {
+		ew.Applied(ex)
+	}
+	ew.Close()
+}
@@ -0,0 +1,148 @@
+package tag_popup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/tag"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// ManageWindow renames, merges or deletes tags on every file carrying them.
+type ManageWindow struct {
+	*gtk.Window
+	// Applied is called once the tags are changed.
+	Applied func()
+
+	tagManager  *tag.TagManager
+	form        *gtk.Box
+	statusLabel *gtk.Label
+	applyButton *gtk.Button
+}
+
+func newManageWindow(tagManager *tag.TagManager, title, action string) *ManageWindow {
+	mw := &ManageWindow{
+		Window:      gtk.NewWindow(),
+		tagManager:  tagManager,
+		form:        gtk.NewBox(gtk.OrientationVertical, 6),
+		statusLabel: gtk.NewLabel(""),
+		applyButton: gtk.NewButtonWithLabel(action),
+	}
+	mw.SetTitle(title)
+	mw.SetDefaultSize(360, -1)
+	mw.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 12)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	mw.SetChild(box)
+	box.Append(mw.form)
+
+	mw.statusLabel.SetXAlign(0)
+	mw.statusLabel.SetWrap(true)
+	mw.statusLabel.AddCSSClass("error")
+	box.Append(mw.statusLabel)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(mw.Close)
+	buttonBox.Append(cancelButton)
+	buttonBox.Append(mw.applyButton)
+	box.Append(buttonBox)
+	return mw
+}
+
+func (mw *ManageWindow) done(err error) {
+	if err != nil {
+		mw.statusLabel.SetText(err.Error())
+		return
+	}
+	if mw.Applied != nil {
+		mw.Applied()
+	}
+	mw.Close()
+}
+
+func describe(label string) *gtk.Label {
+	l := gtk.NewLabel(label)
+	l.SetXAlign(0)
+	l.SetWrap(true)
+	return l
+}
+
+// fileCount returns how many files carry one of tags.
+func fileCount(tagManager *tag.TagManager, tags []string) int {
+	paths := make(map[string]bool)
+	for _, t := range tags {
+		for _, path := range tagManager.GetPathsForTag(t) {
+			paths[path] = true
+		}
+	}
+	return len(paths)
+}
+
+func NewRenameTagWindow(tagManager *tag.TagManager, oldTag string) *ManageWindow {
+	mw := newManageWindow(tagManager, "Rename Tag", "Rename")
+	mw.applyButton.AddCSSClass("suggested-action")
+
+	entry := gtk.NewEntry()
+	entry.SetText(oldTag)
+	mw.form.Append(describe(fmt.Sprintf("Rename %s on %d files to", oldTag, fileCount(tagManager, []string{oldTag}))))
+	mw.form.Append(entry)
+	hint := describe("")
+	hint.AddCSSClass("dim-label")
+	mw.form.Append(hint)
+	entry.ConnectChanged(func() {
+		newTag := strings.TrimSpace(entry.Text())
+		if newTag != oldTag && tagManager.GetPathsForTag(newTag) != nil {
+			hint.SetText(fmt.Sprintf("%s is already used, the two tags will be merged.", newTag))
+		} else {
+			hint.SetText("")
+		}
+	})
+
+	mw.applyButton.ConnectClicked(func() {
+		newTag := strings.TrimSpace(entry.Text())
+		if newTag == oldTag {
+			mw.Close()
+			return
+		}
+		mw.done(tagManager.RenameTag(oldTag, newTag))
+	})
+	entry.ConnectActivate(func() {
+		mw.applyButton.Activate()
+	})
+	return mw
+}
+
+func NewMergeTagsWindow(tagManager *tag.TagManager, tags []string) *ManageWindow {
+	mw := newManageWindow(tagManager, "Merge Tags", "Merge")
+	mw.applyButton.AddCSSClass("suggested-action")
+
+	mw.form.Append(describe(fmt.Sprintf("Merge %s on %d files into", strings.Join(tags, ", "), fileCount(tagManager, tags))))
+	dropDown := gtk.NewDropDownFromStrings(tags)
+	mw.form.Append(dropDown)
+
+	mw.applyButton.ConnectClicked(func() {
+		mw.done(tagManager.MergeTags(tags, tags[dropDown.Selected()]))
+	})
+	return mw
+}
+
+func NewDeleteTagsWindow(tagManager *tag.TagManager, tags []string) *ManageWindow {
+	mw := newManageWindow(tagManager, "Delete Tags", "Delete")
+	mw.applyButton.AddCSSClass("destructive-action")
+
+	mw.form.Append(describe(fmt.Sprintf("Remove %s from %d files? The files themselves are kept.", strings.Join(tags, ", "), fileCount(tagManager, tags))))
+
+	mw.applyButton.ConnectClicked(func() {
+		for _, t := range tags {
+			tagManager.DeleteTag(t)
+		}
+		mw.done(nil)
+	})
+	return mw
+}
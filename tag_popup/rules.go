@@ -0,0 +1,359 @@
+package tag_popup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/tag"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// RulesWindow edits the auto-tag rules. Each rule can be tried on a
+// sample file before the rules are saved.
+type RulesWindow struct {
+	*gtk.Window
+	// Saved is called once the rules are saved.
+	Saved func()
+
+	autoTagger *tag.AutoTagger
+	rules      []tag.Rule
+	current    int
+	loading    bool
+
+	ruleList      *gtk.ListBox
+	rowLabels     []*gtk.Label
+	form          *gtk.Grid
+	nameEntry     *gtk.Entry
+	enabledCheck  *gtk.CheckButton
+	globEntry     *gtk.Entry
+	extEntry      *gtk.Entry
+	mimeEntry     *gtk.Entry
+	minSizeSpin   *gtk.SpinButton
+	maxSizeSpin   *gtk.SpinButton
+	minAgeSpin    *gtk.SpinButton
+	maxAgeSpin    *gtk.SpinButton
+	contentEntry  *gtk.Entry
+	tagsEntry     *gtk.Entry
+	testLabel     *gtk.Label
+	statusLabel   *gtk.Label
+	removeButton  *gtk.Button
+	testButton    *gtk.Button
+	lastTestedDir string
+}
+
+func NewRulesWindow(autoTagger *tag.AutoTagger) *RulesWindow {
+	rw := &RulesWindow{
+		Window:       gtk.NewWindow(),
+		autoTagger:   autoTagger,
+		rules:        autoTagger.Rules(),
+		current:      -1,
+		ruleList:     gtk.NewListBox(),
+		form:         gtk.NewGrid(),
+		nameEntry:    gtk.NewEntry(),
+		enabledCheck: gtk.NewCheckButtonWithLabel("Enabled"),
+		globEntry:    gtk.NewEntry(),
+		extEntry:     gtk.NewEntry(),
+		mimeEntry:    gtk.NewEntry(),
+		minSizeSpin:  gtk.NewSpinButtonWithRange(0, 1<<30, 1),
+		maxSizeSpin:  gtk.NewSpinButtonWithRange(0, 1<<30, 1),
+		minAgeSpin:   gtk.NewSpinButtonWithRange(0, 36500, 1),
+		maxAgeSpin:   gtk.NewSpinButtonWithRange(0, 36500, 1),
+		contentEntry: gtk.NewEntry(),
+		tagsEntry:    gtk.NewEntry(),
+		testLabel:    gtk.NewLabel(""),
+		statusLabel:  gtk.NewLabel(""),
+		removeButton: gtk.NewButtonFromIconName("list-remove-symbolic"),
+		testButton:   gtk.NewButtonWithLabel("Test on File…"),
+	}
+	rw.SetTitle("Tag Rules")
+	rw.SetDefaultSize(640, -1)
+	rw.SetModal(true)
+
+	box := gtk.NewBox(gtk.OrientationVertical, 12)
+	box.SetMarginTop(12)
+	box.SetMarginBottom(12)
+	box.SetMarginStart(12)
+	box.SetMarginEnd(12)
+	rw.SetChild(box)
+
+	content := gtk.NewBox(gtk.OrientationHorizontal, 12)
+	box.Append(content)
+
+	listBox := gtk.NewBox(gtk.OrientationVertical, 6)
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetPolicy(gtk.PolicyNever, gtk.PolicyAutomatic)
+	scrolled.SetSizeRequest(180, 320)
+	scrolled.SetVExpand(true)
+	scrolled.SetChild(rw.ruleList)
+	listBox.Append(scrolled)
+	rw.ruleList.ConnectRowSelected(func(row *gtk.ListBoxRow) {
+		if row != nil && !rw.loading {
+			rw.selectRule(row.Index())
+		}
+	})
+
+	listButtons := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	addButton := gtk.NewButtonFromIconName("list-add-symbolic")
+	addButton.SetTooltipText("Add Rule")
+	addButton.ConnectClicked(rw.addRule)
+	listButtons.Append(addButton)
+	rw.removeButton.SetTooltipText("Remove Rule")
+	rw.removeButton.ConnectClicked(rw.removeRule)
+	listButtons.Append(rw.removeButton)
+	listBox.Append(listButtons)
+	content.Append(listBox)
+
+	rw.form.SetRowSpacing(6)
+	rw.form.SetColumnSpacing(6)
+	rw.form.SetHExpand(true)
+	content.Append(rw.form)
+
+	rw.globEntry.SetPlaceholderText("~/Downloads/*.pdf")
+	rw.extEntry.SetPlaceholderText("pdf, odt")
+	rw.mimeEntry.SetPlaceholderText("application/pdf, image/*")
+	rw.contentEntry.SetPlaceholderText("Invoice")
+	rw.contentEntry.SetTooltipText("A regular expression searched in the text of the file")
+	rw.tagsEntry.SetPlaceholderText("invoice, finance")
+
+	row := 0
+	addRow := func(label string, widgets ...gtk.Widgetter) {
+		title := gtk.NewLabel(label)
+		title.SetXAlign(0)
+		rw.form.Attach(title, 0, row, 1, 1)
+		for i, widget := range widgets {
+			// A lone widget takes the column of the enabled check too.
+			rw.form.Attach(widget, i+1, row, 3-len(widgets), 1)
+		}
+		row++
+	}
+	rw.nameEntry.SetHExpand(true)
+	addRow("Name", rw.nameEntry, rw.enabledCheck)
+	addRow("Path", rw.globEntry)
+	addRow("Extensions", rw.extEntry)
+	addRow("Type", rw.mimeEntry)
+	addRow("Size in KB", rangeBox(rw.minSizeSpin, rw.maxSizeSpin))
+	addRow("Age in days", rangeBox(rw.minAgeSpin, rw.maxAgeSpin))
+	addRow("Content", rw.contentEntry)
+	addRow("Tags", rw.tagsEntry)
+	rw.nameEntry.ConnectChanged(func() {
+		if rw.current >= 0 && !rw.loading {
+			rw.rowLabels[rw.current].SetText(ruleTitle(rw.nameEntry.Text()))
+		}
+	})
+
+	rw.testButton.ConnectClicked(rw.test)
+	testBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	testBox.Append(rw.testButton)
+	rw.testLabel.SetXAlign(0)
+	rw.testLabel.SetWrap(true)
+	rw.testLabel.SetHExpand(true)
+	testBox.Append(rw.testLabel)
+	rw.form.Attach(testBox, 0, row, 3, 1)
+
+	rw.statusLabel.SetXAlign(0)
+	rw.statusLabel.SetWrap(true)
+	rw.statusLabel.AddCSSClass("error")
+	box.Append(rw.statusLabel)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	cancelButton := gtk.NewButtonWithLabel("Cancel")
+	cancelButton.ConnectClicked(rw.Close)
+	buttonBox.Append(cancelButton)
+	saveButton := gtk.NewButtonWithLabel("Save")
+	saveButton.AddCSSClass("suggested-action")
+	saveButton.ConnectClicked(rw.save)
+	buttonBox.Append(saveButton)
+	box.Append(buttonBox)
+
+	for _, rule := range rw.rules {
+		rw.appendRow(rule.Name)
+	}
+	if len(rw.rules) == 0 {
+		rw.addRule()
+	} else {
+		rw.ruleList.SelectRow(rw.ruleList.RowAtIndex(0))
+	}
+	return rw
+}
+
+func rangeBox(from, to *gtk.SpinButton) *gtk.Box {
+	box := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	from.SetTooltipText("At least, 0 for no limit")
+	to.SetTooltipText("At most, 0 for no limit")
+	box.Append(from)
+	box.Append(gtk.NewLabel("to"))
+	box.Append(to)
+	return box
+}
+
+func ruleTitle(name string) string {
+	if strings.TrimSpace(name) == "" {
+		return "Unnamed Rule"
+	}
+	return name
+}
+
+// appendRow lists a rule under name.
+func (rw *RulesWindow) appendRow(name string) {
+	label := gtk.NewLabel(ruleTitle(name))
+	label.SetXAlign(0)
+	label.SetMarginTop(6)
+	label.SetMarginBottom(6)
+	label.SetMarginStart(6)
+	label.SetMarginEnd(6)
+	rw.rowLabels = append(rw.rowLabels, label)
+	rw.ruleList.Append(label)
+}
+
+func (rw *RulesWindow) addRule() {
+	rw.storeForm()
+	rw.rules = append(rw.rules, tag.Rule{Name: fmt.Sprintf("Rule %d", len(rw.rules)+1)})
+	rw.appendRow(rw.rules[len(rw.rules)-1].Name)
+	rw.ruleList.SelectRow(rw.ruleList.RowAtIndex(len(rw.rules) - 1))
+}
+
+func (rw *RulesWindow) removeRule() {
+	if rw.current < 0 {
+		return
+	}
+	index := rw.current
+	rw.current = -1
+	rw.rules = append(rw.rules[:index], rw.rules[index+1:]...)
+	rw.rowLabels = append(rw.rowLabels[:index], rw.rowLabels[index+1:]...)
+	rw.loading = true
+	rw.ruleList.Remove(rw.ruleList.RowAtIndex(index))
+	rw.loading = false
+	if len(rw.rules) == 0 {
+		rw.showRule(-1)
+		return
+	}
+	rw.ruleList.SelectRow(rw.ruleList.RowAtIndex(min(index, len(rw.rules)-1)))
+}
+
+func (rw *RulesWindow) selectRule(index int) {
+	if index == rw.current {
+		return
+	}
+	rw.storeForm()
+	rw.showRule(index)
+}
+
+// showRule fills the form with the rule at index, or empties and disables
+// it for -1.
+func (rw *RulesWindow) showRule(index int) {
+	rw.current = index
+	rule := tag.Rule{}
+	if index >= 0 {
+		rule = rw.rules[index]
+	}
+	rw.loading = true
+	rw.nameEntry.SetText(rule.Name)
+	rw.enabledCheck.SetActive(!rule.Disabled)
+	rw.globEntry.SetText(rule.Glob)
+	rw.extEntry.SetText(strings.Join(rule.Extensions, ", "))
+	rw.mimeEntry.SetText(rule.MimeType)
+	rw.minSizeSpin.SetValue(float64(rule.MinSize / 1024))
+	rw.maxSizeSpin.SetValue(float64(rule.MaxSize / 1024))
+	rw.minAgeSpin.SetValue(float64(rule.MinAgeDays))
+	rw.maxAgeSpin.SetValue(float64(rule.MaxAgeDays))
+	rw.contentEntry.SetText(rule.Content)
+	rw.tagsEntry.SetText(strings.Join(rule.Tags, ", "))
+	rw.loading = false
+	rw.testLabel.SetText("")
+	rw.form.SetSensitive(index >= 0)
+	rw.removeButton.SetSensitive(index >= 0)
+}
+
+// readForm returns the rule the form describes.
+func (rw *RulesWindow) readForm() tag.Rule {
+	return tag.Rule{
+		Name:       strings.TrimSpace(rw.nameEntry.Text()),
+		Disabled:   !rw.enabledCheck.Active(),
+		Glob:       strings.TrimSpace(rw.globEntry.Text()),
+		Extensions: tag.SplitTags(rw.extEntry.Text()),
+		MimeType:   strings.TrimSpace(rw.mimeEntry.Text()),
+		MinSize:    int64(rw.minSizeSpin.Value()) * 1024,
+		MaxSize:    int64(rw.maxSizeSpin.Value()) * 1024,
+		MinAgeDays: int(rw.minAgeSpin.Value()),
+		MaxAgeDays: int(rw.maxAgeSpin.Value()),
+		Content:    rw.contentEntry.Text(),
+		Tags:       tag.SplitTags(rw.tagsEntry.Text()),
+	}
+}
+
+func (rw *RulesWindow) storeForm() {
+	if rw.current >= 0 {
+		rw.rules[rw.current] = rw.readForm()
+	}
+}
+
+func (rw *RulesWindow) test() {
+	rule := rw.readForm()
+	if err := rule.Validate(); err != nil {
+		rw.showTest(err.Error(), false)
+		return
+	}
+	dialog := gtk.NewFileDialog()
+	dialog.SetTitle("Test " + ruleTitle(rule.Name))
+	folder := rw.lastTestedDir
+	if folder == "" {
+		folder = rule.WatchedFolder()
+	}
+	if folder != "" {
+		dialog.SetInitialFolder(gio.NewFileForPath(folder))
+	}
+	dialog.Open(context.Background(), rw.Window, func(res gio.AsyncResulter) {
+		file, err := dialog.OpenFinish(res)
+		if err != nil || file == nil {
+			return
+		}
+		path := file.Path()
+		rw.lastTestedDir = filepath.Dir(path)
+		matched, reason, err := rule.Check(path)
+		switch {
+		case err != nil:
+			rw.showTest(err.Error(), false)
+		case matched:
+			rw.showTest(fmt.Sprintf("%s matches and would be tagged %s.", filepath.Base(path), strings.Join(rule.Tags, ", ")), true)
+		default:
+			rw.showTest(fmt.Sprintf("%s doesn't match. %s.", filepath.Base(path), reason), false)
+		}
+	})
+}
+
+func (rw *RulesWindow) showTest(text string, matched bool) {
+	rw.testLabel.SetText(text)
+	rw.testLabel.RemoveCSSClass("success")
+	rw.testLabel.RemoveCSSClass("error")
+	if matched {
+		rw.testLabel.AddCSSClass("success")
+	} else {
+		rw.testLabel.AddCSSClass("error")
+	}
+}
+
+func (rw *RulesWindow) save() {
+	rw.storeForm()
+	for i, rule := range rw.rules {
+		if rule.Disabled {
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			rw.ruleList.SelectRow(rw.ruleList.RowAtIndex(i))
+			rw.statusLabel.SetText(fmt.Sprintf("%s: %s", ruleTitle(rule.Name), err.Error()))
+			return
+		}
+	}
+	if err := rw.autoTagger.SetRules(rw.rules); err != nil {
+		rw.statusLabel.SetText(err.Error())
+		return
+	}
+	if rw.Saved != nil {
+		rw.Saved()
+	}
+	rw.Close()
+}
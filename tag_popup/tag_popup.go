@@ -1,28 +1,38 @@
 package tag_popup
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/MrSametBurgazoglu/atilgan/tag"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
+const maxSuggestions = 6
+
 type TagPopup struct {
 	*gtk.Window
-	entry      *gtk.Entry
-	tagManager *tag.TagManager
-	path       string
+	entry       *gtk.Entry
+	tagManager  *tag.TagManager
+	path        string
+	current     *gtk.FlowBox
+	suggestions *gtk.ListBox
 }
 
 func NewTagPopup(parent *gtk.Window, tagManager *tag.TagManager, path string) *TagPopup {
 	popup := &TagPopup{
-		Window:     gtk.NewWindow(),
-		entry:      gtk.NewEntry(),
-		tagManager: tagManager,
-		path:       path,
+		Window:      gtk.NewWindow(),
+		entry:       gtk.NewEntry(),
+		tagManager:  tagManager,
+		path:        path,
+		current:     gtk.NewFlowBox(),
+		suggestions: gtk.NewListBox(),
 	}
 
 	popup.SetTransientFor(parent)
 	popup.SetModal(true)
-	popup.SetTitle("Add Tag")
+	popup.SetTitle("Tags")
+	popup.SetDefaultSize(360, -1)
 
 	box := gtk.NewBox(gtk.OrientationVertical, 6)
 	box.SetMarginTop(12)
@@ -31,21 +41,112 @@ func NewTagPopup(parent *gtk.Window, tagManager *tag.TagManager, path string) *T
 	box.SetMarginEnd(12)
 	popup.SetChild(box)
 
+	popup.current.SetSelectionMode(gtk.SelectionNone)
+	popup.current.SetColumnSpacing(4)
+	popup.current.SetRowSpacing(4)
+	box.Append(popup.current)
+
+	popup.entry.SetPlaceholderText("Tags, separated by commas")
 	box.Append(popup.entry)
 
+	popup.suggestions.AddCSSClass("boxed-list")
+	popup.suggestions.ConnectRowActivated(func(row *gtk.ListBoxRow) {
+		popup.complete(row.Name())
+	})
+	box.Append(popup.suggestions)
+
+	buttonBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
+	buttonBox.SetHAlign(gtk.AlignEnd)
+	closeButton := gtk.NewButtonWithLabel("Close")
+	closeButton.ConnectClicked(popup.Close)
 	addButton := gtk.NewButtonWithLabel("Add")
+	addButton.AddCSSClass("suggested-action")
 	addButton.ConnectClicked(func() {
-		tag := popup.entry.Text()
-		if tag != "" {
-			popup.tagManager.AddTag(popup.path, tag)
+		tags := tag.SplitTags(popup.entry.Text())
+		if len(tags) > 0 {
+			for _, t := range tags {
+				popup.tagManager.AddTag(popup.path, t)
+			}
 			popup.Close()
 		}
 	})
-	box.Append(addButton)
+	buttonBox.Append(closeButton)
+	buttonBox.Append(addButton)
+	box.Append(buttonBox)
 
 	popup.entry.ConnectActivate(func() {
 		addButton.Activate()
 	})
+	popup.entry.ConnectChanged(popup.suggest)
 
+	popup.showCurrent()
+	popup.suggest()
 	return popup
 }
+
+// showCurrent lists the tags of the file, each with a button to remove it.
+func (popup *TagPopup) showCurrent() {
+	popup.current.RemoveAll()
+	tags := popup.tagManager.GetTags(popup.path)
+	popup.current.SetVisible(len(tags) > 0)
+	for _, t := range tags {
+		chip := gtk.NewBox(gtk.OrientationHorizontal, 4)
+		chip.AddCSSClass("card")
+		chip.SetMarginStart(2)
+		chip.Append(NewColorDot(popup.tagManager.TagColor(t)))
+		chip.Append(gtk.NewLabel(t))
+		removeButton := gtk.NewButtonFromIconName("window-close-symbolic")
+		removeButton.AddCSSClass("flat")
+		removeButton.SetTooltipText("Remove Tag")
+		removeButton.ConnectClicked(func() {
+			popup.tagManager.RemoveTag(popup.path, t)
+			popup.showCurrent()
+			popup.suggest()
+		})
+		chip.Append(removeButton)
+		popup.current.Append(chip)
+	}
+}
+
+// suggest lists the existing tags starting with the one being typed that
+// the file doesn't have yet.
+func (popup *TagPopup) suggest() {
+	popup.suggestions.RemoveAll()
+	text := popup.entry.Text()
+	typed := strings.ToLower(strings.TrimSpace(text[strings.LastIndex(text, ",")+1:]))
+	have := slices.Concat(popup.tagManager.GetTags(popup.path), tag.SplitTags(text))
+
+	count := 0
+	for _, t := range popup.tagManager.GetAllTags() {
+		if count == maxSuggestions {
+			break
+		}
+		if slices.Contains(have, t) || !strings.HasPrefix(strings.ToLower(t), typed) {
+			continue
+		}
+		row := gtk.NewBox(gtk.OrientationHorizontal, 8)
+		row.SetMarginTop(4)
+		row.SetMarginBottom(4)
+		row.SetMarginStart(8)
+		row.Append(NewColorDot(popup.tagManager.TagColor(t)))
+		row.Append(gtk.NewLabel(t))
+		listRow := gtk.NewListBoxRow()
+		listRow.SetName(t)
+		listRow.SetChild(row)
+		popup.suggestions.Append(listRow)
+		count++
+	}
+	popup.suggestions.SetVisible(count > 0)
+}
+
+// complete replaces the tag being typed with t.
+func (popup *TagPopup) complete(t string) {
+	text := popup.entry.Text()
+	prefix := text[:strings.LastIndex(text, ",")+1]
+	if prefix != "" {
+		prefix += " "
+	}
+	popup.entry.SetText(prefix + t + ", ")
+	popup.entry.SetPosition(-1)
+	popup.entry.GrabFocus()
+}
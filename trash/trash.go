@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -188,3 +189,17 @@ func Restore(itemName string) error {
 
 	return nil
 }
+
+// MoveToTrash moves paths to the trash with gio, which also handles trash
+// directories on other mounts.
+func MoveToTrash(paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	args := append([]string{"trash", "--"}, paths...)
+	output, err := exec.Command("gio", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to move to trash: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
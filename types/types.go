@@ -1,11 +1,20 @@
 package types
 
+import "time"
+
 type ListItem struct {
 	Name        string
 	IsDir       bool
 	Path        string // full path include file name and extension
 	Group       string
 	ItemCount   int
-	Size        int64  //as byte
+	Size        int64 //as byte
+	TotalSize   int64 // recursive size of a folder, 0 until its disk usage is scanned
+	ModTime     time.Time
+	CreatedTime time.Time
+	ChangeTime  time.Time
 	SpecialInfo string // for special paths
+	IsSymlink   bool
+	LinkTarget  string // target of a symbolic link as it is stored
+	BrokenLink  bool   // the target of a symbolic link doesn't exist
 }
@@ -0,0 +1,67 @@
+package undo
+
+import "errors"
+
+const maxOperations = 100
+
+// Operation is a completed file operation that can be reverted and applied
+// again.
+type Operation interface {
+	Name() string
+	Undo() error
+	Redo() error
+}
+
+type History struct {
+	done   []Operation
+	undone []Operation
+}
+
+func NewHistory() *History {
+	return &History{}
+}
+
+// Push records an operation that has just been applied.
+func (h *History) Push(op Operation) {
+	h.done = append(h.done, op)
+	if len(h.done) > maxOperations {
+		h.done = h.done[len(h.done)-maxOperations:]
+	}
+	h.undone = nil
+}
+
+func (h *History) CanUndo() bool {
+	return len(h.done) > 0
+}
+
+func (h *History) CanRedo() bool {
+	return len(h.undone) > 0
+}
+
+// Undo reverts the last operation. An operation that fails to undo is
+// dropped since its files are no longer in a known state.
+func (h *History) Undo() (Operation, error) {
+	if !h.CanUndo() {
+		return nil, errors.New("nothing to undo")
+	}
+	op := h.done[len(h.done)-1]
+	h.done = h.done[:len(h.done)-1]
+	if err := op.Undo(); err != nil {
+		return op, err
+	}
+	h.undone = append(h.undone, op)
+	return op, nil
+}
+
+func (h *History) Redo() (Operation, error) {
+	if !h.CanRedo() {
+		return nil, errors.New("nothing to redo")
+	}
+	op := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	if err := op.Redo(); err != nil {
+		return op, err
+	}
+	h.done = append(h.done, op)
+	return op, nil
+}
@@ -0,0 +1,292 @@
+package view_state
+
+import (
+	"encoding/json"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/MrSametBurgazoglu/atilgan/columns"
+	"github.com/MrSametBurgazoglu/atilgan/json_store"
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
+)
+
+// ViewState holds the settings a directory overrides. Unset fields are
+// inherited from the closest parent directory that sets them and finally
+// from the global default.
+type ViewState struct {
+	SortOptions *sorter.SortOptions `json:"sort,omitempty"`
+	Filters     map[string]bool     `json:"filters,omitempty"`
+	ShowHidden  *bool               `json:"show_hidden,omitempty"`
+	GridMode    *bool               `json:"grid_mode,omitempty"`
+	DetailsMode *bool               `json:"details_mode,omitempty"`
+}
+
+// View is the resolved state for a directory.
+type View struct {
+	SortOptions sorter.SortOptions
+	Filters     map[string]bool
+	ShowHidden  bool
+	GridMode    bool
+	DetailsMode bool
+}
+
+// viewStateFile is view_state.json.
+type viewStateFile struct {
+	Default ViewState                 `json:"default"`
+	Paths   map[string]*ViewState     `json:"paths"`
+	Layouts map[string]columns.Layout `json:"layouts,omitempty"`
+}
+
+func parseViewStateFile(data []byte) (*viewStateFile, error) {
+	file := &viewStateFile{}
+	if data != nil {
+		if err := json.Unmarshal(data, file); err != nil {
+			return nil, err
+		}
+	}
+	if file.Paths == nil {
+		file.Paths = make(map[string]*ViewState)
+	}
+	if file.Layouts == nil {
+		file.Layouts = make(map[string]columns.Layout)
+	}
+	return file, nil
+}
+
+type ViewStateManager struct {
+	file  *viewStateFile
+	mu    sync.Mutex
+	store *json_store.Store
+	// pending holds the changes not saved yet. They are replayed on the
+	// view_state.json other instances may have written in the meantime.
+	pending []func(file *viewStateFile)
+}
+
+func NewViewStateManager() (*ViewStateManager, error) {
+	store, err := json_store.Open("view_state.json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Read()
+	if err != nil {
+		println("couldn't read view states:", err.Error())
+	}
+	// A broken file starts the views over, it is written again with the
+	// first change.
+	file, err := parseViewStateFile(data)
+	if err != nil {
+		println("view_state.json can't be read, starting over:", err.Error())
+		file, _ = parseViewStateFile(nil)
+	}
+	vsm := &ViewStateManager{
+		file:  file,
+		store: store,
+	}
+	store.Changed = vsm.reload
+	if err := store.Watch(); err != nil {
+		println("couldn't watch view_state.json:", err.Error())
+	}
+	return vsm, nil
+}
+
+// change applies op now and has it saved.
+func (vsm *ViewStateManager) change(op func(file *viewStateFile)) {
+	vsm.mu.Lock()
+	defer vsm.mu.Unlock()
+	op(vsm.file)
+	vsm.pending = append(vsm.pending, op)
+	vsm.store.Schedule(vsm.flush)
+}
+
+// replay applies the pending changes to the view states in data.
+func (vsm *ViewStateManager) replay(data []byte) (*viewStateFile, error) {
+	file, err := parseViewStateFile(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range vsm.pending {
+		op(file)
+	}
+	return file, nil
+}
+
+func (vsm *ViewStateManager) flush() {
+	vsm.mu.Lock()
+	defer vsm.mu.Unlock()
+	err := vsm.store.Update(func(data []byte) ([]byte, error) {
+		file, err := vsm.replay(data)
+		if err != nil {
+			println("view_state.json can't be read, writing it again:", err.Error())
+			file = vsm.file
+		}
+		vsm.file = file
+		return json.MarshalIndent(vsm.file, "", "  ")
+	})
+	if err != nil {
+		println("couldn't save view states:", err.Error())
+		return
+	}
+	vsm.pending = nil
+}
+
+// reload takes in the view_state.json another instance wrote.
+func (vsm *ViewStateManager) reload() {
+	data, err := vsm.store.Read()
+	if err != nil {
+		return
+	}
+	vsm.mu.Lock()
+	defer vsm.mu.Unlock()
+	if file, err := vsm.replay(data); err == nil {
+		vsm.file = file
+	}
+}
+
+// Flush writes the changes waiting to be saved right away.
+func (vsm *ViewStateManager) Flush() {
+	vsm.store.Flush()
+}
+
+// Get resolves the view for path by applying the global default, then every
+// parent directory from the root down and finally path itself.
+func (vsm *ViewStateManager) Get(path string) View {
+	vsm.mu.Lock()
+	defer vsm.mu.Unlock()
+	return vsm.file.get(path)
+}
+
+func (file *viewStateFile) get(path string) View {
+	view := View{
+		SortOptions: sorter.DefaultSortOptions(),
+		Filters:     make(map[string]bool),
+	}
+	view.apply(&file.Default)
+	if isSpecialPath(path) {
+		return view
+	}
+
+	var chain []*ViewState
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if state, ok := file.Paths[dir]; ok {
+			chain = append(chain, state)
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		view.apply(chain[i])
+	}
+	return view
+}
+
+func (v *View) apply(state *ViewState) {
+	if state.SortOptions != nil {
+		v.SortOptions = *state.SortOptions
+	}
+	maps.Copy(v.Filters, state.Filters)
+	if state.ShowHidden != nil {
+		v.ShowHidden = *state.ShowHidden
+	}
+	if state.GridMode != nil {
+		v.GridMode = *state.GridMode
+	}
+	if state.DetailsMode != nil {
+		v.DetailsMode = *state.DetailsMode
+	}
+}
+
+func (vsm *ViewStateManager) SetSortOptions(path string, opts sorter.SortOptions) {
+	vsm.update(path, func(state *ViewState) {
+		state.SortOptions = &opts
+	})
+}
+
+func (vsm *ViewStateManager) SetFilter(path string, filter string, enabled bool) {
+	vsm.update(path, func(state *ViewState) {
+		if state.Filters == nil {
+			state.Filters = make(map[string]bool)
+		}
+		state.Filters[filter] = enabled
+	})
+}
+
+func (vsm *ViewStateManager) SetShowHidden(path string, showHidden bool) {
+	vsm.update(path, func(state *ViewState) {
+		state.ShowHidden = &showHidden
+	})
+}
+
+func (vsm *ViewStateManager) SetGridMode(path string, gridMode bool) {
+	vsm.update(path, func(state *ViewState) {
+		state.GridMode = &gridMode
+	})
+}
+
+func (vsm *ViewStateManager) SetDetailsMode(path string, detailsMode bool) {
+	vsm.update(path, func(state *ViewState) {
+		state.DetailsMode = &detailsMode
+	})
+}
+
+// GetColumnLayout returns the details view columns of the named view.
+func (vsm *ViewStateManager) GetColumnLayout(view string) columns.Layout {
+	vsm.mu.Lock()
+	defer vsm.mu.Unlock()
+	if layout, ok := vsm.file.Layouts[view]; ok && len(layout) > 0 {
+		return layout
+	}
+	return columns.DefaultLayout()
+}
+
+func (vsm *ViewStateManager) SetColumnLayout(view string, layout columns.Layout) {
+	layout = slices.Clone(layout)
+	vsm.change(func(file *viewStateFile) {
+		file.Layouts[view] = layout
+	})
+}
+
+// SetDefault makes the resolved view of path the global default and drops
+// the overrides of path so it follows the new default.
+func (vsm *ViewStateManager) SetDefault(path string) {
+	view := vsm.Get(path)
+	vsm.change(func(file *viewStateFile) {
+		file.Default = ViewState{
+			SortOptions: &view.SortOptions,
+			Filters:     maps.Clone(view.Filters),
+			ShowHidden:  &view.ShowHidden,
+			GridMode:    &view.GridMode,
+			DetailsMode: &view.DetailsMode,
+		}
+		delete(file.Paths, filepath.Clean(path))
+	})
+}
+
+// Reset removes the overrides of path so it inherits again.
+func (vsm *ViewStateManager) Reset(path string) {
+	vsm.change(func(file *viewStateFile) {
+		delete(file.Paths, filepath.Clean(path))
+	})
+}
+
+func (vsm *ViewStateManager) update(path string, change func(*ViewState)) {
+	if isSpecialPath(path) {
+		return
+	}
+	path = filepath.Clean(path)
+	vsm.change(func(file *viewStateFile) {
+		state, ok := file.Paths[path]
+		if !ok {
+			state = &ViewState{}
+			file.Paths[path] = state
+		}
+		change(state)
+	})
+}
+
+func isSpecialPath(path string) bool {
+	return path == "" || strings.Contains(path, "://")
+}
@@ -0,0 +1,80 @@
+package viewer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/MrSametBurgazoglu/atilgan/dir_compare"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/pango"
+)
+
+// compareBar holds the actions of the compare:// view.
+type compareBar struct {
+	*gtk.Box
+	viewer  *FileViewer
+	path    *dir_compare.ComparePath
+	summary *gtk.Label
+}
+
+func newCompareBar(viewer *FileViewer) *compareBar {
+	bar := &compareBar{
+		Box:     gtk.NewBox(gtk.OrientationHorizontal, 6),
+		viewer:  viewer,
+		summary: gtk.NewLabel(""),
+	}
+	bar.summary.SetXAlign(0)
+	bar.summary.SetHExpand(true)
+	bar.summary.SetEllipsize(pango.EllipsizeMiddle)
+	bar.Append(bar.summary)
+
+	syncButton := gtk.NewButtonWithLabel("Sync…")
+	syncButton.ConnectClicked(func() {
+		result := bar.path.Result()
+		if result == nil || viewer.SyncFolders == nil {
+			return
+		}
+		// The cursor item alone doesn't count as a selection.
+		var selected []string
+		if items := viewer.FileViewerList.SelectedItems(); len(items) > 1 {
+			for _, item := range items {
+				if entry := result.Entry(item.Path); entry != nil {
+					selected = append(selected, entry.Rel)
+				}
+			}
+		}
+		viewer.SyncFolders(result, selected)
+	})
+	bar.Append(syncButton)
+
+	rescanButton := gtk.NewButtonFromIconName("view-refresh-symbolic")
+	rescanButton.SetTooltipText("Compare Again")
+	rescanButton.ConnectClicked(func() {
+		if viewer.CompareFolders == nil {
+			return
+		}
+		var opts dir_compare.Options
+		if result := bar.path.Result(); result != nil {
+			opts = result.Options
+		}
+		viewer.CompareFolders(bar.path.Left(), bar.path.Right(), opts)
+	})
+	bar.Append(rescanButton)
+
+	return bar
+}
+
+func (bar *compareBar) update(path *dir_compare.ComparePath) {
+	bar.path = path
+	result := path.Result()
+	if result == nil {
+		bar.summary.SetText("Not compared yet")
+		return
+	}
+	if len(result.Entries) == 0 {
+		bar.summary.SetText(fmt.Sprintf("%s and %s are the same", path.Left(), path.Right()))
+		return
+	}
+	bar.summary.SetText(fmt.Sprintf("%d differences between %s and %s", len(result.Entries), filepath.Base(path.Left()), filepath.Base(path.Right())))
+	bar.summary.SetTooltipText(fmt.Sprintf("Left: %s\nRight: %s", path.Left(), path.Right()))
+}
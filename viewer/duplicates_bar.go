@@ -0,0 +1,112 @@
+package viewer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MrSametBurgazoglu/atilgan/duplicates"
+	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// duplicatesBar holds the actions of the duplicates:// view.
+type duplicatesBar struct {
+	*gtk.Box
+	viewer  *FileViewer
+	path    *duplicates.DuplicatesPath
+	summary *gtk.Label
+}
+
+func newDuplicatesBar(viewer *FileViewer) *duplicatesBar {
+	bar := &duplicatesBar{
+		Box:     gtk.NewBox(gtk.OrientationHorizontal, 6),
+		viewer:  viewer,
+		summary: gtk.NewLabel(""),
+	}
+	bar.summary.SetXAlign(0)
+	bar.summary.SetHExpand(true)
+	bar.Append(bar.summary)
+
+	keepNewest := gtk.NewButtonWithLabel("Select All but Newest")
+	keepNewest.ConnectClicked(func() {
+		if result := bar.path.Result(); result != nil {
+			viewer.FileViewerList.SelectPaths(result.AllButNewest())
+		}
+	})
+	bar.Append(keepNewest)
+
+	keepOldest := gtk.NewButtonWithLabel("Select All but Oldest")
+	keepOldest.ConnectClicked(func() {
+		if result := bar.path.Result(); result != nil {
+			viewer.FileViewerList.SelectPaths(result.AllButOldest())
+		}
+	})
+	bar.Append(keepOldest)
+
+	trashButton := gtk.NewButtonFromIconName("user-trash-symbolic")
+	trashButton.SetTooltipText("Move Selected to Trash")
+	trashButton.ConnectClicked(func() {
+		bar.apply("Moving duplicates to trash", (*duplicates.Result).Trash)
+	})
+	bar.Append(trashButton)
+
+	linkButton := gtk.NewButtonFromIconName("insert-link-symbolic")
+	linkButton.SetTooltipText("Replace Selected with Hard Links")
+	linkButton.ConnectClicked(func() {
+		bar.apply("Replacing duplicates with hard links", (*duplicates.Result).Hardlink)
+	})
+	bar.Append(linkButton)
+
+	rescanButton := gtk.NewButtonFromIconName("view-refresh-symbolic")
+	rescanButton.SetTooltipText("Scan Again")
+	rescanButton.ConnectClicked(func() {
+		if viewer.FindDuplicates != nil {
+			viewer.FindDuplicates(bar.path.Root())
+		}
+	})
+	bar.Append(rescanButton)
+
+	return bar
+}
+
+func (bar *duplicatesBar) update(path *duplicates.DuplicatesPath) {
+	bar.path = path
+	result := path.Result()
+	if result == nil {
+		bar.summary.SetText("Not scanned yet")
+		return
+	}
+	var reclaimable int64
+	for _, group := range result.Groups {
+		reclaimable += group.Reclaimable()
+	}
+	bar.summary.SetText(fmt.Sprintf("%d groups, %s reclaimable", len(result.Groups), fileops.GetFileSizeAsString(reclaimable)))
+}
+
+// apply runs action on the selected files as a job and lists the view again
+// once it is done.
+func (bar *duplicatesBar) apply(name string, action func(*duplicates.Result, []string) []error) {
+	result := bar.path.Result()
+	if result == nil || bar.viewer.RunJob == nil {
+		return
+	}
+	var selected []string
+	for _, item := range bar.viewer.FileViewerList.SelectedItems() {
+		selected = append(selected, item.Path)
+	}
+	if len(selected) == 0 {
+		return
+	}
+	bar.viewer.RunJob(name, func(ctx context.Context, progress func(float64)) error {
+		errs := action(result, selected)
+		for _, err := range errs {
+			println(err.Error())
+		}
+		if len(errs) > 0 {
+			return errs[0]
+		}
+		return nil
+	}, func(err error) {
+		bar.viewer.Refresh(false)
+	})
+}
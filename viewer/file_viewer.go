@@ -1,31 +1,33 @@
 package viewer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
-	"time"
 
+	"github.com/MrSametBurgazoglu/atilgan/columns"
 	"github.com/MrSametBurgazoglu/atilgan/create_popup"
+	"github.com/MrSametBurgazoglu/atilgan/dir_compare"
+	"github.com/MrSametBurgazoglu/atilgan/duplicates"
 	"github.com/MrSametBurgazoglu/atilgan/file_list"
 	"github.com/MrSametBurgazoglu/atilgan/fileops"
+	"github.com/MrSametBurgazoglu/atilgan/recent"
+	"github.com/MrSametBurgazoglu/atilgan/sort_popup"
+	"github.com/MrSametBurgazoglu/atilgan/sorter"
 	"github.com/MrSametBurgazoglu/atilgan/special_path"
+	"github.com/MrSametBurgazoglu/atilgan/tag"
 	"github.com/MrSametBurgazoglu/atilgan/types"
+	"github.com/MrSametBurgazoglu/atilgan/view_state"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
-type SortOrder int
-
-const (
-	SortByName SortOrder = iota
-	SortByTime
-)
-
 type FileType int
 
 const (
@@ -46,7 +48,7 @@ type FileViewHistory struct {
 type FileViewer struct {
 	*gtk.Box
 	Path               string
-	SortOrder          SortOrder
+	SortOptions        sorter.SortOptions
 	SearchValue        string
 	SearchRevealer     *gtk.Revealer
 	SearchEntry        *gtk.SearchEntry
@@ -56,20 +58,54 @@ type FileViewer struct {
 	FiltersMap         map[string]bool
 	IsCopy             bool
 	IsCut              bool
+	VerifyCopies       bool
+	IsLink             bool
+	LinkMode           fileops.LinkMode
 	folderIcon         *gtk.Image
 	folderName         *gtk.Label
 	popover            *gtk.Popover
 	createPopover      *create_popup.CreatePopover
+	sortButton         *gtk.MenuButton
+	sortPopover        *sort_popup.SortPopover
+	detailsButton      *gtk.ToggleButton
+	updatingView       bool
 	FileViewerHistory  map[string]*FileViewHistory
 	FileViewerList     *file_list.FileList
 	specialPathManager *special_path.SpecialPathManager
+	viewStateManager   *view_state.ViewStateManager
+	duplicatesBar      *duplicatesBar
+	compareBar         *compareBar
+	tagsBar            *tagsBar
+	recentBar          *recentBar
+	diskUsageButton    *gtk.ToggleButton
+	DiskUsageMode      bool
+
+	// RunJob runs work in the background with progress in the header bar.
+	RunJob func(name string, work func(ctx context.Context, progress func(float64)) error, done func(err error))
+	// FindDuplicates scans root and shows its duplicates:// view.
+	FindDuplicates func(root string)
+	// DiskUsageToggled is called when the disk usage mode is switched.
+	DiskUsageToggled func(active bool)
+	// CompareFolders compares left with right and shows the compare:// view.
+	CompareFolders func(left, right string, opts dir_compare.Options)
+	// SyncFolders previews and runs a sync of a comparison.
+	SyncFolders func(result *dir_compare.Result, selected []string)
+	// RenameTag, MergeTags and DeleteTags edit the tags selected in tags://.
+	RenameTag  func(t string)
+	MergeTags  func(tags []string)
+	DeleteTags func(tags []string)
+	// EditTagRules opens the auto-tag rules.
+	EditTagRules func()
+	// ImportTags and ExportTags move tags in and out of the app.
+	ImportTags func()
+	ExportTags func()
 }
 
-func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string), specialPathManager *special_path.SpecialPathManager) *FileViewer {
+func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string), specialPathManager *special_path.SpecialPathManager, viewStateManager *view_state.ViewStateManager) *FileViewer {
 	viewer := &FileViewer{
 		Box:                gtk.NewBox(gtk.OrientationVertical, 6),
 		Path:               path,
-		SortOrder:          SortByName,
+		SortOptions:        sorter.DefaultSortOptions(),
 		SearchValue:        "",
 		FiltersMap:         make(map[string]bool),
 		FileViewerHistory:  make(map[string]*FileViewHistory),
@@ -78,6 +114,7 @@ func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string)
 		folderIcon:         gtk.NewImageFromIconName("folder-symbolic"),
 		folderName:         gtk.NewLabel(filepath.Base(path)),
 		specialPathManager: specialPathManager,
+		viewStateManager:   viewStateManager,
 	}
 	viewer.SetVExpand(true)
 
@@ -101,6 +138,22 @@ func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string)
 	viewer.SearchRevealer.SetTransitionType(gtk.RevealerTransitionTypeSlideLeft)
 	viewer.Box.Append(viewer.SearchRevealer)
 
+	viewer.duplicatesBar = newDuplicatesBar(viewer)
+	viewer.duplicatesBar.SetVisible(false)
+	viewer.Box.Append(viewer.duplicatesBar)
+
+	viewer.compareBar = newCompareBar(viewer)
+	viewer.compareBar.SetVisible(false)
+	viewer.Box.Append(viewer.compareBar)
+
+	viewer.tagsBar = newTagsBar(viewer)
+	viewer.tagsBar.SetVisible(false)
+	viewer.Box.Append(viewer.tagsBar)
+
+	viewer.recentBar = newRecentBar(viewer)
+	viewer.recentBar.SetVisible(false)
+	viewer.Box.Append(viewer.recentBar)
+
 	viewer.SearchEntry.ConnectSearchChanged(func() {
 		viewer.SearchValue = viewer.SearchEntry.Text()
 		viewer.Refresh(false)
@@ -119,7 +172,11 @@ func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string)
 	rightBox := gtk.NewBox(gtk.OrientationHorizontal, 6)
 	headerBox.Append(rightBox)
 
-	sortButton := gtk.NewButtonFromIconName("view-sort-descending-symbolic")
+	viewer.sortPopover = sort_popup.NewSortPopover(viewer.setSortOptions)
+	viewer.sortButton = gtk.NewMenuButton()
+	viewer.sortButton.SetIconName(viewer.sortPopover.IconName())
+	viewer.sortButton.SetTooltipText(viewer.SortOptions.String())
+	viewer.sortButton.SetPopover(viewer.sortPopover)
 
 	newButton := gtk.NewMenuButton()
 	newButton.SetIconName("list-add-symbolic")
@@ -141,11 +198,42 @@ func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string)
 		}
 	})
 
+	viewer.detailsButton = gtk.NewToggleButton()
+	viewer.detailsButton.SetIconName("view-list-symbolic")
+	viewer.detailsButton.SetTooltipText("Details view")
+	viewer.detailsButton.ConnectToggled(func() {
+		if viewer.updatingView {
+			return
+		}
+		viewer.FileViewerList.SetDetailsMode(viewer.detailsButton.Active())
+		viewer.viewStateManager.SetDetailsMode(viewer.Path, viewer.detailsButton.Active())
+	})
+
+	viewer.FileViewerList.SetLayout(viewer.viewStateManager.GetColumnLayout("viewer"))
+	viewer.FileViewerList.LayoutChanged = func(layout columns.Layout) {
+		viewer.viewStateManager.SetColumnLayout("viewer", layout)
+	}
+	viewer.FileViewerList.ColumnClicked = func(column columns.Column) {
+		viewer.setSortOptions(column.SortOptions(viewer.SortOptions))
+	}
+
+	viewer.diskUsageButton = gtk.NewToggleButton()
+	viewer.diskUsageButton.SetIconName("drive-harddisk-symbolic")
+	viewer.diskUsageButton.SetTooltipText("Disk usage")
+	viewer.diskUsageButton.ConnectToggled(func() {
+		viewer.DiskUsageMode = viewer.diskUsageButton.Active()
+		if viewer.DiskUsageToggled != nil {
+			viewer.DiskUsageToggled(viewer.DiskUsageMode)
+		}
+	})
+
 	filterButton := gtk.NewMenuButton()
 	filterButton.SetIconName("preferences-system-symbolic")
 	rightBox.Append(newButton)
 	rightBox.Append(terminalButton)
-	rightBox.Append(sortButton)
+	rightBox.Append(viewer.diskUsageButton)
+	rightBox.Append(viewer.detailsButton)
+	rightBox.Append(viewer.sortButton)
 	rightBox.Append(filterButton)
 
 	viewer.popover = gtk.NewPopover()
@@ -155,15 +243,6 @@ func NewFileViewer(mainWindow *gtk.Window, path string, pathChanged func(string)
 	viewer.popover.SetChild(popoverBox)
 	viewer.Box.Append(viewer.FileViewerList)
 
-	sortButton.ConnectClicked(func() {
-		if viewer.SortOrder == SortByTime {
-			viewer.SortOrder = SortByName
-		} else {
-			viewer.SortOrder = SortByTime
-		}
-		viewer.Refresh(false)
-	})
-
 	viewer.Refresh(true)
 
 	return viewer
@@ -186,8 +265,39 @@ func (viewer *FileViewer) Refresh(newFilter bool) {
 		return
 	}
 	specialPath := viewer.specialPathManager.GetPath(viewer.Path)
+	duplicatesPath, isDuplicates := specialPath.(*duplicates.DuplicatesPath)
+	viewer.duplicatesBar.SetVisible(isDuplicates)
+	if isDuplicates {
+		viewer.duplicatesBar.update(duplicatesPath)
+	}
+	comparePath, isCompare := specialPath.(*dir_compare.ComparePath)
+	viewer.compareBar.SetVisible(isCompare)
+	if isCompare {
+		viewer.compareBar.update(comparePath)
+	}
+	var tagsQuery string
+	var tagsErr error
+	isTags, isTag := true, false
+	switch p := specialPath.(type) {
+	case *tag.TagsPath:
+	case *tag.TagPath:
+		tagsQuery, isTag = p.Tag(), true
+	case *tag.QueryPath:
+		tagsQuery, tagsErr = p.GetName(), p.Err()
+	default:
+		isTags = false
+	}
+	viewer.tagsBar.SetVisible(isTags)
+	recentPath, isRecent := specialPath.(*recent.RecentPath)
+	viewer.recentBar.SetVisible(isRecent)
 	if specialPath != nil {
 		items := specialPath.GetItems()
+		if isTags {
+			viewer.tagsBar.update(tagsQuery, isTag, len(items), tagsErr)
+		}
+		if isRecent {
+			viewer.recentBar.update(recentPath, len(items))
+		}
 		viewer.FileViewerList.SetItems(items)
 		viewer.SetFolderName(specialPath.GetName())
 		viewer.folderIcon.SetFromIconName(fileops.GetIconForFolderSymbolic(viewer.Path))
@@ -240,6 +350,7 @@ func (viewer *FileViewer) Refresh(newFilter bool) {
 		}
 		sort.Strings(extensions)
 		viewer.Filters = append(viewer.Filters, extensions...)
+		viewer.applyViewState()
 		viewer.UpdateFilterPopover()
 	}
 
@@ -250,7 +361,7 @@ func (viewer *FileViewer) Refresh(newFilter bool) {
 				continue
 			}
 		}
-		fileType := getFileType(entry)
+		fileType := getFileType(viewer.Path, entry)
 		show := false
 		if fileType == TypeDir {
 			if viewer.FiltersMap["Directories"] {
@@ -278,54 +389,26 @@ func (viewer *FileViewer) Refresh(newFilter bool) {
 		filteredEntries = append(filteredEntries, entry)
 	}
 
-	sort.Slice(filteredEntries, func(i, j int) bool {
-		switch viewer.SortOrder {
-		case SortByTime:
-			infoI, errI := filteredEntries[i].Info()
-			infoJ, errJ := filteredEntries[j].Info()
-			if errI != nil || errJ != nil {
-				return false
-			}
-			return infoI.ModTime().After(infoJ.ModTime())
-		default:
-			return strings.Title(filteredEntries[i].Name()) < strings.Title(filteredEntries[j].Name())
-		}
-	})
-
-	newFiles := make([]*types.ListItem, 0)
+	diskUsage := viewer.specialPathManager.GetDiskUsage()
+	newFiles := make([]*types.ListItem, 0, len(filteredEntries))
 	for _, entry := range filteredEntries {
-		fullPath := path.Join(viewer.Path, entry.Name())
-		var group string
-		if viewer.SortOrder == SortByTime {
-			info, err := entry.Info()
-			if err != nil {
-				group = "Unknown"
-			} else {
-				group = getGroupForTime(info.ModTime())
-			}
-		} else {
-			name := entry.Name()
-			runes := []rune(strings.Title(name))
-			firstRune := runes[0]
-			group = string(firstRune)
+		item := fileops.NewListItem(viewer.Path, entry)
+		if item.IsDir {
+			item.TotalSize, _ = diskUsage.Size(item.Path)
 		}
-		listItem := &types.ListItem{
-			Name:  entry.Name(),
-			Path:  fullPath,
-			Group: group,
-			IsDir: entry.IsDir(),
-		}
-		if listItem.IsDir {
-			listItem.ItemCount = getDirItemCount(fullPath)
-		} else {
-			info, err := entry.Info()
-			if err == nil {
-				listItem.Size = info.Size()
-			}
-		}
-		newFiles = append(newFiles, listItem)
+		newFiles = append(newFiles, item)
 	}
+	missing := false
+	sorter.SortWithValues(newFiles, viewer.SortOptions, func(item *types.ListItem) string {
+		value, ok := viewer.FileViewerList.Columns.SortValue(item, columns.Column(viewer.SortOptions.Column))
+		missing = missing || !ok
+		return value
+	})
+	viewer.FileViewerList.SortOptions = viewer.SortOptions
 	viewer.FileViewerList.SetItems(newFiles)
+	if missing {
+		viewer.loadSortValues(newFiles)
+	}
 	println("this is where I set folder icon")
 	viewer.folderIcon.SetFromIconName(fileops.GetIconForFolderSymbolic(viewer.Path))
 }
@@ -342,6 +425,7 @@ func (viewer *FileViewer) UpdateFilterPopover() {
 		filterName := filter
 		checkButton.ConnectToggled(func() {
 			viewer.FiltersMap[filterName] = checkButton.Active()
+			viewer.saveFilter(filterName, checkButton.Active())
 			viewer.UpdateFilterPopover()
 			viewer.Refresh(false)
 		})
@@ -356,47 +440,91 @@ func (viewer *FileViewer) UpdateFilterPopover() {
 		filterName := filter
 		checkButton.ConnectToggled(func() {
 			viewer.FiltersMap[filterName] = checkButton.Active()
+			viewer.saveFilter(filterName, checkButton.Active())
 			viewer.UpdateFilterPopover()
 			viewer.Refresh(false)
 		})
 		popoverBox.Append(checkButton)
 	}
-}
 
-func getGroupForTime(modTime time.Time) string {
-	now := time.Now()
-	duration := now.Sub(modTime)
+	popoverBox.Append(gtk.NewSeparator(gtk.OrientationHorizontal))
 
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	if modTime.After(todayStart) {
-		return "Today"
-	}
+	defaultButton := gtk.NewButtonWithLabel("Use as Default")
+	defaultButton.ConnectClicked(func() {
+		viewer.viewStateManager.SetDefault(viewer.Path)
+		viewer.popover.Popdown()
+	})
+	popoverBox.Append(defaultButton)
 
-	if duration.Hours() <= 24 {
-		return "Last 24 hours"
-	}
+	resetButton := gtk.NewButtonWithLabel("Reset Folder View")
+	resetButton.ConnectClicked(func() {
+		viewer.viewStateManager.Reset(viewer.Path)
+		viewer.popover.Popdown()
+		viewer.Refresh(true)
+	})
+	popoverBox.Append(resetButton)
+}
 
-	if duration.Hours() <= 24*7 {
-		return "Last Week"
+func (viewer *FileViewer) applyViewState() {
+	view := viewer.viewStateManager.Get(viewer.Path)
+	for filter, enabled := range view.Filters {
+		if _, ok := viewer.FiltersMap[filter]; ok {
+			viewer.FiltersMap[filter] = enabled
+		}
 	}
-
-	if duration.Hours() <= 24*30 {
-		return "Last Month"
+	if _, ok := viewer.FiltersMap["Hidden"]; ok {
+		viewer.FiltersMap["Hidden"] = view.ShowHidden
 	}
+	viewer.SortOptions = view.SortOptions
+	viewer.sortPopover.SetOptions(view.SortOptions)
+	viewer.sortButton.SetIconName(viewer.sortPopover.IconName())
+	viewer.sortButton.SetTooltipText(view.SortOptions.String())
+	viewer.updatingView = true
+	viewer.detailsButton.SetActive(view.DetailsMode)
+	viewer.updatingView = false
+	viewer.FileViewerList.SetDetailsMode(view.DetailsMode)
+}
 
-	return "Later"
+func (viewer *FileViewer) setSortOptions(opts sorter.SortOptions) {
+	viewer.SortOptions = opts
+	viewer.viewStateManager.SetSortOptions(viewer.Path, opts)
+	viewer.sortPopover.SetOptions(opts)
+	viewer.sortButton.SetIconName(viewer.sortPopover.IconName())
+	viewer.sortButton.SetTooltipText(opts.String())
+	viewer.Refresh(false)
 }
 
-func getDirItemCount(dirPath string) int {
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return 0
-	}
+// loadSortValues sorts the items again, keeping the selected one, once the
+// values of the column they are sorted by are loaded in the background.
+func (viewer *FileViewer) loadSortValues(items []*types.ListItem) {
+	path, opts := viewer.Path, viewer.SortOptions
+	column := columns.Column(opts.Column)
+	viewer.FileViewerList.Columns.LoadSortValues(items, column, func(values map[string]string) {
+		glib.IdleAdd(func() {
+			list := viewer.FileViewerList
+			if viewer.Path != path || viewer.SortOptions != opts || len(list.Items) == 0 {
+				return
+			}
+			selected := list.Items[min(list.SelectedIDX, len(list.Items)-1)].Path
+			sorted := slices.Clone(list.Items)
+			sorter.SortWithValues(sorted, opts, func(item *types.ListItem) string {
+				return values[item.Path]
+			})
+			list.SetItems(sorted)
+			list.SelectPath(selected)
+		})
+	})
+}
 
-	return len(entries)
+func (viewer *FileViewer) saveFilter(filter string, enabled bool) {
+	if filter == "Hidden" {
+		viewer.viewStateManager.SetShowHidden(viewer.Path, enabled)
+	} else {
+		viewer.viewStateManager.SetFilter(viewer.Path, filter, enabled)
+	}
 }
 
-func getFileType(entry os.DirEntry) FileType {
+func getFileType(dirPath string, entry os.DirEntry) FileType {
 	fileName := entry.Name()
 	if strings.HasPrefix(fileName, ".") {
 		return TypeHidden
@@ -414,6 +542,16 @@ func getFileType(entry os.DirEntry) FileType {
 	if err != nil {
 		return TypeOther
 	}
+	// A link has the type of its target. Broken links have no target and
+	// all links have every mode bit set, so they aren't executables.
+	if info.Mode()&os.ModeSymlink != 0 {
+		if info, err = os.Stat(filepath.Join(dirPath, fileName)); err != nil {
+			return TypeOther
+		}
+		if info.IsDir() {
+			return TypeDir
+		}
+	}
 
 	if info.Mode()&0111 != 0 {
 		return TypeExec
@@ -458,17 +596,38 @@ func (viewer *FileViewer) ExecuteCopyPaste(progress func(float64)) error {
 		filePaths[i] = file
 	}
 
-	if viewer.IsCut {
+	if viewer.IsLink {
+		errors := fileops.LinkFiles(filePaths, viewer.Path, viewer.LinkMode)
+		if len(errors) > 0 {
+			println(errors[0].Error())
+			return errors[0]
+		}
+	} else if viewer.IsCut {
 		errors := fileops.CutFiles(filePaths, viewer.Path)
 		if errors != nil && len(errors) > 0 {
 			return errors[0]
 		}
 	} else {
-		errors := fileops.CopyFiles(filePaths, viewer.Path, progress)
+		copyProgress := progress
+		if viewer.VerifyCopies && progress != nil {
+			copyProgress = func(f float64) { progress(f / 2) }
+		}
+		errors := fileops.CopyFiles(filePaths, viewer.Path, copyProgress)
 		if errors != nil && len(errors) > 0 {
 			println(errors[0].Error())
 			return errors[0]
 		}
+		if viewer.VerifyCopies {
+			errors := fileops.VerifyCopies(filePaths, viewer.Path, func(f float64) {
+				if progress != nil {
+					progress(0.5 + f/2)
+				}
+			})
+			if len(errors) > 0 {
+				println(errors[0].Error())
+				return errors[0]
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,146 @@
+package viewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/recent"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// recentBar switches recent:// between files and folders and edits the
+// history.
+type recentBar struct {
+	*gtk.Box
+	viewer          *FileViewer
+	summary         *gtk.Label
+	filesButton     *gtk.ToggleButton
+	foldersButton   *gtk.ToggleButton
+	exclusionsView  *gtk.TextView
+	exclusionsPopup *gtk.Popover
+	updating        bool
+}
+
+func newRecentBar(viewer *FileViewer) *recentBar {
+	bar := &recentBar{
+		Box:             gtk.NewBox(gtk.OrientationHorizontal, 6),
+		viewer:          viewer,
+		summary:         gtk.NewLabel(""),
+		filesButton:     gtk.NewToggleButtonWithLabel("Files"),
+		foldersButton:   gtk.NewToggleButtonWithLabel("Folders"),
+		exclusionsView:  gtk.NewTextView(),
+		exclusionsPopup: gtk.NewPopover(),
+	}
+	bar.summary.SetXAlign(0)
+	bar.summary.SetHExpand(true)
+	bar.Append(bar.summary)
+
+	switchBox := gtk.NewBox(gtk.OrientationHorizontal, 0)
+	switchBox.AddCSSClass("linked")
+	bar.foldersButton.SetGroup(bar.filesButton)
+	for _, choice := range []struct {
+		button *gtk.ToggleButton
+		path   string
+	}{
+		{bar.filesButton, "recent://"},
+		{bar.foldersButton, recent.FoldersPath},
+	} {
+		choice.button.ConnectToggled(func() {
+			if !bar.updating && choice.button.Active() && viewer.FileViewerList.PathChanged != nil {
+				viewer.FileViewerList.PathChanged(choice.path)
+			}
+		})
+		switchBox.Append(choice.button)
+	}
+	bar.Append(switchBox)
+
+	removeButton := gtk.NewButtonWithLabel("Remove from Recent")
+	removeButton.ConnectClicked(func() {
+		var paths []string
+		for _, item := range viewer.FileViewerList.SelectedItems() {
+			paths = append(paths, item.Path)
+		}
+		if len(paths) > 0 && viewer.specialPathManager != nil {
+			viewer.specialPathManager.GetRecentManager().Remove(paths)
+			viewer.Refresh(false)
+		}
+	})
+	bar.Append(removeButton)
+
+	bar.Append(bar.newExclusionsButton())
+	bar.Append(bar.newClearButton())
+	return bar
+}
+
+func (bar *recentBar) newExclusionsButton() *gtk.MenuButton {
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	label := gtk.NewLabel("Places matching these patterns, one per line, aren't recorded, like /tmp, ~/Private or .secret")
+	label.SetXAlign(0)
+	label.SetWrap(true)
+	label.SetMaxWidthChars(40)
+	box.Append(label)
+	scrolled := gtk.NewScrolledWindow()
+	scrolled.SetSizeRequest(300, 120)
+	scrolled.SetChild(bar.exclusionsView)
+	box.Append(scrolled)
+	saveButton := gtk.NewButtonWithLabel("Save")
+	saveButton.SetHAlign(gtk.AlignEnd)
+	saveButton.AddCSSClass("suggested-action")
+	saveButton.ConnectClicked(func() {
+		buffer := bar.exclusionsView.Buffer()
+		var patterns []string
+		for _, line := range strings.Split(buffer.Text(buffer.StartIter(), buffer.EndIter(), false), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				patterns = append(patterns, line)
+			}
+		}
+		bar.viewer.specialPathManager.GetRecentManager().SetExclusions(patterns)
+		bar.exclusionsPopup.Popdown()
+		bar.viewer.Refresh(false)
+	})
+	box.Append(saveButton)
+	bar.exclusionsPopup.SetChild(box)
+	bar.exclusionsPopup.ConnectShow(func() {
+		exclusions := bar.viewer.specialPathManager.GetRecentManager().Exclusions()
+		bar.exclusionsView.Buffer().SetText(strings.Join(exclusions, "\n"))
+	})
+
+	button := gtk.NewMenuButton()
+	button.SetLabel("Exclusions…")
+	button.SetPopover(bar.exclusionsPopup)
+	return button
+}
+
+func (bar *recentBar) newClearButton() *gtk.MenuButton {
+	popover := gtk.NewPopover()
+	box := gtk.NewBox(gtk.OrientationVertical, 6)
+	box.Append(gtk.NewLabel("Forget all recent files and folders, also those other applications list?"))
+	clearButton := gtk.NewButtonWithLabel("Clear History")
+	clearButton.AddCSSClass("destructive-action")
+	clearButton.ConnectClicked(func() {
+		bar.viewer.specialPathManager.GetRecentManager().Clear()
+		popover.Popdown()
+		bar.viewer.Refresh(false)
+	})
+	box.Append(clearButton)
+	popover.SetChild(box)
+
+	button := gtk.NewMenuButton()
+	button.SetIconName("edit-clear-all-symbolic")
+	button.SetTooltipText("Clear History")
+	button.SetPopover(popover)
+	return button
+}
+
+// update shows the bar for a recent:// view of count items.
+func (bar *recentBar) update(path *recent.RecentPath, count int) {
+	bar.updating = true
+	bar.filesButton.SetActive(!path.Folders())
+	bar.foldersButton.SetActive(path.Folders())
+	bar.updating = false
+	if path.Folders() {
+		bar.summary.SetText(fmt.Sprintf("%d folders", count))
+	} else {
+		bar.summary.SetText(fmt.Sprintf("%d files", count))
+	}
+}
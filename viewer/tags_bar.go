@@ -0,0 +1,180 @@
+package viewer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/MrSametBurgazoglu/atilgan/tag_popup"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// tagsBar holds the actions of the tags:// views. The tag actions work on
+// the selected tags, a tag or a query can be pinned to the sidebar.
+type tagsBar struct {
+	*gtk.Box
+	viewer        *FileViewer
+	summary       *gtk.Label
+	queryEntry    *gtk.Entry
+	manageBox     *gtk.Box
+	moreButton    *gtk.MenuButton
+	subtagsButton *gtk.ToggleButton
+	pinButton     *gtk.ToggleButton
+	query         string
+	updating      bool
+}
+
+func newTagsBar(viewer *FileViewer) *tagsBar {
+	bar := &tagsBar{
+		Box:           gtk.NewBox(gtk.OrientationHorizontal, 6),
+		viewer:        viewer,
+		summary:       gtk.NewLabel(""),
+		queryEntry:    gtk.NewEntry(),
+		manageBox:     gtk.NewBox(gtk.OrientationHorizontal, 6),
+		moreButton:    gtk.NewMenuButton(),
+		subtagsButton: gtk.NewToggleButton(),
+		pinButton:     gtk.NewToggleButton(),
+	}
+	bar.summary.SetXAlign(0)
+	bar.summary.SetHExpand(true)
+	bar.Append(bar.summary)
+
+	queryEntry := bar.queryEntry
+	queryEntry.SetPlaceholderText("work+urgent, photos|scans…")
+	queryEntry.SetTooltipText("Show the files matching a query: + for and, | for or, ! or -! for not, \\ before any of them in a tag name")
+	queryEntry.ConnectActivate(func() {
+		if text := strings.TrimSpace(queryEntry.Text()); text != "" && viewer.FileViewerList.PathChanged != nil {
+			queryEntry.SetText("")
+			viewer.FileViewerList.PathChanged("tags://" + text)
+		}
+	})
+	bar.Append(queryEntry)
+
+	renameButton := gtk.NewButtonWithLabel("Rename…")
+	renameButton.ConnectClicked(func() {
+		if tags := bar.selectedTags(); len(tags) > 0 && viewer.RenameTag != nil {
+			viewer.RenameTag(tags[0])
+		}
+	})
+	bar.manageBox.Append(renameButton)
+
+	mergeButton := gtk.NewButtonWithLabel("Merge…")
+	mergeButton.SetTooltipText("Merge the selected tags into one")
+	mergeButton.ConnectClicked(func() {
+		if tags := bar.selectedTags(); len(tags) > 1 && viewer.MergeTags != nil {
+			viewer.MergeTags(tags)
+		}
+	})
+	bar.manageBox.Append(mergeButton)
+
+	if viewer.specialPathManager != nil {
+		bar.manageBox.Append(tag_popup.NewColorButton(viewer.specialPathManager.GetTagManager(), bar.selectedTags))
+	}
+
+	deleteButton := gtk.NewButtonFromIconName("user-trash-symbolic")
+	deleteButton.SetTooltipText("Delete Tags")
+	deleteButton.ConnectClicked(func() {
+		if tags := bar.selectedTags(); len(tags) > 0 && viewer.DeleteTags != nil {
+			viewer.DeleteTags(tags)
+		}
+	})
+	bar.manageBox.Append(deleteButton)
+	bar.Append(bar.manageBox)
+
+	morePopover := gtk.NewPopover()
+	moreBox := gtk.NewBox(gtk.OrientationVertical, 0)
+	for _, action := range []struct {
+		label string
+		run   *func()
+	}{
+		{"Auto-tag Rules…", &viewer.EditTagRules},
+		{"Import Tags…", &viewer.ImportTags},
+		{"Export Tags…", &viewer.ExportTags},
+	} {
+		button := gtk.NewButtonWithLabel(action.label)
+		button.AddCSSClass("flat")
+		button.ConnectClicked(func() {
+			morePopover.Popdown()
+			if *action.run != nil {
+				(*action.run)()
+			}
+		})
+		moreBox.Append(button)
+	}
+	morePopover.SetChild(moreBox)
+	bar.moreButton.SetIconName("view-more-symbolic")
+	bar.moreButton.SetTooltipText("More Tag Actions")
+	bar.moreButton.SetPopover(morePopover)
+	bar.Append(bar.moreButton)
+
+	bar.subtagsButton.SetIconName("view-list-symbolic")
+	bar.subtagsButton.SetTooltipText("Include Files of Sub-tags")
+	bar.subtagsButton.ConnectToggled(func() {
+		if !bar.updating && viewer.specialPathManager != nil {
+			viewer.specialPathManager.GetTagManager().SetIncludeSubtags(bar.subtagsButton.Active())
+		}
+	})
+	bar.Append(bar.subtagsButton)
+
+	bar.pinButton.SetIconName("view-pin-symbolic")
+	bar.pinButton.ConnectToggled(func() {
+		if bar.updating || viewer.specialPathManager == nil {
+			return
+		}
+		tagManager := viewer.specialPathManager.GetTagManager()
+		if bar.pinButton.Active() {
+			tagManager.PinQuery(bar.query)
+		} else {
+			tagManager.UnpinQuery(bar.query)
+		}
+	})
+	bar.Append(bar.pinButton)
+
+	return bar
+}
+
+func (bar *tagsBar) selectedTags() []string {
+	var tags []string
+	for _, item := range bar.viewer.FileViewerList.SelectedItems() {
+		if t, ok := strings.CutPrefix(item.Path, "tags://"); ok {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// update shows the bar for the tags:// path, query being empty at the root
+// and isTag telling a single tag from a query.
+func (bar *tagsBar) update(query string, isTag bool, count int, err error) {
+	bar.query = query
+	bar.queryEntry.SetVisible(query == "")
+	bar.manageBox.SetVisible(query == "" || isTag)
+	bar.moreButton.SetVisible(query == "")
+	bar.subtagsButton.SetVisible(isTag)
+	bar.pinButton.SetVisible(query != "" && err == nil)
+	bar.summary.RemoveCSSClass("error")
+	switch {
+	case err != nil:
+		bar.summary.SetText(err.Error())
+		bar.summary.AddCSSClass("error")
+	case query == "":
+		bar.summary.SetText(fmt.Sprintf("%d tags", count))
+	case isTag:
+		bar.summary.SetText(fmt.Sprintf("%d items", count))
+	default:
+		bar.summary.SetText(fmt.Sprintf("%d files", count))
+	}
+
+	pinned := bar.viewer.specialPathManager != nil && slices.Contains(bar.viewer.specialPathManager.GetTagManager().PinnedQueries(), query)
+	bar.updating = true
+	bar.pinButton.SetActive(pinned)
+	if bar.viewer.specialPathManager != nil {
+		bar.subtagsButton.SetActive(bar.viewer.specialPathManager.GetTagManager().IncludeSubtags())
+	}
+	bar.updating = false
+	if pinned {
+		bar.pinButton.SetTooltipText("Unpin from Sidebar")
+	} else {
+		bar.pinButton.SetTooltipText("Pin to Sidebar")
+	}
+}
@@ -2,6 +2,7 @@ package viewer_panel
 
 import (
 	"github.com/MrSametBurgazoglu/atilgan/special_path"
+	"github.com/MrSametBurgazoglu/atilgan/view_state"
 	"github.com/MrSametBurgazoglu/atilgan/viewer"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
@@ -12,11 +13,11 @@ type Panel struct {
 	FileViewer *viewer.FileViewer
 }
 
-func NewPanel(mainWindow *gtk.Window, path string, pathChanged func(string), specialPathManager *special_path.SpecialPathManager) *Panel {
+func NewPanel(mainWindow *gtk.Window, path string, pathChanged func(string), specialPathManager *special_path.SpecialPathManager, viewStateManager *view_state.ViewStateManager) *Panel {
 	panel := &Panel{
 		Box:        gtk.NewBox(gtk.OrientationHorizontal, 0),
 		Path:       path,
-		FileViewer: viewer.NewFileViewer(mainWindow, path, pathChanged, specialPathManager),
+		FileViewer: viewer.NewFileViewer(mainWindow, path, pathChanged, specialPathManager, viewStateManager),
 	}
 	panel.Box.AddCSSClass("preview-panel")
 	panel.SetHExpand(false)